@@ -0,0 +1,131 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Otabek228101/mehmon/config"
+	"github.com/Otabek228101/mehmon/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestAuthRoutes(t *testing.T) {
+	s := newTestServer(t)
+	anon := s.anonymous()
+
+	anon.expect(http.StatusUnauthorized, http.MethodGet, "/api/auth/me", nil, nil)
+	anon.expect(http.StatusUnauthorized, http.MethodPost, "/api/auth/login", fiber.Map{"email": "admin@example.com", "password": "wrong-password"}, nil)
+	anon.expect(http.StatusUnauthorized, http.MethodPost, "/api/auth/login", fiber.Map{"email": "nobody@example.com", "password": testPassword}, nil)
+	anon.expect(http.StatusBadRequest, http.MethodPost, "/api/auth/login", fiber.Map{}, nil)
+	forged := &testServer{t: t, app: s.app, token: "not-a-token"}
+	forged.expect(http.StatusUnauthorized, http.MethodGet, "/api/auth/me", nil, nil)
+
+	var me models.User
+	s.expect(http.StatusOK, http.MethodGet, "/api/auth/me", nil, &me)
+	if me.Email != "admin@example.com" || me.Role != models.RoleAdmin {
+		t.Fatalf("me = %+v, want the admin", me)
+	}
+	s.expect(http.StatusOK, http.MethodPost, "/api/auth/logout", nil, nil)
+}
+
+func TestMissingSecretFailsRequests(t *testing.T) {
+	s := newTestServer(t)
+	receipt := s.createReceipt(receiptPayload("Ann", "2026-05-10"))
+
+	config.Current.JWTSecret = ""
+	anon := s.anonymous()
+	anon.expect(http.StatusInternalServerError, http.MethodPost, "/api/auth/login", fiber.Map{"email": "admin@example.com", "password": testPassword}, nil)
+	anon.expect(http.StatusInternalServerError, http.MethodGet, "/api/public/receipts/"+id(receipt.ID)+"?sig=00", nil, nil)
+	s.expect(http.StatusInternalServerError, http.MethodGet, "/api/receipts/"+id(receipt.ID)+"/share", nil, nil)
+}
+
+func TestPermissions(t *testing.T) {
+	s := newTestServer(t)
+	hotel := s.createHotel("Palazzo", "BARI")
+	receipt := s.createReceipt(receiptPayload("Ann", "2026-05-01"))
+	agent := s.as(models.RoleAgent)
+	manager := s.as(models.RoleManager)
+
+	agent.expect(http.StatusOK, http.MethodGet, "/api/hotels", nil, nil)
+	agent.createReceipt(receiptPayload("Bob", "2026-05-02"))
+	agent.expect(http.StatusForbidden, http.MethodPost, "/api/hotels", fiber.Map{"name": "X"}, nil)
+	agent.expect(http.StatusForbidden, http.MethodDelete, "/api/hotels/"+id(hotel.ID), nil, nil)
+	agent.expect(http.StatusForbidden, http.MethodPost, "/api/car-rentals", fiber.Map{"name": "X"}, nil)
+	agent.expect(http.StatusForbidden, http.MethodDelete, "/api/receipts/"+id(receipt.ID), nil, nil)
+	agent.expect(http.StatusForbidden, http.MethodGet, "/api/trash", nil, nil)
+	agent.expect(http.StatusForbidden, http.MethodGet, "/api/audit", nil, nil)
+
+	manager.expect(http.StatusOK, http.MethodDelete, "/api/receipts/"+id(receipt.ID), nil, nil)
+	manager.expect(http.StatusOK, http.MethodGet, "/api/audit", nil, nil)
+	manager.expect(http.StatusForbidden, http.MethodGet, "/api/users", nil, nil)
+}
+
+func TestUserRoutes(t *testing.T) {
+	s := newTestServer(t)
+
+	var user models.User
+	s.expect(http.StatusCreated, http.MethodPost, "/api/users", fiber.Map{
+		"email": " Agent@Example.com ", "name": "Agent", "password": "long-enough",
+	}, &user)
+	if user.Email != "agent@example.com" || user.Role != models.RoleAgent {
+		t.Fatalf("created user = %+v, want a lowercased agent", user)
+	}
+	s.expect(http.StatusConflict, http.MethodPost, "/api/users", fiber.Map{"email": "agent@example.com", "password": "long-enough"}, nil)
+	var body fiber.Map
+	s.expect(http.StatusBadRequest, http.MethodPost, "/api/users", fiber.Map{"email": "bad", "password": "short", "role": "owner"}, &body)
+	for _, field := range []string{"email", "password", "role"} {
+		if !hasField(body, field) {
+			t.Fatalf("field errors %v, want %s", errorFields(body), field)
+		}
+	}
+
+	s.anonymous().expect(http.StatusOK, http.MethodPost, "/api/auth/login", fiber.Map{"email": "agent@example.com", "password": "long-enough"}, nil)
+
+	path := "/api/users/" + id(user.ID)
+	var updated models.User
+	s.expect(http.StatusOK, http.MethodPut, path, fiber.Map{"role": models.RoleManager, "name": "Manager"}, &updated)
+	if updated.Role != models.RoleManager || updated.Name != "Manager" {
+		t.Fatalf("updated user = %+v", updated)
+	}
+	s.expect(http.StatusConflict, http.MethodPut, path, fiber.Map{"email": "admin@example.com"}, nil)
+	var users []models.User
+	s.expect(http.StatusOK, http.MethodGet, "/api/users", nil, &users)
+	if len(users) != 2 {
+		t.Fatalf("users = %d, want 2", len(users))
+	}
+
+	var me models.User
+	s.expect(http.StatusOK, http.MethodGet, "/api/auth/me", nil, &me)
+	self := "/api/users/" + id(me.ID)
+	s.expect(http.StatusBadRequest, http.MethodPut, self, fiber.Map{"role": models.RoleAgent}, nil)
+	s.expect(http.StatusBadRequest, http.MethodDelete, self, nil, nil)
+
+	s.expect(http.StatusOK, http.MethodDelete, path, nil, nil)
+	s.expect(http.StatusNotFound, http.MethodDelete, path, nil, nil)
+	s.expect(http.StatusNotFound, http.MethodPut, path, fiber.Map{"name": "Ghost"}, nil)
+}
+
+func TestAuditRoutes(t *testing.T) {
+	s := newTestServer(t)
+	hotel := s.createHotel("Palazzo", "BARI")
+	s.expect(http.StatusOK, http.MethodPut, "/api/hotels/"+id(hotel.ID), fiber.Map{
+		"name": "Palazzo Nuovo", "city": "BARI", "address": "Via Roma 1", "type": "hotel", "stars": 4,
+	}, nil)
+
+	var me models.User
+	s.expect(http.StatusOK, http.MethodGet, "/api/auth/me", nil, &me)
+	var logs []models.AuditLog
+	s.expect(http.StatusOK, http.MethodGet, "/api/audit?entity=hotel&id="+id(hotel.ID)+"&actor="+id(me.ID), nil, &logs)
+	if len(logs) != 2 || logs[0].Action != "update" || logs[1].Action != "create" {
+		t.Fatalf("hotel audit = %+v, want update then create", logs)
+	}
+	if logs[0].ActorEmail != me.Email {
+		t.Fatalf("actor = %q, want %q", logs[0].ActorEmail, me.Email)
+	}
+	s.expect(http.StatusOK, http.MethodGet, "/api/audit?action=create&limit=1", nil, &logs)
+	if len(logs) != 1 {
+		t.Fatalf("limited audit = %d entries, want 1", len(logs))
+	}
+	s.expect(http.StatusBadRequest, http.MethodGet, "/api/audit?id=abc", nil, nil)
+	s.expect(http.StatusBadRequest, http.MethodGet, "/api/audit?actor=abc", nil, nil)
+}
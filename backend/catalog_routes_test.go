@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Otabek228101/mehmon/config"
+	"github.com/Otabek228101/mehmon/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestHotelRoutes(t *testing.T) {
+	s := newTestServer(t)
+	bari := s.createHotel("Palazzo", "BARI")
+	s.createHotel("Duomo", "MILANO")
+
+	var hotels []models.Hotel
+	s.expect(http.StatusOK, http.MethodGet, "/api/hotels", nil, &hotels)
+	if len(hotels) != 2 {
+		t.Fatalf("hotels = %d, want 2", len(hotels))
+	}
+	s.expect(http.StatusOK, http.MethodGet, "/api/hotels?city=bar", nil, &hotels)
+	if len(hotels) != 1 || hotels[0].ID != bari.ID {
+		t.Fatalf("hotels in bar* = %+v, want Palazzo", hotels)
+	}
+
+	path := "/api/hotels/" + id(bari.ID)
+	var updated models.Hotel
+	s.expect(http.StatusOK, http.MethodPut, path, fiber.Map{
+		"name": "Palazzo Nuovo", "city": "BARI", "address": "Via Roma 2", "type": "apartment", "stars": 5, "breakfast": true,
+	}, &updated)
+	if updated.Name != "Palazzo Nuovo" || updated.Stars != 5 || !updated.Breakfast {
+		t.Fatalf("updated hotel = %+v", updated)
+	}
+	var got models.Hotel
+	s.expect(http.StatusOK, http.MethodGet, path, nil, &got)
+	if got.Name != "Palazzo Nuovo" || got.Type != "apartment" {
+		t.Fatalf("stored hotel = %+v", got)
+	}
+
+	receipt := s.createReceipt(receiptPayload("Ann", "2026-05-01", fiber.Map{
+		"type": "hotel", "hotelId": bari.ID, "checkIn": "2026-05-10", "checkOut": "2026-05-12", "amount": 100,
+	}))
+	s.createReceipt(receiptPayload("Bob", "2026-05-01", hotelStay("Elsewhere", "2026-05-10", "2026-05-12", 100)))
+	var page receiptPage
+	s.expect(http.StatusOK, http.MethodGet, path+"/receipts", nil, &page)
+	if page.Total != 1 || page.Data[0].ID != receipt.ID {
+		t.Fatalf("hotel receipts = %+v, want Ann's receipt", page.Data)
+	}
+
+	var body fiber.Map
+	s.expect(http.StatusBadRequest, http.MethodPost, "/api/hotels", fiber.Map{"type": "castle", "stars": 9}, &body)
+	for _, field := range []string{"name", "city", "address", "stars", "type"} {
+		if !hasField(body, field) {
+			t.Fatalf("field errors %v, want %s", errorFields(body), field)
+		}
+	}
+	s.expect(http.StatusBadRequest, http.MethodPut, path, fiber.Map{"name": "No city"}, nil)
+	s.expect(http.StatusBadRequest, http.MethodPost, "/api/hotels", "not an object", nil)
+
+	s.expect(http.StatusOK, http.MethodDelete, path, nil, nil)
+	s.expect(http.StatusNotFound, http.MethodGet, path, nil, nil)
+	s.expect(http.StatusNotFound, http.MethodPut, path, fiber.Map{"name": "X", "city": "Y", "address": "Z", "stars": 3}, nil)
+	s.expect(http.StatusNotFound, http.MethodDelete, path, nil, nil)
+	s.expect(http.StatusNotFound, http.MethodGet, path+"/receipts", nil, nil)
+}
+
+func TestHotelImages(t *testing.T) {
+	s := newTestServer(t)
+	hotel := s.createHotel("Palazzo", "BARI")
+	path := "/api/hotels/" + id(hotel.ID) + "/images"
+	png := []byte("\x89PNG\r\n\x1a\nfake")
+
+	var uploaded struct {
+		Uploaded []struct {
+			ID        uint   `json:"id"`
+			Path      string `json:"path"`
+			Mime      string `json:"mime"`
+			SortOrder int    `json:"sortOrder"`
+		} `json:"uploaded"`
+	}
+	status := s.upload(path, "files", map[string][]byte{"front.png": png}, &uploaded)
+	if status != http.StatusOK || len(uploaded.Uploaded) != 1 {
+		t.Fatalf("upload: status %d, %d images", status, len(uploaded.Uploaded))
+	}
+	if img := uploaded.Uploaded[0]; img.Mime != "image/png" || img.SortOrder != 1 || !strings.HasPrefix(img.Path, "/uploads/hotels/") {
+		t.Fatalf("uploaded image = %+v", img)
+	}
+	if status := s.upload(path, "file", map[string][]byte{"lobby.bmp": []byte("lobby")}, &uploaded); status != http.StatusOK {
+		t.Fatalf("second upload: status %d", status)
+	}
+	if img := uploaded.Uploaded[0]; img.Mime != "image/jpeg" || img.SortOrder != 2 {
+		t.Fatalf("second image = %+v, want a jpeg after the first", img)
+	}
+	stored, err := os.ReadFile(filepath.Join(config.Current.UploadDir, "hotels", id(hotel.ID), id(hotel.ID)+"_1.png"))
+	if err != nil || !bytes.Equal(stored, png) {
+		t.Fatalf("stored file: %v", err)
+	}
+
+	var images struct {
+		Images []fiber.Map `json:"images"`
+	}
+	s.expect(http.StatusOK, http.MethodGet, path, nil, &images)
+	if len(images.Images) != 2 {
+		t.Fatalf("images = %d, want 2", len(images.Images))
+	}
+	var inline struct {
+		Images []string `json:"images"`
+	}
+	s.expect(http.StatusOK, http.MethodGet, path+"/base64?limit=1", nil, &inline)
+	if len(inline.Images) != 1 || !strings.HasPrefix(inline.Images[0], "data:image/png;base64,") {
+		t.Fatalf("inline images = %v, want the png as a data URL", inline.Images)
+	}
+	var withImages models.Hotel
+	s.expect(http.StatusOK, http.MethodGet, "/api/hotels/"+id(hotel.ID), nil, &withImages)
+	if len(withImages.Images) != 2 {
+		t.Fatalf("hotel images = %d, want 2", len(withImages.Images))
+	}
+
+	if status := s.upload(path, "files", nil, nil); status != http.StatusBadRequest {
+		t.Fatalf("empty upload: status %d, want 400", status)
+	}
+	if status := s.upload("/api/hotels/999/images", "files", map[string][]byte{"a.png": png}, nil); status != http.StatusNotFound {
+		t.Fatalf("upload to missing hotel: status %d, want 404", status)
+	}
+	config.Current.UploadMaxFiles = 1
+	if status := s.upload(path, "files", map[string][]byte{"a.png": png, "b.png": png}, nil); status != http.StatusBadRequest {
+		t.Fatalf("too many files: status %d, want 400", status)
+	}
+	config.Current.UploadMaxFileSize = 4
+	if status := s.upload(path, "files", map[string][]byte{"a.png": png}, nil); status != http.StatusRequestEntityTooLarge {
+		t.Fatalf("oversized file: status %d, want 413", status)
+	}
+	s.expect(http.StatusNotFound, http.MethodGet, "/api/hotels/999/images", nil, nil)
+	s.expect(http.StatusNotFound, http.MethodGet, "/api/hotels/999/images/base64", nil, nil)
+}
+
+func TestCarRentalRoutes(t *testing.T) {
+	s := newTestServer(t)
+
+	var rental models.CarRental
+	s.expect(http.StatusCreated, http.MethodPost, "/api/car-rentals", fiber.Map{"name": "Sixt"}, &rental)
+	var body fiber.Map
+	s.expect(http.StatusBadRequest, http.MethodPost, "/api/car-rentals", fiber.Map{"name": ""}, &body)
+	if !hasField(body, "name") {
+		t.Fatalf("field errors %v, want name", errorFields(body))
+	}
+
+	var rentals []models.CarRental
+	s.expect(http.StatusOK, http.MethodGet, "/api/car-rentals", nil, &rentals)
+	if len(rentals) != 1 || rentals[0].Name != "Sixt" {
+		t.Fatalf("car rentals = %+v, want Sixt", rentals)
+	}
+
+	receipt := s.createReceipt(receiptPayload("Ann", "2026-05-01", carRental(rental.ID)))
+	if a := receipt.Activities[0]; a.CarRentalID == nil || *a.CarRentalID != rental.ID || a.PropertyName != "Sixt" {
+		t.Fatalf("activity = %+v, want it linked to Sixt", a)
+	}
+
+	path := "/api/car-rentals/" + id(rental.ID)
+	s.expect(http.StatusOK, http.MethodDelete, path, nil, nil)
+	s.expect(http.StatusNotFound, http.MethodDelete, path, nil, nil)
+	s.expect(http.StatusOK, http.MethodGet, "/api/car-rentals", nil, &rentals)
+	if len(rentals) != 0 {
+		t.Fatalf("car rentals = %d, want 0 after delete", len(rentals))
+	}
+	var got models.Receipt
+	s.expect(http.StatusOK, http.MethodGet, "/api/receipts/"+id(receipt.ID), nil, &got)
+	if got.Activities[0].CarRentalID != nil || got.Activities[0].PropertyName != "Sixt" {
+		t.Fatalf("activity after delete = %+v, want the link cleared and the name kept", got.Activities[0])
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Otabek228101/mehmon/database"
+	"github.com/Otabek228101/mehmon/models"
+	"github.com/Otabek228101/mehmon/repository"
+	"github.com/Otabek228101/mehmon/services"
+	"gorm.io/gorm"
+)
+
+// requireSchema stops maintenance commands from running against a schema that serve
+// would still migrate.
+func requireSchema() error {
+	states, err := database.MigrationStatus()
+	if err != nil {
+		return err
+	}
+	for _, s := range states {
+		if s.AppliedAt == nil {
+			return fmt.Errorf("migration %04d_%s is pending; run `migrate up` first", s.Version, s.Name)
+		}
+	}
+	return nil
+}
+
+func runSeed(args []string) error {
+	if len(args) > 0 {
+		return errors.New("seed takes no arguments")
+	}
+	if err := requireSchema(); err != nil {
+		return err
+	}
+	return services.SeedData(repository.NewGormStore(database.DB))
+}
+
+func runCreateUser(args []string) error {
+	flags := flag.NewFlagSet("create-user", flag.ContinueOnError)
+	var req models.UserRequest
+	flags.StringVar(&req.Email, "email", "", "email address used to sign in")
+	flags.StringVar(&req.Name, "name", "", "display name")
+	flags.StringVar(&req.Role, "role", models.RoleAgent, "agent, manager or admin")
+	flags.StringVar(&req.Password, "password", "", "password; read from stdin when omitted")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := requireSchema(); err != nil {
+		return err
+	}
+
+	if req.Password == "" {
+		fmt.Fprint(os.Stderr, "Password: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		req.Password = strings.TrimRight(line, "\r\n")
+	}
+	user, err := services.NewUserService(repository.NewGormStore(database.DB)).Create(nil, req)
+	if errors.Is(err, services.ErrEmailTaken) {
+		return fmt.Errorf("a user with email %s already exists", strings.ToLower(strings.TrimSpace(req.Email)))
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("created %s user %s (id %d)\n", user.Role, user.Email, user.ID)
+	return nil
+}
+
+func runRenumber(args []string) error {
+	if len(args) > 0 {
+		return errors.New("renumber takes no arguments")
+	}
+	if err := requireSchema(); err != nil {
+		return err
+	}
+	var changes []services.SequenceChange
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		changes, err = services.ResyncNumberSequences(tx)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		fmt.Println("number counters are in sync")
+	}
+	for _, c := range changes {
+		name := c.Name
+		if c.Period != "" {
+			name += " " + c.Period
+		}
+		fmt.Printf("%s: %d -> %d\n", name, c.From, c.To)
+	}
+	return nil
+}
+
+func runExport(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	output := flags.String("o", "", "file to write instead of stdout")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := requireSchema(); err != nil {
+		return err
+	}
+
+	data, err := services.ExportData(database.DB)
+	if err != nil {
+		return err
+	}
+	out := io.Writer(os.Stdout)
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(data); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "exported %d receipts, %d proposals, %d clients, %d hotels\n",
+		len(data.Receipts), len(data.Proposals), len(data.Clients), len(data.Hotels))
+	return nil
+}
+
+func runImport(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: import FILE")
+	}
+	if err := requireSchema(); err != nil {
+		return err
+	}
+
+	in := io.Reader(os.Stdin)
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	var data services.Export
+	if err := json.NewDecoder(in).Decode(&data); err != nil {
+		return fmt.Errorf("reading export: %w", err)
+	}
+
+	var counts services.ImportCounts
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		counts, err = services.ImportData(tx, &data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("imported %d receipts, %d proposals, %d clients, %d hotels, %d car rentals, %d exchange rates\n",
+		counts.Receipts, counts.Proposals, counts.Clients, counts.Hotels, counts.CarRentals, counts.ExchangeRates)
+	return nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/Otabek228101/mehmon/database"
+	"github.com/Otabek228101/mehmon/models"
+	"github.com/Otabek228101/mehmon/repository"
+	"github.com/Otabek228101/mehmon/services"
+	"gorm.io/gorm"
+)
+
+func TestImportLeavesOtherChildrenAlone(t *testing.T) {
+	s := newTestServer(t)
+	existing := s.createReceipt(receiptPayload("Ann", "2026-05-10", hotelStay("Hotel A", "2026-05-10", "2026-05-12", 100)))
+	taken := existing.Activities[0].ID
+
+	data := &services.Export{Version: 1, Receipts: []models.Receipt{{
+		ID:            existing.ID + 100,
+		ReceiptNumber: "X00001",
+		ClientName:    "Bob",
+		Activities:    []models.Activity{{ID: taken, ReceiptID: existing.ID + 100, Type: models.ActivityOther, Description: "Visa"}},
+		Payments:      []models.Payment{{ID: 1, ReceiptID: existing.ID + 100, Method: models.PaymentCash, Amount: models.MoneyFromCents(500)}},
+	}}}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		_, err := services.ImportData(tx, data)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+
+	var kept models.Activity
+	if err := database.DB.First(&kept, taken).Error; err != nil || kept.ReceiptID != existing.ID || kept.PropertyName != "Hotel A" {
+		t.Fatalf("activity %d = %+v, %v; want it left on receipt %d", taken, kept, err, existing.ID)
+	}
+	var imported []models.Activity
+	database.DB.Where("receipt_id = ?", existing.ID+100).Find(&imported)
+	if len(imported) != 1 || imported[0].ID == taken || imported[0].Description != "Visa" {
+		t.Fatalf("imported activities = %+v, want one with a fresh id", imported)
+	}
+
+	// Importing the same file again replaces the children instead of duplicating them.
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		_, err := services.ImportData(tx, data)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("second import: %v", err)
+	}
+	var activities, payments int64
+	database.DB.Model(&models.Activity{}).Where("receipt_id = ?", existing.ID+100).Count(&activities)
+	database.DB.Model(&models.Payment{}).Where("receipt_id = ?", existing.ID+100).Count(&payments)
+	if activities != 1 || payments != 1 {
+		t.Fatalf("after reimport: %d activities and %d payments, want 1 each", activities, payments)
+	}
+}
+
+func TestImportRejectsClashingNumbers(t *testing.T) {
+	s := newTestServer(t)
+	existing := s.createReceipt(receiptPayload("Ann", "2026-05-10"))
+
+	data := &services.Export{Version: 1,
+		Receipts: []models.Receipt{{ID: existing.ID + 100, ReceiptNumber: existing.ReceiptNumber, ClientName: "Bob"}},
+		Proposals: []models.Proposal{
+			{ID: 50, ProposalNumber: "P00009", ClientName: "Cem"},
+			{ID: 51, ProposalNumber: "P00009", ClientName: "Dan"},
+		},
+	}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		_, err := services.ImportData(tx, data)
+		return err
+	})
+	var clash *services.NumberClashError
+	if !errors.As(err, &clash) || len(clash.Clashes) != 2 {
+		t.Fatalf("import err = %v, want two number clashes", err)
+	}
+	if c := clash.Clashes[0]; c.Entity != "receipt" || c.Number != existing.ReceiptNumber || !slices.Equal(c.IDs, []uint{existing.ID, existing.ID + 100}) {
+		t.Fatalf("receipt clash = %+v", c)
+	}
+	if c := clash.Clashes[1]; c.Entity != "proposal" || c.Number != "P00009" || !slices.Equal(c.IDs, []uint{50, 51}) {
+		t.Fatalf("proposal clash = %+v", c)
+	}
+	var receipts int64
+	database.DB.Model(&models.Receipt{}).Count(&receipts)
+	if receipts != 1 {
+		t.Fatalf("receipts after rejected import = %d, want 1", receipts)
+	}
+
+	// Overwriting the existing receipt under its own id is not a clash.
+	data = &services.Export{Version: 1, Receipts: []models.Receipt{{ID: existing.ID, ReceiptNumber: existing.ReceiptNumber, ClientName: "Ann"}}}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		_, err := services.ImportData(tx, data)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("reimport of the same receipt: %v", err)
+	}
+}
+
+func TestSeedNumbersDocumentsAndUsesCreatedHotels(t *testing.T) {
+	s := newTestServer(t)
+	gone := s.createHotel("Gone", "BARI")
+	s.expect(200, "DELETE", "/api/hotels/"+fmt.Sprint(gone.ID), nil, nil)
+
+	store := repository.NewGormStore(database.DB)
+	if err := services.SeedData(store); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	if err := services.SeedData(store); err != nil {
+		t.Fatalf("second seed: %v", err)
+	}
+
+	var receipts []models.Receipt
+	database.DB.Find(&receipts)
+	if len(receipts) != 1 || receipts[0].ReceiptNumber != "M00001" {
+		t.Fatalf("seeded receipts = %+v, want one numbered M00001", receipts)
+	}
+	var proposals []models.Proposal
+	database.DB.Find(&proposals)
+	if len(proposals) != 1 || proposals[0].ProposalNumber != "P00001" {
+		t.Fatalf("seeded proposals = %+v, want one numbered P00001", proposals)
+	}
+	var hotel models.Hotel
+	if err := database.DB.First(&hotel, proposals[0].HotelID).Error; err != nil || hotel.ID == gone.ID {
+		t.Fatalf("proposal hotel %d: %v; want a seeded hotel", proposals[0].HotelID, err)
+	}
+}
@@ -0,0 +1,376 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// Config is the runtime configuration. Values come from, in increasing priority: the
+// defaults, the config file (.env format, optional unless named with -config), the
+// environment, and command-line flags.
+type Config struct {
+	ListenAddr string
+	LogLevel   string
+
+	Database DatabaseConfig
+
+	CORSOrigins []string
+
+	UploadDir         string
+	UploadMaxFileSize int64
+	UploadMaxFiles    int
+
+	ReceiptNumber  NumberFormat
+	ProposalNumber NumberFormat
+
+	BaseCurrency      string
+	ExchangeRatesFile string
+
+	JWTSecret     string
+	AdminEmail    string
+	AdminPassword string
+}
+
+type DatabaseConfig struct {
+	// URL is a full Postgres connection string; when set the other connection fields are ignored.
+	URL      string
+	Host     string
+	Port     int
+	User     string
+	Password string
+	Name     string
+	SSLMode  string
+
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
+// NumberFormat describes document numbers such as M00042 or M2026-00042.
+type NumberFormat struct {
+	Prefix  string
+	Padding int
+	Yearly  bool
+}
+
+// Log levels, from quietest to most verbose. Info logs every SQL statement.
+const (
+	LogSilent = "silent"
+	LogError  = "error"
+	LogWarn   = "warn"
+	LogInfo   = "info"
+)
+
+var sslModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+// Current is the configuration in use. It holds the defaults until Load replaces it.
+var Current = Default()
+
+func Default() *Config {
+	return &Config{
+		ListenAddr: ":8080",
+		LogLevel:   LogInfo,
+		Database: DatabaseConfig{
+			Host:            "localhost",
+			Port:            5432,
+			User:            "postgres",
+			Name:            "mehmon",
+			SSLMode:         "disable",
+			MaxOpenConns:    25,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
+		},
+		CORSOrigins:       []string{"*"},
+		UploadDir:         "uploads",
+		UploadMaxFileSize: 5 << 20,
+		UploadMaxFiles:    10,
+		ReceiptNumber:     NumberFormat{Prefix: "M", Padding: 5},
+		ProposalNumber:    NumberFormat{Prefix: "P", Padding: 5},
+		BaseCurrency:      "USD",
+	}
+}
+
+// Load builds the configuration from the global flags at the start of args and returns
+// it with the arguments left after them, which name the command to run.
+func Load(args []string) (*Config, []string, error) {
+	flags := flag.NewFlagSet("mehmon", flag.ContinueOnError)
+	file := flags.String("config", "", "configuration file in .env format (default .env, if present)")
+	listen := flags.String("listen", "", "address to serve HTTP on, e.g. :8080")
+	dsn := flags.String("dsn", "", "Postgres connection string")
+	logLevel := flags.String("log-level", "", "silent, error, warn or info")
+	uploadDir := flags.String("upload-dir", "", "directory for uploaded hotel images")
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	values := map[string]string{}
+	path, required := *file, true
+	if path == "" {
+		path, required = ".env", false
+	}
+	fileValues, err := godotenv.Read(path)
+	if err != nil && (required || !errors.Is(err, os.ErrNotExist)) {
+		return nil, nil, fmt.Errorf("reading config file %s: %w", path, err)
+	}
+	for k, v := range fileValues {
+		values[k] = v
+	}
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			values[k] = v
+		}
+	}
+	overrides := map[string]*string{"LISTEN_ADDR": listen, "DATABASE_URL": dsn, "LOG_LEVEL": logLevel, "UPLOAD_DIR": uploadDir}
+	for k, v := range overrides {
+		if *v != "" {
+			values[k] = *v
+		}
+	}
+
+	cfg, err := fromValues(values)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cfg, flags.Args(), nil
+}
+
+// fromValues applies the values over the defaults and validates the result.
+func fromValues(values map[string]string) (*Config, error) {
+	cfg := Default()
+	p := parser{values: values}
+
+	if port := values["SERVER_PORT"]; port != "" {
+		cfg.ListenAddr = ":" + port
+	}
+	p.str("LISTEN_ADDR", &cfg.ListenAddr)
+	p.str("LOG_LEVEL", &cfg.LogLevel)
+	cfg.LogLevel = strings.ToLower(cfg.LogLevel)
+
+	db := &cfg.Database
+	p.str("DATABASE_URL", &db.URL)
+	p.str("DB_HOST", &db.Host)
+	p.int("DB_PORT", &db.Port)
+	p.str("DB_USER", &db.User)
+	p.str("DB_PASSWORD", &db.Password)
+	p.str("DB_NAME", &db.Name)
+	p.str("DB_SSLMODE", &db.SSLMode)
+	p.int("DB_MAX_OPEN_CONNS", &db.MaxOpenConns)
+	p.int("DB_MAX_IDLE_CONNS", &db.MaxIdleConns)
+	p.duration("DB_CONN_MAX_LIFETIME", &db.ConnMaxLifetime)
+
+	if v, ok := values["CORS_ALLOWED_ORIGINS"]; ok && strings.TrimSpace(v) != "" {
+		cfg.CORSOrigins = nil
+		for _, origin := range strings.Split(v, ",") {
+			if origin = strings.TrimSpace(origin); origin != "" {
+				cfg.CORSOrigins = append(cfg.CORSOrigins, origin)
+			}
+		}
+	}
+
+	p.str("UPLOAD_DIR", &cfg.UploadDir)
+	p.size("UPLOAD_MAX_FILE_SIZE", &cfg.UploadMaxFileSize)
+	p.int("UPLOAD_MAX_FILES", &cfg.UploadMaxFiles)
+
+	p.numberFormat("RECEIPT", &cfg.ReceiptNumber)
+	p.numberFormat("PROPOSAL", &cfg.ProposalNumber)
+
+	p.str("BASE_CURRENCY", &cfg.BaseCurrency)
+	cfg.BaseCurrency = strings.ToUpper(strings.TrimSpace(cfg.BaseCurrency))
+	p.str("EXCHANGE_RATES_FILE", &cfg.ExchangeRatesFile)
+
+	p.str("JWT_SECRET", &cfg.JWTSecret)
+	p.str("ADMIN_EMAIL", &cfg.AdminEmail)
+	cfg.AdminEmail = strings.ToLower(strings.TrimSpace(cfg.AdminEmail))
+	p.str("ADMIN_PASSWORD", &cfg.AdminPassword)
+
+	return cfg, errors.Join(append(p.errs, cfg.Validate()...)...)
+}
+
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// Validate lists every invalid setting.
+func (c *Config) Validate() []error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	check(c.ListenAddr != "", "LISTEN_ADDR must not be empty")
+	check(oneOf(c.LogLevel, LogSilent, LogError, LogWarn, LogInfo), "LOG_LEVEL must be one of silent, error, warn, info")
+	if c.Database.URL != "" {
+		u, err := url.Parse(c.Database.URL)
+		check(err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql") || strings.Contains(c.Database.URL, "="),
+			"DATABASE_URL must be a postgres:// URL or a key=value connection string")
+	} else {
+		check(c.Database.Host != "", "DB_HOST must not be empty")
+		check(c.Database.Port > 0 && c.Database.Port < 65536, "DB_PORT must be between 1 and 65535")
+		check(c.Database.Name != "", "DB_NAME must not be empty")
+		check(oneOf(c.Database.SSLMode, sslModes...), "DB_SSLMODE must be one of %s", strings.Join(sslModes, ", "))
+	}
+	check(c.Database.MaxOpenConns > 0, "DB_MAX_OPEN_CONNS must be positive")
+	check(c.Database.MaxIdleConns >= 0 && c.Database.MaxIdleConns <= c.Database.MaxOpenConns,
+		"DB_MAX_IDLE_CONNS must be between 0 and DB_MAX_OPEN_CONNS")
+	check(c.Database.ConnMaxLifetime >= 0, "DB_CONN_MAX_LIFETIME must not be negative")
+	check(len(c.CORSOrigins) > 0, "CORS_ALLOWED_ORIGINS must not be empty")
+	check(c.UploadDir != "", "UPLOAD_DIR must not be empty")
+	check(c.UploadMaxFileSize > 0, "UPLOAD_MAX_FILE_SIZE must be positive")
+	check(c.UploadMaxFiles > 0, "UPLOAD_MAX_FILES must be positive")
+	check(c.ReceiptNumber.Padding > 0 && c.ReceiptNumber.Padding <= 12, "RECEIPT_NUMBER_PADDING must be between 1 and 12")
+	check(c.ProposalNumber.Padding > 0 && c.ProposalNumber.Padding <= 12, "PROPOSAL_NUMBER_PADDING must be between 1 and 12")
+	check(currencyCode.MatchString(c.BaseCurrency), "BASE_CURRENCY must be a 3-letter currency code")
+	check(len(c.JWTSecret) >= minSecretLength && !isPlaceholder(c.JWTSecret),
+		"JWT_SECRET must be at least %d random bytes and not a placeholder, e.g. the output of `openssl rand -hex 32`", minSecretLength)
+	if c.AdminPassword != "" {
+		check(len(c.AdminPassword) >= minAdminPasswordLength && !isPlaceholder(c.AdminPassword),
+			"ADMIN_PASSWORD must be at least %d characters and not a placeholder", minAdminPasswordLength)
+	}
+	return errs
+}
+
+// minSecretLength is the shortest JWT_SECRET accepted. The secret signs both login tokens
+// and public receipt links.
+const minSecretLength = 32
+
+// minAdminPasswordLength matches the minimum for passwords set through the API.
+const minAdminPasswordLength = 8
+
+// placeholders are values from examples and templates, compared ignoring case and punctuation.
+var placeholders = []string{"changeme", "secret", "password", "jwtsecret", "supersecret", "yoursecret", "yoursecretkey", "example", "admin"}
+
+func isPlaceholder(value string) bool {
+	var b strings.Builder
+	for _, r := range strings.ToLower(value) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	normalized := b.String()
+	if strings.Contains(normalized, "changeme") {
+		return true
+	}
+	return oneOf(normalized, placeholders...)
+}
+
+// DSN is the connection string passed to the Postgres driver.
+func (d DatabaseConfig) DSN() string {
+	if d.URL != "" {
+		return d.URL
+	}
+	return fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=%s",
+		d.Host, d.User, d.Password, d.Name, d.Port, d.SSLMode)
+}
+
+// BodyLimit is the largest request body the server accepts: a full batch of image uploads
+// plus room for the multipart framing.
+func (c *Config) BodyLimit() int {
+	return int(c.UploadMaxFileSize)*c.UploadMaxFiles + 1<<20
+}
+
+// FormatSize writes a byte count in the largest unit that divides it, e.g. "5 MB".
+func FormatSize(n int64) string {
+	switch {
+	case n >= 1<<20 && n%(1<<20) == 0:
+		return fmt.Sprintf("%d MB", n>>20)
+	case n >= 1<<10 && n%(1<<10) == 0:
+		return fmt.Sprintf("%d KB", n>>10)
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
+
+func oneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
+
+// parser reads typed values, collecting an error per malformed key.
+type parser struct {
+	values map[string]string
+	errs   []error
+}
+
+func (p *parser) lookup(key string) (string, bool) {
+	v, ok := p.values[key]
+	return strings.TrimSpace(v), ok && strings.TrimSpace(v) != ""
+}
+
+func (p *parser) str(key string, dest *string) {
+	if v, ok := p.lookup(key); ok {
+		*dest = v
+	}
+}
+
+func (p *parser) int(key string, dest *int) {
+	if v, ok := p.lookup(key); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			p.errs = append(p.errs, fmt.Errorf("%s must be a whole number, got %q", key, v))
+			return
+		}
+		*dest = n
+	}
+}
+
+func (p *parser) bool(key string, dest *bool) {
+	if v, ok := p.lookup(key); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			p.errs = append(p.errs, fmt.Errorf("%s must be true or false, got %q", key, v))
+			return
+		}
+		*dest = b
+	}
+}
+
+func (p *parser) duration(key string, dest *time.Duration) {
+	if v, ok := p.lookup(key); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			p.errs = append(p.errs, fmt.Errorf("%s must be a duration such as 30m, got %q", key, v))
+			return
+		}
+		*dest = d
+	}
+}
+
+var sizeUnits = map[string]int64{"": 1, "B": 1, "KB": 1 << 10, "MB": 1 << 20, "GB": 1 << 30}
+
+// size reads a byte count such as 5242880, 512KB or 5MB.
+func (p *parser) size(key string, dest *int64) {
+	v, ok := p.lookup(key)
+	if !ok {
+		return
+	}
+	upper := strings.ToUpper(strings.ReplaceAll(v, " ", ""))
+	number := strings.TrimRight(upper, "KMGB")
+	unit, known := sizeUnits[upper[len(number):]]
+	n, err := strconv.ParseInt(number, 10, 64)
+	if !known || err != nil {
+		p.errs = append(p.errs, fmt.Errorf("%s must be a size such as 5MB, got %q", key, v))
+		return
+	}
+	*dest = n * unit
+}
+
+// numberFormat reads <NAME>_NUMBER_PREFIX, _PADDING and _YEARLY. The prefix may be set
+// to an empty string, so it is read even when blank.
+func (p *parser) numberFormat(name string, dest *NumberFormat) {
+	if v, ok := p.values[name+"_NUMBER_PREFIX"]; ok {
+		dest.Prefix = strings.TrimSpace(v)
+	}
+	p.int(name+"_NUMBER_PADDING", &dest.Padding)
+	p.bool(name+"_NUMBER_YEARLY", &dest.Yearly)
+}
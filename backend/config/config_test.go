@@ -0,0 +1,111 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+// withSecret adds the one setting that has no default.
+func withSecret(values map[string]string) map[string]string {
+	values["JWT_SECRET"] = testSecret
+	return values
+}
+
+func TestDefaultsAreValid(t *testing.T) {
+	cfg, err := fromValues(withSecret(map[string]string{}))
+	if err != nil {
+		t.Fatalf("defaults: %v", err)
+	}
+	want := "host=localhost user=postgres password= dbname=mehmon port=5432 sslmode=disable"
+	if got := cfg.Database.DSN(); got != want {
+		t.Errorf("DSN = %q, want %q", got, want)
+	}
+}
+
+func TestValuesOverrideDefaults(t *testing.T) {
+	cfg, err := fromValues(withSecret(map[string]string{
+		"SERVER_PORT":           "9000",
+		"DB_SSLMODE":            "require",
+		"DB_CONN_MAX_LIFETIME":  "1h",
+		"CORS_ALLOWED_ORIGINS":  "https://a.example, https://b.example",
+		"UPLOAD_MAX_FILE_SIZE":  "512KB",
+		"RECEIPT_NUMBER_PREFIX": "",
+		"RECEIPT_NUMBER_YEARLY": "true",
+		"BASE_CURRENCY":         "eur",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ListenAddr != ":9000" {
+		t.Errorf("ListenAddr = %q, want :9000 from SERVER_PORT", cfg.ListenAddr)
+	}
+	if !strings.HasSuffix(cfg.Database.DSN(), "sslmode=require") {
+		t.Errorf("DSN = %q, want sslmode=require", cfg.Database.DSN())
+	}
+	if cfg.Database.ConnMaxLifetime != time.Hour {
+		t.Errorf("ConnMaxLifetime = %v, want 1h", cfg.Database.ConnMaxLifetime)
+	}
+	if len(cfg.CORSOrigins) != 2 || cfg.CORSOrigins[1] != "https://b.example" {
+		t.Errorf("CORSOrigins = %q", cfg.CORSOrigins)
+	}
+	if cfg.UploadMaxFileSize != 512<<10 {
+		t.Errorf("UploadMaxFileSize = %d, want %d", cfg.UploadMaxFileSize, 512<<10)
+	}
+	if cfg.ReceiptNumber != (NumberFormat{Prefix: "", Padding: 5, Yearly: true}) {
+		t.Errorf("ReceiptNumber = %+v", cfg.ReceiptNumber)
+	}
+	if cfg.BaseCurrency != "EUR" {
+		t.Errorf("BaseCurrency = %q, want EUR", cfg.BaseCurrency)
+	}
+
+	cfg, err = fromValues(withSecret(map[string]string{"SERVER_PORT": "9000", "LISTEN_ADDR": "127.0.0.1:8081"}))
+	if err != nil || cfg.ListenAddr != "127.0.0.1:8081" {
+		t.Errorf("ListenAddr = %q, %v; want LISTEN_ADDR to win over SERVER_PORT", cfg.ListenAddr, err)
+	}
+}
+
+func TestInvalidValuesAreAllReported(t *testing.T) {
+	_, err := fromValues(map[string]string{
+		"DB_PORT":              "five",
+		"DB_SSLMODE":           "sometimes",
+		"LOG_LEVEL":            "loud",
+		"UPLOAD_MAX_FILE_SIZE": "5 parsecs",
+		"BASE_CURRENCY":        "dollars",
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, key := range []string{"DB_PORT", "DB_SSLMODE", "LOG_LEVEL", "UPLOAD_MAX_FILE_SIZE", "BASE_CURRENCY"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("error does not mention %s: %v", key, err)
+		}
+	}
+}
+
+func TestSecretsMustNotBePlaceholders(t *testing.T) {
+	cases := []struct {
+		name   string
+		values map[string]string
+		key    string
+	}{
+		{"missing secret", map[string]string{}, "JWT_SECRET"},
+		{"short secret", map[string]string{"JWT_SECRET": "s3cr3t"}, "JWT_SECRET"},
+		{"placeholder secret", map[string]string{"JWT_SECRET": "change-me-change-me-change-me-change-me"}, "JWT_SECRET"},
+		{"placeholder admin password", withSecret(map[string]string{"ADMIN_PASSWORD": "Change-Me"}), "ADMIN_PASSWORD"},
+		{"short admin password", withSecret(map[string]string{"ADMIN_PASSWORD": "abc"}), "ADMIN_PASSWORD"},
+	}
+	for _, tc := range cases {
+		_, err := fromValues(tc.values)
+		if err == nil || !strings.Contains(err.Error(), tc.key) {
+			t.Errorf("%s: error = %v, want one about %s", tc.name, err, tc.key)
+		}
+	}
+
+	cfg, err := fromValues(withSecret(map[string]string{"ADMIN_PASSWORD": "a-real-first-password"}))
+	if err != nil || cfg.JWTSecret != testSecret {
+		t.Errorf("valid secrets: %v", err)
+	}
+}
@@ -1,12 +1,11 @@
 package database
 
 import (
-	"fmt"
 	"log"
-	"os"
-	"time"
 
+	"github.com/Otabek228101/mehmon/config"
 	"github.com/Otabek228101/mehmon/models"
+	"golang.org/x/crypto/bcrypt"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -15,117 +14,78 @@ import (
 var DB *gorm.DB
 
 func Connect() {
+	cfg := config.Current
 	var err error
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
-		os.Getenv("DB_HOST"),
-		os.Getenv("DB_USER"),
-		os.Getenv("DB_PASSWORD"),
-		os.Getenv("DB_NAME"),
-		os.Getenv("DB_PORT"))
-
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+	DB, err = gorm.Open(postgres.Open(cfg.Database.DSN()), &gorm.Config{
+		Logger: logger.Default.LogMode(LogLevel(cfg.LogLevel)),
 	})
-
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
 
+	sqlDB, err := DB.DB()
+	if err != nil {
+		log.Fatal("Failed to get database handle:", err)
+	}
+	sqlDB.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.Database.ConnMaxLifetime)
+
 	log.Println("Database connected successfully")
 }
 
-func Migrate() {
-	if err := DB.AutoMigrate(
-		&models.Receipt{},
-		&models.Activity{},
-		&models.Hotel{},
-		&models.HotelImage{},
-		&models.CarRental{},
-		&models.Proposal{},
-		&models.ProposalRoom{},
-	); err != nil {
-		log.Fatal("Failed to migrate database:", err)
+// LogLevel maps a config log level to gorm's; unknown levels log everything.
+func LogLevel(level string) logger.LogLevel {
+	switch level {
+	case config.LogSilent:
+		return logger.Silent
+	case config.LogError:
+		return logger.Error
+	case config.LogWarn:
+		return logger.Warn
 	}
-	log.Println("Database migrated successfully")
+	return logger.Info
 }
 
-func SeedData() {
-	var hotelCount, rentalCount, receiptCount, proposalCount int64
-
-	DB.Model(&models.Hotel{}).Count(&hotelCount)
-	DB.Model(&models.CarRental{}).Count(&rentalCount)
-	DB.Model(&models.Receipt{}).Count(&receiptCount)
-	DB.Model(&models.Proposal{}).Count(&proposalCount)
+// Text searched by /api/search. The GIN indexes of migration 0002 are built over exactly
+// these expressions, so queries must use them verbatim for Postgres to pick the index.
+const (
+	ReceiptSearchDocument  = "coalesce(receipts.receipt_number, '') || ' ' || coalesce(receipts.client_name, '') || ' ' || coalesce(receipts.client_email, '') || ' ' || coalesce(receipts.client_phone, '')"
+	ActivitySearchDocument = "coalesce(activities.type, '') || ' ' || coalesce(activities.property_name, '') || ' ' || coalesce(activities.property_address, '') || ' ' || coalesce(activities.pickup_location, '') || ' ' || coalesce(activities.dropoff_location, '') || ' ' || coalesce(activities.transfer_type, '') || ' ' || coalesce(activities.description, '') || ' ' || coalesce(activities.provider, '') || ' ' || coalesce(activities.reference, '')"
+	ProposalSearchDocument = "coalesce(proposals.proposal_number, '') || ' ' || coalesce(proposals.client_name, '') || ' ' || coalesce(proposals.status, '')"
+)
 
-	if hotelCount == 0 {
-		hotels := []models.Hotel{
-			{Name: "UNA Hotels Regina Bari", City: "BARI", GroupName: "UNA Hotels", Type: "hotel", Address: "SP57 Torre a Mare / Noicattaro, Noicattaro (BA)", Stars: 4, Breakfast: true, WebsiteLink: "https://booking.unaitalianhospitality.com/?adult=1&arrive=2025-06-05&chain=33116&child=0&depart=2025-06-06&level=chain&locale=it-IT&rooms=1", LocationLink: "https://goo.gl/maps/example1"},
-			{Name: "UNA Hotels Bologna Centro", City: "BOLOGNA", GroupName: "UNA Hotels", Type: "hotel", Address: "Viale Pietro Pietramellara, 41, Bologna", Stars: 4, Breakfast: true, WebsiteLink: "https://booking.unaitalianhospitality.com/?adult=1&arrive=2025-06-05&chain=33116&child=0&depart=2025-06-06&level=chain&locale=it-IT&rooms=1", LocationLink: "https://goo.gl/maps/example2"},
-			{Name: "Principi di Piemonte | UNA Esperienze", City: "TORINO", GroupName: "UNA Hotels", Type: "hotel", Address: "Via Piero Gobetti, 15, 10123 Torino TO", Stars: 5, Breakfast: true, WebsiteLink: "https://booking.unaitalianhospitality.com/?adult=1&arrive=2025-06-05&chain=33116&child=0&depart=2025-06-06&level=chain&locale=it-IT&rooms=1", LocationLink: "https://goo.gl/maps/example3"},
-		}
-		for i, hotel := range hotels {
-			if err := DB.Create(&hotel).Error; err != nil {
-				log.Printf("Failed to create hotel %d: %v", i+1, err)
-			}
-		}
-		log.Println("Hotels seeded successfully")
-	}
+func SearchVector(document string) string {
+	return "to_tsvector('simple'::regconfig, " + document + ")"
+}
 
-	if rentalCount == 0 {
-		carRentals := []models.CarRental{
-			{Name: "Hertz"},
-			{Name: "Avis"},
-		}
-		for i, rental := range carRentals {
-			if err := DB.Create(&rental).Error; err != nil {
-				log.Printf("Failed to create car rental %d: %v", i+1, err)
-			}
-		}
-		log.Println("Car rentals seeded successfully")
-	}
+// EnsureAdmin creates the first account from ADMIN_EMAIL and ADMIN_PASSWORD when there are
+// no users, and promotes ADMIN_EMAIL back to admin if no admin is left.
+func EnsureAdmin() {
+	var userCount int64
+	DB.Model(&models.User{}).Count(&userCount)
 
-	if receiptCount == 0 {
-		receipts := []models.Receipt{
-			{ReceiptNumber: "R00001", ClientName: "John Doe", ClientEmail: "john@example.com", ClientPhone: "+1234567890", ReceiptDate: time.Now(), AmountPaid: 500.0},
-		}
-		for i, receipt := range receipts {
-			if err := DB.Create(&receipt).Error; err != nil {
-				log.Printf("Failed to create receipt %d: %v", i+1, err)
+	if userCount == 0 {
+		email, password := config.Current.AdminEmail, config.Current.AdminPassword
+		if email != "" && password != "" {
+			hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+			if err != nil {
+				log.Printf("Failed to hash admin password: %v", err)
+			} else if err := DB.Create(&models.User{Email: email, Name: "Admin", Role: models.RoleAdmin, PasswordHash: string(hash)}).Error; err != nil {
+				log.Printf("Failed to create admin user: %v", err)
+			} else {
+				log.Printf("Admin user %s created", email)
 			}
+		} else {
+			log.Println("No users exist; set ADMIN_EMAIL and ADMIN_PASSWORD to create the first account")
 		}
-		log.Println("Receipts seeded successfully")
 	}
 
-	if proposalCount == 0 {
-		proposals := []models.Proposal{
-			{
-				ProposalNumber: "P00001",
-				ClientName:     "Test Client",
-				Guests:         2,
-				CheckIn:        time.Now(),
-				CheckOut:       time.Now().Add(72 * time.Hour),
-				Breakfast:      true,
-				FreeCancel:     true,
-				Price:          450.0,
-				HotelID:        1,
-			},
+	var adminCount int64
+	DB.Model(&models.User{}).Where("role = ?", models.RoleAdmin).Count(&adminCount)
+	if email := config.Current.AdminEmail; adminCount == 0 && email != "" {
+		if res := DB.Model(&models.User{}).Where("email = ?", email).Update("role", models.RoleAdmin); res.RowsAffected > 0 {
+			log.Printf("User %s promoted to admin", email)
 		}
-		for _, proposal := range proposals {
-			if err := DB.Create(&proposal).Error; err != nil {
-				log.Printf("Failed to create proposal %s: %v", proposal.ProposalNumber, err)
-				continue
-			}
-			rooms := []models.ProposalRoom{
-				{ProposalID: proposal.ID, Count: 1},
-			}
-			for _, room := range rooms {
-				if err := DB.Create(&room).Error; err != nil {
-					log.Printf("Failed to create room for proposal %d: %v", proposal.ID, err)
-				}
-			}
-		}
-		log.Println("Proposals seeded successfully")
 	}
-
-	log.Println("Database seeding completed")
 }
@@ -0,0 +1,263 @@
+package database
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Otabek228101/mehmon/config"
+	"gorm.io/gorm"
+)
+
+// Migrations live in migrations/<dialect>/ as NNNN_name.up.sql and NNNN_name.down.sql.
+// Every version needs both files, and each dialect directory needs the same versions.
+// {{base_currency}} in a migration is replaced by the quoted BASE_CURRENCY.
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+// baselineVersion creates the original tables; its down file drops them.
+const baselineVersion = 1
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// SchemaMigration records an applied migration.
+type SchemaMigration struct {
+	Version   int       `gorm:"column:version;primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"column:name;not null"`
+	AppliedAt time.Time `gorm:"column:applied_at;not null"`
+}
+
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// MigrationState is one line of `migrate status`. Migrations applied by a newer build have
+// no files here and are reported as missing.
+type MigrationState struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+	Missing   bool
+}
+
+func loadMigrations(dialect string) ([]Migration, error) {
+	dir := path.Join("migrations", dialect)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for %s: %w", dialect, err)
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		file := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(file, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(file, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+		base := strings.TrimSuffix(file, "."+direction+".sql")
+		number, name, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(number)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: name must look like 0001_description.%s.sql", file, direction)
+		}
+		body, err := fs.ReadFile(migrationFiles, path.Join(dir, file))
+		if err != nil {
+			return nil, err
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.Name, name)
+		}
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if strings.TrimSpace(m.Up) == "" || strings.TrimSpace(m.Down) == "" {
+			return nil, fmt.Errorf("migration %04d_%s needs both an up and a down file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+func expandMigration(sql string) (string, error) {
+	if !strings.Contains(sql, "{{base_currency}}") {
+		return sql, nil
+	}
+	base := config.Current.BaseCurrency
+	if !currencyCode.MatchString(base) {
+		return "", fmt.Errorf("BASE_CURRENCY %q is not a 3-letter currency code", base)
+	}
+	return strings.ReplaceAll(sql, "{{base_currency}}", "'"+base+"'"), nil
+}
+
+func appliedMigrations() (map[int]SchemaMigration, error) {
+	timestamp := "datetime"
+	if DB.Dialector.Name() == "postgres" {
+		timestamp = "timestamptz"
+	}
+	ddl := "CREATE TABLE IF NOT EXISTS schema_migrations (version bigint PRIMARY KEY, name text NOT NULL, applied_at " + timestamp + " NOT NULL)"
+	if err := DB.Exec(ddl).Error; err != nil {
+		return nil, err
+	}
+	var rows []SchemaMigration
+	if err := DB.Order("version").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int]SchemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// MigrateUp applies every pending migration in version order, each in its own transaction,
+// and returns the ones it applied.
+func MigrateUp() ([]Migration, error) {
+	migrations, err := loadMigrations(DB.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		up, err := expandMigration(m.Up)
+		if err != nil {
+			return done, fmt.Errorf("migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+		err = DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(up).Error; err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// MigrateDown reverts the latest steps applied migrations, newest first. Reverting the
+// baseline drops every table, so it is refused unless force is set.
+func MigrateDown(steps int, force bool) ([]Migration, error) {
+	migrations, err := loadMigrations(DB.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[int]Migration, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = m
+	}
+	versions := make([]int, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+	if len(versions) > steps {
+		versions = versions[:steps]
+	}
+	if !force && len(versions) > 0 && versions[len(versions)-1] == baselineVersion {
+		return nil, fmt.Errorf("reverting migration %04d drops every table and its data; pass --force to do it anyway", baselineVersion)
+	}
+
+	var done []Migration
+	for _, version := range versions {
+		m, ok := known[version]
+		if !ok {
+			return done, fmt.Errorf("migration %04d_%s was applied by a newer build and has no down file here", version, applied[version].Name)
+		}
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(m.Down).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&SchemaMigration{}, version).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("reverting migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+func MigrationStatus() ([]MigrationState, error) {
+	migrations, err := loadMigrations(DB.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]MigrationState, 0, len(migrations))
+	for _, m := range migrations {
+		state := MigrationState{Version: m.Version, Name: m.Name}
+		if row, ok := applied[m.Version]; ok {
+			state.AppliedAt = &row.AppliedAt
+			delete(applied, m.Version)
+		}
+		states = append(states, state)
+	}
+	for _, row := range applied {
+		appliedAt := row.AppliedAt
+		states = append(states, MigrationState{Version: row.Version, Name: row.Name, AppliedAt: &appliedAt, Missing: true})
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Version < states[j].Version })
+	return states, nil
+}
+
+// Migrate brings the schema up to date at startup.
+func Migrate() {
+	applied, err := MigrateUp()
+	for _, m := range applied {
+		log.Printf("Applied migration %04d_%s", m.Version, m.Name)
+	}
+	if err != nil {
+		log.Fatal("Failed to migrate database:", err)
+	}
+	log.Println("Database migrated successfully")
+}
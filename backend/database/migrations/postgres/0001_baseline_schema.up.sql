@@ -0,0 +1,82 @@
+-- The schema AutoMigrate created before versioned migrations, column for column. Databases
+-- from those releases already have these tables and adopt this migration unchanged; every
+-- later addition lives in a migration of its own.
+
+CREATE TABLE IF NOT EXISTS receipts (
+    id bigserial PRIMARY KEY,
+    receipt_number text,
+    client_name text,
+    client_email text,
+    client_phone text,
+    receipt_date timestamptz,
+    amount_paid decimal,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS activities (
+    id bigserial PRIMARY KEY,
+    receipt_id bigint CONSTRAINT fk_receipts_activities REFERENCES receipts (id),
+    type text,
+    property_name text,
+    property_address text,
+    check_in timestamptz,
+    check_out timestamptz,
+    amount decimal,
+    pickup_location text,
+    dropoff_location text,
+    transfer_type text,
+    description text,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS hotels (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL,
+    city text NOT NULL,
+    group_name text,
+    type text,
+    stars bigint,
+    address text NOT NULL,
+    location_link text,
+    website_link text,
+    breakfast boolean DEFAULT false
+);
+
+CREATE TABLE IF NOT EXISTS hotel_images (
+    id bigserial PRIMARY KEY,
+    hotel_id bigint CONSTRAINT fk_hotels_images REFERENCES hotels (id),
+    path text,
+    mime text,
+    sort_order bigint DEFAULT 0,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_hotel_images_hotel_id ON hotel_images (hotel_id);
+
+CREATE TABLE IF NOT EXISTS car_rentals (
+    id bigserial PRIMARY KEY,
+    name text
+);
+
+CREATE TABLE IF NOT EXISTS proposals (
+    id bigserial PRIMARY KEY,
+    proposal_number text,
+    client_name text,
+    guests bigint,
+    check_in timestamptz,
+    check_out timestamptz,
+    price decimal,
+    breakfast boolean DEFAULT false,
+    free_cancel boolean DEFAULT false,
+    hotel_id bigint CONSTRAINT fk_proposals_hotel REFERENCES hotels (id),
+    created_at timestamptz,
+    updated_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS proposal_rooms (
+    id bigserial PRIMARY KEY,
+    proposal_id bigint CONSTRAINT fk_proposals_rooms REFERENCES proposals (id),
+    count bigint
+);
@@ -0,0 +1,38 @@
+-- Back to the baseline schema. Money columns keep their exact numeric type.
+DROP INDEX IF EXISTS idx_proposals_search;
+DROP INDEX IF EXISTS idx_activities_search;
+DROP INDEX IF EXISTS idx_receipts_search;
+DROP INDEX IF EXISTS idx_proposal_rooms_proposal_id;
+
+DROP TABLE IF EXISTS payments;
+DROP TABLE IF EXISTS proposal_status_changes;
+
+DROP INDEX IF EXISTS idx_proposals_proposal_number;
+ALTER TABLE proposals
+    DROP COLUMN IF EXISTS deleted_at,
+    DROP COLUMN IF EXISTS status,
+    DROP COLUMN IF EXISTS client_id,
+    DROP COLUMN IF EXISTS receipt_id,
+    DROP COLUMN IF EXISTS currency;
+
+DROP INDEX IF EXISTS idx_activities_receipt_id;
+ALTER TABLE activities
+    DROP COLUMN IF EXISTS car_rental_id,
+    DROP COLUMN IF EXISTS hotel_id,
+    DROP COLUMN IF EXISTS reference,
+    DROP COLUMN IF EXISTS provider,
+    DROP COLUMN IF EXISTS currency;
+
+DROP INDEX IF EXISTS idx_receipts_receipt_number;
+ALTER TABLE receipts
+    DROP COLUMN IF EXISTS deleted_at,
+    DROP COLUMN IF EXISTS created_by_id,
+    DROP COLUMN IF EXISTS client_id,
+    DROP COLUMN IF EXISTS proposal_id,
+    DROP COLUMN IF EXISTS currency;
+
+DROP TABLE IF EXISTS exchange_rates;
+DROP TABLE IF EXISTS number_sequences;
+DROP TABLE IF EXISTS audit_logs;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS clients;
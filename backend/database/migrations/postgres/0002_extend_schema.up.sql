@@ -0,0 +1,151 @@
+-- Tables and columns added since the baseline. Development databases that ran AutoMigrate
+-- from commits made before versioned migrations landed may already have some of them, so
+-- every statement is skipped when its object exists, and columns are added before any
+-- index or constraint that uses them.
+-- Currency has no database default: existing rows get BASE_CURRENCY and the code sets it
+-- on every insert. AutoMigrate added the column with a USD default, which is dropped.
+
+CREATE TABLE IF NOT EXISTS clients (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL,
+    email text,
+    phone text,
+    notes text,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_clients_email ON clients (email);
+CREATE INDEX IF NOT EXISTS idx_clients_phone ON clients (phone);
+
+CREATE TABLE IF NOT EXISTS users (
+    id bigserial PRIMARY KEY,
+    email text NOT NULL,
+    name text,
+    role text NOT NULL DEFAULT 'agent',
+    password_hash text NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id bigserial PRIMARY KEY,
+    actor_id bigint,
+    actor_email text,
+    entity text,
+    entity_id bigint,
+    action text,
+    before_state text,
+    after_state text,
+    diff text,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_actor_id ON audit_logs (actor_id);
+CREATE INDEX IF NOT EXISTS idx_audit_entity ON audit_logs (entity, entity_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs (created_at);
+
+CREATE TABLE IF NOT EXISTS number_sequences (
+    name text,
+    period text,
+    value bigint NOT NULL,
+    PRIMARY KEY (name, period)
+);
+
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    currency varchar(3) PRIMARY KEY,
+    rate numeric NOT NULL,
+    source text,
+    updated_at timestamptz
+);
+
+ALTER TABLE receipts
+    ADD COLUMN IF NOT EXISTS currency varchar(3),
+    ADD COLUMN IF NOT EXISTS proposal_id bigint,
+    ADD COLUMN IF NOT EXISTS client_id bigint CONSTRAINT fk_receipts_client REFERENCES clients (id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS created_by_id bigint,
+    ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+UPDATE receipts SET currency = {{base_currency}} WHERE currency IS NULL;
+ALTER TABLE receipts ALTER COLUMN currency SET NOT NULL, ALTER COLUMN currency DROP DEFAULT;
+-- Numbers were not unique before 0002. Later duplicates keep their number with "-<id>"
+-- appended, and each rename is recorded in the audit log so it can be found and fixed.
+INSERT INTO audit_logs (entity, entity_id, action, diff, created_at)
+SELECT 'receipt', d.id, 'update',
+    json_build_object('receiptNumber', json_build_object('before', d.receipt_number, 'after', d.receipt_number || '-' || d.id))::text, CURRENT_TIMESTAMP
+FROM receipts d
+WHERE EXISTS (SELECT 1 FROM receipts o WHERE o.receipt_number = d.receipt_number AND o.id < d.id);
+UPDATE receipts SET receipt_number = receipt_number || '-' || id
+WHERE EXISTS (SELECT 1 FROM receipts o WHERE o.receipt_number = receipts.receipt_number AND o.id < receipts.id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_receipts_receipt_number ON receipts (receipt_number);
+CREATE INDEX IF NOT EXISTS idx_receipts_proposal_id ON receipts (proposal_id);
+CREATE INDEX IF NOT EXISTS idx_receipts_client_id ON receipts (client_id);
+CREATE INDEX IF NOT EXISTS idx_receipts_created_by_id ON receipts (created_by_id);
+CREATE INDEX IF NOT EXISTS idx_receipts_deleted_at ON receipts (deleted_at);
+
+ALTER TABLE activities
+    ADD COLUMN IF NOT EXISTS currency varchar(3),
+    ADD COLUMN IF NOT EXISTS provider text,
+    ADD COLUMN IF NOT EXISTS reference text,
+    ADD COLUMN IF NOT EXISTS hotel_id bigint CONSTRAINT fk_activities_hotel REFERENCES hotels (id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS car_rental_id bigint CONSTRAINT fk_activities_car_rental REFERENCES car_rentals (id) ON DELETE SET NULL;
+UPDATE activities SET currency = {{base_currency}} WHERE currency IS NULL;
+ALTER TABLE activities ALTER COLUMN currency SET NOT NULL, ALTER COLUMN currency DROP DEFAULT;
+CREATE INDEX IF NOT EXISTS idx_activities_receipt_id ON activities (receipt_id);
+CREATE INDEX IF NOT EXISTS idx_activities_hotel_id ON activities (hotel_id);
+CREATE INDEX IF NOT EXISTS idx_activities_car_rental_id ON activities (car_rental_id);
+
+ALTER TABLE proposals
+    ADD COLUMN IF NOT EXISTS currency varchar(3),
+    ADD COLUMN IF NOT EXISTS receipt_id bigint,
+    ADD COLUMN IF NOT EXISTS client_id bigint CONSTRAINT fk_proposals_client REFERENCES clients (id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS status text DEFAULT 'draft',
+    ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+UPDATE proposals SET currency = {{base_currency}} WHERE currency IS NULL;
+ALTER TABLE proposals ALTER COLUMN currency SET NOT NULL, ALTER COLUMN currency DROP DEFAULT;
+-- Numbers were not unique before 0002. Later duplicates keep their number with "-<id>"
+-- appended, and each rename is recorded in the audit log so it can be found and fixed.
+INSERT INTO audit_logs (entity, entity_id, action, diff, created_at)
+SELECT 'proposal', d.id, 'update',
+    json_build_object('proposalNumber', json_build_object('before', d.proposal_number, 'after', d.proposal_number || '-' || d.id))::text, CURRENT_TIMESTAMP
+FROM proposals d
+WHERE EXISTS (SELECT 1 FROM proposals o WHERE o.proposal_number = d.proposal_number AND o.id < d.id);
+UPDATE proposals SET proposal_number = proposal_number || '-' || id
+WHERE EXISTS (SELECT 1 FROM proposals o WHERE o.proposal_number = proposals.proposal_number AND o.id < proposals.id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_proposals_proposal_number ON proposals (proposal_number);
+CREATE INDEX IF NOT EXISTS idx_proposals_receipt_id ON proposals (receipt_id);
+CREATE INDEX IF NOT EXISTS idx_proposals_client_id ON proposals (client_id);
+CREATE INDEX IF NOT EXISTS idx_proposals_status ON proposals (status);
+CREATE INDEX IF NOT EXISTS idx_proposals_deleted_at ON proposals (deleted_at);
+
+CREATE INDEX IF NOT EXISTS idx_proposal_rooms_proposal_id ON proposal_rooms (proposal_id);
+
+CREATE TABLE IF NOT EXISTS proposal_status_changes (
+    id bigserial PRIMARY KEY,
+    proposal_id bigint CONSTRAINT fk_proposals_status_history REFERENCES proposals (id),
+    from_status text,
+    to_status text,
+    note text,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_proposal_status_changes_proposal_id ON proposal_status_changes (proposal_id);
+
+CREATE TABLE IF NOT EXISTS payments (
+    id bigserial PRIMARY KEY,
+    receipt_id bigint NOT NULL CONSTRAINT fk_receipts_payments REFERENCES receipts (id),
+    paid_at timestamptz NOT NULL,
+    method varchar(20) NOT NULL,
+    amount numeric(14,2),
+    reference text,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_payments_receipt_id ON payments (receipt_id);
+
+-- Money was stored as floating point; this is a no-op on columns that are already exact.
+ALTER TABLE receipts ALTER COLUMN amount_paid TYPE numeric(14,2) USING round(amount_paid::numeric, 2);
+ALTER TABLE activities ALTER COLUMN amount TYPE numeric(14,2) USING round(amount::numeric, 2);
+ALTER TABLE proposals ALTER COLUMN price TYPE numeric(14,2) USING round(price::numeric, 2);
+
+-- Full-text search for /api/search. The expressions must match the *SearchDocument
+-- constants in database.go verbatim for the planner to use these indexes.
+CREATE INDEX IF NOT EXISTS idx_receipts_search ON receipts USING GIN (to_tsvector('simple'::regconfig, coalesce(receipts.receipt_number, '') || ' ' || coalesce(receipts.client_name, '') || ' ' || coalesce(receipts.client_email, '') || ' ' || coalesce(receipts.client_phone, '')));
+CREATE INDEX IF NOT EXISTS idx_activities_search ON activities USING GIN (to_tsvector('simple'::regconfig, coalesce(activities.type, '') || ' ' || coalesce(activities.property_name, '') || ' ' || coalesce(activities.property_address, '') || ' ' || coalesce(activities.pickup_location, '') || ' ' || coalesce(activities.dropoff_location, '') || ' ' || coalesce(activities.transfer_type, '') || ' ' || coalesce(activities.description, '') || ' ' || coalesce(activities.provider, '') || ' ' || coalesce(activities.reference, '')));
+CREATE INDEX IF NOT EXISTS idx_proposals_search ON proposals USING GIN (to_tsvector('simple'::regconfig, coalesce(proposals.proposal_number, '') || ' ' || coalesce(proposals.client_name, '') || ' ' || coalesce(proposals.status, '')));
@@ -0,0 +1,21 @@
+-- Brings data written before 0002 up to date.
+
+-- Receipts from before payments existed get their amount_paid as a single payment.
+INSERT INTO payments (receipt_id, paid_at, method, amount, reference, created_at)
+SELECT r.id, r.receipt_date, 'cash', r.amount_paid, 'Opening balance', CURRENT_TIMESTAMP
+FROM receipts r
+WHERE r.amount_paid <> 0 AND NOT EXISTS (SELECT 1 FROM payments p WHERE p.receipt_id = r.id);
+
+-- Unlinked hotel stays whose property name matches exactly one catalog hotel.
+UPDATE activities SET hotel_id = (SELECT hotels.id FROM hotels WHERE LOWER(hotels.name) = LOWER(activities.property_name))
+WHERE hotel_id IS NULL AND type = 'hotel'
+    AND (SELECT COUNT(*) FROM hotels WHERE LOWER(hotels.name) = LOWER(activities.property_name)) = 1;
+
+-- Receipt creators recorded only in the audit trail.
+UPDATE receipts SET created_by_id = (
+    SELECT MIN(audit_logs.actor_id) FROM audit_logs
+    WHERE audit_logs.entity = 'receipt' AND audit_logs.entity_id = receipts.id AND audit_logs.action = 'create'
+)
+WHERE created_by_id IS NULL;
+
+UPDATE proposals SET status = 'draft' WHERE status IS NULL;
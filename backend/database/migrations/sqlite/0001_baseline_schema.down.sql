@@ -0,0 +1,8 @@
+-- Drops every table and all data in it. `migrate down` only runs this with --force.
+DROP TABLE IF EXISTS proposal_rooms;
+DROP TABLE IF EXISTS proposals;
+DROP TABLE IF EXISTS activities;
+DROP TABLE IF EXISTS receipts;
+DROP TABLE IF EXISTS car_rentals;
+DROP TABLE IF EXISTS hotel_images;
+DROP TABLE IF EXISTS hotels;
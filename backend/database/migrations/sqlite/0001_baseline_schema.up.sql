@@ -0,0 +1,80 @@
+-- SQLite twin of the Postgres baseline, for tests. Keep the two in step.
+
+CREATE TABLE IF NOT EXISTS receipts (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    receipt_number text,
+    client_name text,
+    client_email text,
+    client_phone text,
+    receipt_date datetime,
+    amount_paid real,
+    created_at datetime,
+    updated_at datetime
+);
+
+CREATE TABLE IF NOT EXISTS activities (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    receipt_id integer CONSTRAINT fk_receipts_activities REFERENCES receipts (id),
+    type text,
+    property_name text,
+    property_address text,
+    check_in datetime,
+    check_out datetime,
+    amount real,
+    pickup_location text,
+    dropoff_location text,
+    transfer_type text,
+    description text,
+    created_at datetime,
+    updated_at datetime
+);
+
+CREATE TABLE IF NOT EXISTS hotels (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    name text NOT NULL,
+    city text NOT NULL,
+    group_name text,
+    type text,
+    stars integer,
+    address text NOT NULL,
+    location_link text,
+    website_link text,
+    breakfast boolean DEFAULT false
+);
+
+CREATE TABLE IF NOT EXISTS hotel_images (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    hotel_id integer CONSTRAINT fk_hotels_images REFERENCES hotels (id),
+    path text,
+    mime text,
+    sort_order integer DEFAULT 0,
+    created_at datetime,
+    updated_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_hotel_images_hotel_id ON hotel_images (hotel_id);
+
+CREATE TABLE IF NOT EXISTS car_rentals (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    name text
+);
+
+CREATE TABLE IF NOT EXISTS proposals (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    proposal_number text,
+    client_name text,
+    guests integer,
+    check_in datetime,
+    check_out datetime,
+    price real,
+    breakfast boolean DEFAULT false,
+    free_cancel boolean DEFAULT false,
+    hotel_id integer CONSTRAINT fk_proposals_hotel REFERENCES hotels (id),
+    created_at datetime,
+    updated_at datetime
+);
+
+CREATE TABLE IF NOT EXISTS proposal_rooms (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    proposal_id integer CONSTRAINT fk_proposals_rooms REFERENCES proposals (id),
+    count integer
+);
@@ -0,0 +1,42 @@
+-- SQLite cannot drop an indexed column, so indexes go first.
+DROP INDEX IF EXISTS idx_proposal_rooms_proposal_id;
+
+DROP TABLE IF EXISTS payments;
+DROP TABLE IF EXISTS proposal_status_changes;
+
+DROP INDEX IF EXISTS idx_proposals_proposal_number;
+DROP INDEX IF EXISTS idx_proposals_receipt_id;
+DROP INDEX IF EXISTS idx_proposals_client_id;
+DROP INDEX IF EXISTS idx_proposals_status;
+DROP INDEX IF EXISTS idx_proposals_deleted_at;
+ALTER TABLE proposals DROP COLUMN deleted_at;
+ALTER TABLE proposals DROP COLUMN status;
+ALTER TABLE proposals DROP COLUMN client_id;
+ALTER TABLE proposals DROP COLUMN receipt_id;
+ALTER TABLE proposals DROP COLUMN currency;
+
+DROP INDEX IF EXISTS idx_activities_receipt_id;
+DROP INDEX IF EXISTS idx_activities_hotel_id;
+DROP INDEX IF EXISTS idx_activities_car_rental_id;
+ALTER TABLE activities DROP COLUMN car_rental_id;
+ALTER TABLE activities DROP COLUMN hotel_id;
+ALTER TABLE activities DROP COLUMN reference;
+ALTER TABLE activities DROP COLUMN provider;
+ALTER TABLE activities DROP COLUMN currency;
+
+DROP INDEX IF EXISTS idx_receipts_receipt_number;
+DROP INDEX IF EXISTS idx_receipts_proposal_id;
+DROP INDEX IF EXISTS idx_receipts_client_id;
+DROP INDEX IF EXISTS idx_receipts_created_by_id;
+DROP INDEX IF EXISTS idx_receipts_deleted_at;
+ALTER TABLE receipts DROP COLUMN deleted_at;
+ALTER TABLE receipts DROP COLUMN created_by_id;
+ALTER TABLE receipts DROP COLUMN client_id;
+ALTER TABLE receipts DROP COLUMN proposal_id;
+ALTER TABLE receipts DROP COLUMN currency;
+
+DROP TABLE IF EXISTS exchange_rates;
+DROP TABLE IF EXISTS number_sequences;
+DROP TABLE IF EXISTS audit_logs;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS clients;
@@ -0,0 +1,131 @@
+-- SQLite twin of the Postgres migration. Test databases are always created fresh, so
+-- columns are added unconditionally. SQLite cannot add a NOT NULL column without a default,
+-- so currency stays nullable here; the code always sets it.
+
+CREATE TABLE clients (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    name text NOT NULL,
+    email text,
+    phone text,
+    notes text,
+    created_at datetime,
+    updated_at datetime
+);
+CREATE INDEX idx_clients_email ON clients (email);
+CREATE INDEX idx_clients_phone ON clients (phone);
+
+CREATE TABLE users (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    email text NOT NULL,
+    name text,
+    role text NOT NULL DEFAULT 'agent',
+    password_hash text NOT NULL,
+    created_at datetime,
+    updated_at datetime
+);
+CREATE UNIQUE INDEX idx_users_email ON users (email);
+
+CREATE TABLE audit_logs (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    actor_id integer,
+    actor_email text,
+    entity text,
+    entity_id integer,
+    action text,
+    before_state text,
+    after_state text,
+    diff text,
+    created_at datetime
+);
+CREATE INDEX idx_audit_logs_actor_id ON audit_logs (actor_id);
+CREATE INDEX idx_audit_entity ON audit_logs (entity, entity_id);
+CREATE INDEX idx_audit_logs_created_at ON audit_logs (created_at);
+
+CREATE TABLE number_sequences (
+    name text,
+    period text,
+    value integer NOT NULL,
+    PRIMARY KEY (name, period)
+);
+
+CREATE TABLE exchange_rates (
+    currency varchar(3) PRIMARY KEY,
+    rate real NOT NULL,
+    source text,
+    updated_at datetime
+);
+
+ALTER TABLE receipts ADD COLUMN currency varchar(3);
+UPDATE receipts SET currency = {{base_currency}} WHERE currency IS NULL;
+ALTER TABLE receipts ADD COLUMN proposal_id integer;
+ALTER TABLE receipts ADD COLUMN client_id integer CONSTRAINT fk_receipts_client REFERENCES clients (id) ON DELETE SET NULL;
+ALTER TABLE receipts ADD COLUMN created_by_id integer;
+ALTER TABLE receipts ADD COLUMN deleted_at datetime;
+-- Numbers were not unique before 0002. Later duplicates keep their number with "-<id>"
+-- appended, and each rename is recorded in the audit log so it can be found and fixed.
+INSERT INTO audit_logs (entity, entity_id, action, diff, created_at)
+SELECT 'receipt', d.id, 'update',
+    json_object('receiptNumber', json_object('before', d.receipt_number, 'after', d.receipt_number || '-' || d.id)), CURRENT_TIMESTAMP
+FROM receipts d
+WHERE EXISTS (SELECT 1 FROM receipts o WHERE o.receipt_number = d.receipt_number AND o.id < d.id);
+UPDATE receipts SET receipt_number = receipt_number || '-' || id
+WHERE EXISTS (SELECT 1 FROM receipts o WHERE o.receipt_number = receipts.receipt_number AND o.id < receipts.id);
+CREATE UNIQUE INDEX idx_receipts_receipt_number ON receipts (receipt_number);
+CREATE INDEX idx_receipts_proposal_id ON receipts (proposal_id);
+CREATE INDEX idx_receipts_client_id ON receipts (client_id);
+CREATE INDEX idx_receipts_created_by_id ON receipts (created_by_id);
+CREATE INDEX idx_receipts_deleted_at ON receipts (deleted_at);
+
+ALTER TABLE activities ADD COLUMN currency varchar(3);
+UPDATE activities SET currency = {{base_currency}} WHERE currency IS NULL;
+ALTER TABLE activities ADD COLUMN provider text;
+ALTER TABLE activities ADD COLUMN reference text;
+ALTER TABLE activities ADD COLUMN hotel_id integer CONSTRAINT fk_activities_hotel REFERENCES hotels (id) ON DELETE SET NULL;
+ALTER TABLE activities ADD COLUMN car_rental_id integer CONSTRAINT fk_activities_car_rental REFERENCES car_rentals (id) ON DELETE SET NULL;
+CREATE INDEX idx_activities_receipt_id ON activities (receipt_id);
+CREATE INDEX idx_activities_hotel_id ON activities (hotel_id);
+CREATE INDEX idx_activities_car_rental_id ON activities (car_rental_id);
+
+ALTER TABLE proposals ADD COLUMN currency varchar(3);
+UPDATE proposals SET currency = {{base_currency}} WHERE currency IS NULL;
+ALTER TABLE proposals ADD COLUMN receipt_id integer;
+ALTER TABLE proposals ADD COLUMN client_id integer CONSTRAINT fk_proposals_client REFERENCES clients (id) ON DELETE SET NULL;
+ALTER TABLE proposals ADD COLUMN status text DEFAULT 'draft';
+ALTER TABLE proposals ADD COLUMN deleted_at datetime;
+-- Numbers were not unique before 0002. Later duplicates keep their number with "-<id>"
+-- appended, and each rename is recorded in the audit log so it can be found and fixed.
+INSERT INTO audit_logs (entity, entity_id, action, diff, created_at)
+SELECT 'proposal', d.id, 'update',
+    json_object('proposalNumber', json_object('before', d.proposal_number, 'after', d.proposal_number || '-' || d.id)), CURRENT_TIMESTAMP
+FROM proposals d
+WHERE EXISTS (SELECT 1 FROM proposals o WHERE o.proposal_number = d.proposal_number AND o.id < d.id);
+UPDATE proposals SET proposal_number = proposal_number || '-' || id
+WHERE EXISTS (SELECT 1 FROM proposals o WHERE o.proposal_number = proposals.proposal_number AND o.id < proposals.id);
+CREATE UNIQUE INDEX idx_proposals_proposal_number ON proposals (proposal_number);
+CREATE INDEX idx_proposals_receipt_id ON proposals (receipt_id);
+CREATE INDEX idx_proposals_client_id ON proposals (client_id);
+CREATE INDEX idx_proposals_status ON proposals (status);
+CREATE INDEX idx_proposals_deleted_at ON proposals (deleted_at);
+
+CREATE INDEX idx_proposal_rooms_proposal_id ON proposal_rooms (proposal_id);
+
+CREATE TABLE proposal_status_changes (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    proposal_id integer CONSTRAINT fk_proposals_status_history REFERENCES proposals (id),
+    from_status text,
+    to_status text,
+    note text,
+    created_at datetime
+);
+CREATE INDEX idx_proposal_status_changes_proposal_id ON proposal_status_changes (proposal_id);
+
+CREATE TABLE payments (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    receipt_id integer NOT NULL CONSTRAINT fk_receipts_payments REFERENCES receipts (id),
+    paid_at datetime NOT NULL,
+    method varchar(20) NOT NULL,
+    amount numeric(14,2),
+    reference text,
+    created_at datetime
+);
+CREATE INDEX idx_payments_receipt_id ON payments (receipt_id);
@@ -0,0 +1,2 @@
+-- Backfilled data stays; 0002's down removes the columns that hold it.
+SELECT 1;
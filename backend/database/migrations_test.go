@@ -0,0 +1,262 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Otabek228101/mehmon/config"
+	"github.com/Otabek228101/mehmon/models"
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func openTestDB(t *testing.T) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("test database handle: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	DB = db
+}
+
+// openPostgresTestDB points DB at a fresh schema of the database in TEST_POSTGRES_DSN and
+// drops the schema afterwards. The test is skipped when the variable is not set.
+func openPostgresTestDB(t *testing.T) {
+	t.Helper()
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open postgres: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("postgres handle: %v", err)
+	}
+	// One connection keeps the search_path set below for every statement.
+	sqlDB.SetMaxOpenConns(1)
+	schema := fmt.Sprintf("migrate_test_%d", time.Now().UnixNano())
+	if err := db.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		sqlDB.Close()
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Exec("DROP SCHEMA " + schema + " CASCADE")
+		sqlDB.Close()
+	})
+	if err := db.Exec("SET search_path TO " + schema).Error; err != nil {
+		t.Fatalf("set search_path: %v", err)
+	}
+	DB = db
+}
+
+func TestDialectsHaveTheSameMigrations(t *testing.T) {
+	postgres, err := loadMigrations("postgres")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlite, err := loadMigrations("sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(postgres) != len(sqlite) {
+		t.Fatalf("postgres has %d migrations, sqlite %d", len(postgres), len(sqlite))
+	}
+	for i := range postgres {
+		if postgres[i].Version != sqlite[i].Version || postgres[i].Name != sqlite[i].Name {
+			t.Errorf("migration %d: postgres %04d_%s, sqlite %04d_%s", i,
+				postgres[i].Version, postgres[i].Name, sqlite[i].Version, sqlite[i].Name)
+		}
+	}
+}
+
+func TestMigrateUpDownRoundTrip(t *testing.T) {
+	openTestDB(t)
+
+	applied, err := MigrateUp()
+	if err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	if len(applied) == 0 {
+		t.Fatal("up applied nothing on an empty database")
+	}
+	if again, err := MigrateUp(); err != nil || len(again) != 0 {
+		t.Fatalf("second up = %d migrations, %v; want none", len(again), err)
+	}
+	if err := DB.Create(&models.Hotel{Name: "H", City: "C", Address: "A"}).Error; err != nil {
+		t.Fatalf("insert after up: %v", err)
+	}
+
+	if reverted, err := MigrateDown(len(applied), false); err == nil || len(reverted) != 0 {
+		t.Fatalf("down past the baseline without force reverted %d migrations, err %v; want a refusal", len(reverted), err)
+	}
+	reverted, err := MigrateDown(len(applied), true)
+	if err != nil {
+		t.Fatalf("down: %v", err)
+	}
+	if len(reverted) != len(applied) {
+		t.Fatalf("reverted %d migrations, want %d", len(reverted), len(applied))
+	}
+	if DB.Migrator().HasTable("hotels") {
+		t.Fatal("hotels table still exists after reverting every migration")
+	}
+
+	states, err := MigrationStatus()
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	for _, s := range states {
+		if s.AppliedAt != nil {
+			t.Errorf("migration %04d_%s still marked applied", s.Version, s.Name)
+		}
+	}
+
+	if _, err := MigrateUp(); err != nil {
+		t.Fatalf("up after down: %v", err)
+	}
+}
+
+// A database created by AutoMigrate before migrations existed: the baseline tables with
+// float money and none of the later columns.
+func TestMigrateUpUpgradesBaselineDatabase(t *testing.T) {
+	openTestDB(t)
+	base := config.Current.BaseCurrency
+	config.Current.BaseCurrency = "EUR"
+	t.Cleanup(func() { config.Current.BaseCurrency = base })
+	baseline := []string{
+		"CREATE TABLE hotels (id integer PRIMARY KEY AUTOINCREMENT, name text NOT NULL, city text NOT NULL, group_name text, type text, stars integer, address text NOT NULL, location_link text, website_link text, breakfast boolean DEFAULT false)",
+		"CREATE TABLE car_rentals (id integer PRIMARY KEY AUTOINCREMENT, name text)",
+		"CREATE TABLE receipts (id integer PRIMARY KEY AUTOINCREMENT, receipt_number text, client_name text, client_email text, client_phone text, receipt_date datetime, amount_paid real, created_at datetime, updated_at datetime)",
+		"CREATE TABLE activities (id integer PRIMARY KEY AUTOINCREMENT, receipt_id integer REFERENCES receipts (id), type text, property_name text, property_address text, check_in datetime, check_out datetime, amount real, pickup_location text, dropoff_location text, transfer_type text, description text, created_at datetime, updated_at datetime)",
+		"CREATE TABLE proposals (id integer PRIMARY KEY AUTOINCREMENT, proposal_number text, client_name text, guests integer, check_in datetime, check_out datetime, price real, breakfast boolean DEFAULT false, free_cancel boolean DEFAULT false, hotel_id integer REFERENCES hotels (id), created_at datetime, updated_at datetime)",
+		"INSERT INTO hotels (name, city, address) VALUES ('Palazzo', 'BARI', 'Via Roma 1')",
+		`INSERT INTO receipts (receipt_number, client_name, receipt_date, amount_paid) VALUES ('R"1', 'Ann', '2024-05-01 00:00:00', 150.5)`,
+		"INSERT INTO activities (receipt_id, type, property_name, amount) VALUES (1, 'hotel', 'palazzo', 300)",
+		"INSERT INTO proposals (proposal_number, client_name, price, hotel_id) VALUES ('P-1', 'Ann', 300, 1)",
+		`INSERT INTO receipts (receipt_number, client_name, receipt_date, amount_paid) VALUES ('R"1', 'Bob', '2024-05-01 00:00:00', 0)`,
+		"INSERT INTO proposals (proposal_number, client_name, price, hotel_id) VALUES ('P-1', 'Bob', 200, 1)",
+	}
+	for _, statement := range baseline {
+		if err := DB.Exec(statement).Error; err != nil {
+			t.Fatalf("baseline %q: %v", statement, err)
+		}
+	}
+
+	if _, err := MigrateUp(); err != nil {
+		t.Fatalf("up on a baseline database: %v", err)
+	}
+
+	for table, column := range map[string]string{"receipts": "client_id", "activities": "hotel_id", "proposals": "status"} {
+		if !DB.Migrator().HasColumn(table, column) {
+			t.Errorf("%s.%s was not added", table, column)
+		}
+	}
+	var payment models.Payment
+	if err := DB.First(&payment, "receipt_id = ?", 1).Error; err != nil {
+		t.Fatalf("opening balance payment: %v", err)
+	}
+	if payment.Amount != models.MoneyFromFloat(150.5) || payment.Reference != "Opening balance" {
+		t.Fatalf("opening balance = %+v, want 150.50", payment)
+	}
+	var receipt models.Receipt
+	if err := DB.First(&receipt, 1).Error; err != nil {
+		t.Fatal(err)
+	}
+	if receipt.Currency != "EUR" {
+		t.Fatalf("receipt currency = %q, want the base currency EUR", receipt.Currency)
+	}
+	var activity models.Activity
+	if err := DB.First(&activity, 1).Error; err != nil {
+		t.Fatal(err)
+	}
+	if activity.Currency != "EUR" {
+		t.Fatalf("activity currency = %q, want the base currency EUR", activity.Currency)
+	}
+	if activity.HotelID == nil || *activity.HotelID != 1 {
+		t.Fatalf("activity hotel = %v, want the Palazzo", activity.HotelID)
+	}
+	var proposal models.Proposal
+	if err := DB.First(&proposal, 1).Error; err != nil {
+		t.Fatal(err)
+	}
+	if proposal.Status != models.ProposalStatusDraft {
+		t.Fatalf("proposal status = %q, want draft", proposal.Status)
+	}
+	var numbers []string
+	DB.Model(&models.Receipt{}).Order("id").Pluck("receipt_number", &numbers)
+	if len(numbers) != 2 || numbers[0] != `R"1` || numbers[1] != `R"1-2` {
+		t.Fatalf("receipt numbers = %v, want the duplicate renamed to R\"1-2", numbers)
+	}
+	DB.Model(&models.Proposal{}).Order("id").Pluck("proposal_number", &numbers)
+	if len(numbers) != 2 || numbers[0] != "P-1" || numbers[1] != "P-1-2" {
+		t.Fatalf("proposal numbers = %v, want the duplicate renamed to P-1-2", numbers)
+	}
+	var renames int64
+	DB.Model(&models.AuditLog{}).Where("entity IN ? AND entity_id = ?", []string{"receipt", "proposal"}, 2).Count(&renames)
+	if renames != 2 {
+		t.Fatalf("audit entries for renamed numbers = %d, want 2", renames)
+	}
+	var rename models.AuditLog
+	if err := DB.Where("entity = ? AND entity_id = ?", "receipt", 2).First(&rename).Error; err != nil {
+		t.Fatal(err)
+	}
+	var diff map[string]map[string]string
+	if err := json.Unmarshal([]byte(rename.Diff), &diff); err != nil || diff["receiptNumber"]["after"] != `R"1-2` {
+		t.Fatalf("rename diff = %s (%v), want receiptNumber renamed to R\"1-2", rename.Diff, err)
+	}
+	if err := DB.Exec(`INSERT INTO receipts (receipt_number, client_name) VALUES ('R"1', 'Cem')`).Error; err == nil {
+		t.Fatal("duplicate receipt number accepted after migrating")
+	}
+}
+
+func TestMigratePostgres(t *testing.T) {
+	openPostgresTestDB(t)
+	base := config.Current.BaseCurrency
+	config.Current.BaseCurrency = "EUR"
+	t.Cleanup(func() { config.Current.BaseCurrency = base })
+
+	applied, err := MigrateUp()
+	if err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	if again, err := MigrateUp(); err != nil || len(again) != 0 {
+		t.Fatalf("second up = %d migrations, %v; want none", len(again), err)
+	}
+	hotel := models.Hotel{Name: "H", City: "C", Address: "A"}
+	if err := DB.Create(&hotel).Error; err != nil {
+		t.Fatalf("insert hotel: %v", err)
+	}
+	if err := DB.Exec("INSERT INTO receipts (receipt_number, client_name, currency) VALUES ('R00001', 'Ann', 'EUR')").Error; err != nil {
+		t.Fatalf("insert receipt: %v", err)
+	}
+	if err := DB.Exec("INSERT INTO receipts (receipt_number, client_name, currency) VALUES ('R00001', 'Bob', 'EUR')").Error; err == nil {
+		t.Fatal("duplicate receipt number accepted")
+	}
+
+	reverted, err := MigrateDown(len(applied), true)
+	if err != nil {
+		t.Fatalf("down: %v", err)
+	}
+	if len(reverted) != len(applied) {
+		t.Fatalf("reverted %d migrations, want %d", len(reverted), len(applied))
+	}
+	if DB.Migrator().HasTable("hotels") {
+		t.Fatal("hotels table still exists after reverting every migration")
+	}
+	if _, err := MigrateUp(); err != nil {
+		t.Fatalf("up after down: %v", err)
+	}
+}
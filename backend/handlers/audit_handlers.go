@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/Otabek228101/mehmon/repository"
+	"github.com/Otabek228101/mehmon/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type AuditHandler struct {
+	audit *services.AuditService
+}
+
+func NewAuditHandler(audit *services.AuditService) *AuditHandler {
+	return &AuditHandler{audit: audit}
+}
+
+func (h *AuditHandler) List(c *fiber.Ctx) error {
+	filter := repository.AuditFilter{Entity: c.Query("entity"), Action: c.Query("action")}
+	if id := c.Query("id"); id != "" {
+		entityID, err := strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid id"})
+		}
+		v := uint(entityID)
+		filter.EntityID = &v
+	}
+	if actor := c.Query("actor"); actor != "" {
+		actorID, err := strconv.ParseUint(actor, 10, 64)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid actor"})
+		}
+		v := uint(actorID)
+		filter.ActorID = &v
+	}
+
+	limit := 100
+	if n, err := strconv.Atoi(c.Query("limit")); err == nil && n > 0 {
+		limit = min(n, 500)
+	}
+
+	logs, err := h.audit.List(filter, limit)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch audit log"})
+	}
+	return c.JSON(logs)
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Otabek228101/mehmon/middleware"
+	"github.com/Otabek228101/mehmon/models"
+	"github.com/Otabek228101/mehmon/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type AuthHandler struct {
+	auth *services.AuthService
+}
+
+func NewAuthHandler(auth *services.AuthService) *AuthHandler {
+	return &AuthHandler{auth: auth}
+}
+
+func (h *AuthHandler) Login(c *fiber.Ctx) error {
+	var request models.LoginRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request data"})
+	}
+
+	if err := request.Validate(); err != nil {
+		return validationFailed(c, err)
+	}
+
+	user, token, expiresAt, err := h.auth.Login(request.Email, request.Password)
+	if errors.Is(err, services.ErrInvalidCredentials) {
+		return c.Status(401).JSON(fiber.Map{"error": "Invalid email or password"})
+	}
+	if err != nil {
+		log.Printf("Failed to log in %s: %v", request.Email, err)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to log in"})
+	}
+
+	c.Cookie(&fiber.Cookie{
+		Name:     middleware.TokenCookie,
+		Value:    token,
+		Expires:  expiresAt,
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+		Secure:   c.Protocol() == "https",
+	})
+
+	return c.JSON(fiber.Map{
+		"token":     token,
+		"expiresAt": expiresAt,
+		"user":      user,
+	})
+}
+
+func (h *AuthHandler) Logout(c *fiber.Ctx) error {
+	c.Cookie(&fiber.Cookie{
+		Name:     middleware.TokenCookie,
+		Value:    "",
+		Expires:  time.Unix(0, 0),
+		HTTPOnly: true,
+	})
+	return c.JSON(fiber.Map{"message": "Logged out"})
+}
+
+func (h *AuthHandler) Me(c *fiber.Ctx) error {
+	return c.JSON(middleware.CurrentUser(c))
+}
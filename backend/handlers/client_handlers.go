@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+
+	"github.com/Otabek228101/mehmon/middleware"
+	"github.com/Otabek228101/mehmon/models"
+	"github.com/Otabek228101/mehmon/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+var clientSortColumns = map[string]sortColumn{
+	"name":    {Column: "name", Kind: sortString},
+	"created": {Column: "created_at", Kind: sortTime},
+}
+
+func clientSortValue(client models.Client, sort string) any {
+	if sort == "created" {
+		return client.CreatedAt
+	}
+	return client.Name
+}
+
+type ClientHandler struct {
+	clients    *services.ClientService
+	currencies *services.CurrencyService
+}
+
+func NewClientHandler(clients *services.ClientService, currencies *services.CurrencyService) *ClientHandler {
+	return &ClientHandler{clients: clients, currencies: currencies}
+}
+
+// clientFailed answers a client service error, reporting the existing client on a duplicate.
+func clientFailed(c *fiber.Ctx, err error, failed string) error {
+	var conflict *services.ClientConflictError
+	if errors.As(err, &conflict) {
+		return c.Status(409).JSON(fiber.Map{
+			"error":  "A client with this email, or this name and phone, already exists",
+			"client": conflict.Client,
+		})
+	}
+	return serviceFailed(c, err, "Client not found", failed)
+}
+
+func (h *ClientHandler) List(c *fiber.Ctx) error {
+	params, err := parseListParams(c, clientSortColumns, "name", false)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	clients, total, err := h.clients.List(c.Query("q"), params.repoPage())
+	if err != nil {
+		log.Printf("Error fetching clients: %v", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch clients"})
+	}
+
+	next := ""
+	if len(clients) > 0 {
+		last := clients[len(clients)-1]
+		next = params.nextCursor(len(clients), clientSortValue(last, params.Sort), last.ID)
+	}
+	return c.JSON(params.page(clients, total, next))
+}
+
+func (h *ClientHandler) Get(c *fiber.Ctx) error {
+	client, err := h.clients.Get(idParam(c))
+	if err != nil {
+		return serviceFailed(c, err, "Client not found", "Failed to fetch client")
+	}
+	return c.JSON(client)
+}
+
+func (h *ClientHandler) Create(c *fiber.Ctx) error {
+	var req models.ClientRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request data"})
+	}
+	client, err := h.clients.Create(middleware.CurrentUser(c), req)
+	if err != nil {
+		return clientFailed(c, err, "Failed to create client")
+	}
+	return c.Status(201).JSON(client)
+}
+
+func (h *ClientHandler) Update(c *fiber.Ctx) error {
+	var req models.ClientRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request data"})
+	}
+	client, err := h.clients.Update(middleware.CurrentUser(c), idParam(c), req)
+	if err != nil {
+		return clientFailed(c, err, "Failed to update client")
+	}
+	return c.JSON(client)
+}
+
+func (h *ClientHandler) Delete(c *fiber.Ctx) error {
+	if err := h.clients.Delete(middleware.CurrentUser(c), idParam(c)); err != nil {
+		return serviceFailed(c, err, "Client not found", "Failed to delete client")
+	}
+	return c.JSON(fiber.Map{"message": "Client deleted successfully"})
+}
+
+// History lists the client's receipts and proposals with what they have paid,
+// per currency and normalized into ?currency= (the base currency by default).
+func (h *ClientHandler) History(c *fiber.Ctx) error {
+	history, err := h.clients.History(idParam(c))
+	if err != nil {
+		return serviceFailed(c, err, "Client not found", "Failed to fetch client history")
+	}
+
+	rates, err := h.currencies.Rates()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch exchange rates"})
+	}
+	target, err := services.ResolveCurrency(rates, c.Query("currency"), services.BaseCurrency())
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"client":     history.Client,
+		"receipts":   history.Receipts,
+		"proposals":  history.Proposals,
+		"currency":   target,
+		"totalSpend": normalizeSums(rates, history.Paid, target),
+	})
+}
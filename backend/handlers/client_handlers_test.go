@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/Otabek228101/mehmon/database"
+	"github.com/Otabek228101/mehmon/models"
+)
+
+func TestGetClientsSearchesByName(t *testing.T) {
+	setupTestDB(t)
+	app := newTestApp()
+	for _, client := range []models.Client{
+		{Name: "Ann Lee", Email: "ann@example.com", Phone: "+998901234567"},
+		{Name: "Bob Stone", Email: "bob@example.com"},
+	} {
+		if err := database.DB.Create(&client).Error; err != nil {
+			t.Fatalf("create client: %v", err)
+		}
+	}
+
+	var page struct {
+		Data  []models.Client `json:"data"`
+		Total int64           `json:"total"`
+	}
+	for q, want := range map[string]string{"bob": "Bob Stone", "ANN": "Ann Lee", "90 123": "Ann Lee"} {
+		if status := doJSON(t, app, http.MethodGet, "/api/clients?q="+url.QueryEscape(q), nil, &page); status != http.StatusOK {
+			t.Fatalf("q=%s: status %d", q, status)
+		}
+		if page.Total != 1 || page.Data[0].Name != want {
+			t.Errorf("q=%s: clients = %+v, want %s only", q, page.Data, want)
+		}
+	}
+}
+
+func TestReceiptsSharingAPhoneKeepSeparateClients(t *testing.T) {
+	setupTestDB(t)
+	app := newTestApp()
+	create := func(name, email string) models.Receipt {
+		t.Helper()
+		p := receiptPayload(name, hotelActivity("A"))
+		p["clientEmail"] = email
+		p["clientPhone"] = "+998 71 200 00 00"
+		var receipt models.Receipt
+		if status := doJSON(t, app, http.MethodPost, "/api/receipts", p, &receipt); status != http.StatusCreated {
+			t.Fatalf("create %s: status %d", name, status)
+		}
+		return receipt
+	}
+
+	ann := create("Ann Lee", "ann@example.com")
+	bob := create("Bob Stone", "bob@example.com")
+	again := create("ann lee", "lee@example.com")
+	if ann.ClientID == nil || bob.ClientID == nil || again.ClientID == nil {
+		t.Fatalf("client ids = %v, %v, %v, want all linked", ann.ClientID, bob.ClientID, again.ClientID)
+	}
+	if *ann.ClientID == *bob.ClientID {
+		t.Fatalf("Ann and Bob share client %d through the office phone", *ann.ClientID)
+	}
+	if *again.ClientID != *ann.ClientID {
+		t.Fatalf("Ann's second receipt has client %d, want %d", *again.ClientID, *ann.ClientID)
+	}
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"log"
+
+	"github.com/Otabek228101/mehmon/config"
+	"github.com/Otabek228101/mehmon/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type CurrencyHandler struct {
+	currencies *services.CurrencyService
+}
+
+func NewCurrencyHandler(currencies *services.CurrencyService) *CurrencyHandler {
+	return &CurrencyHandler{currencies: currencies}
+}
+
+func (h *CurrencyHandler) Rates(c *fiber.Ctx) error {
+	rates, err := h.currencies.Rates()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch exchange rates"})
+	}
+	return c.JSON(fiber.Map{"base": services.BaseCurrency(), "rates": rates})
+}
+
+func (h *CurrencyHandler) Reload(c *fiber.Ctx) error {
+	path := config.Current.ExchangeRatesFile
+	if path == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "EXCHANGE_RATES_FILE is not configured"})
+	}
+	n, err := h.currencies.LoadFile(path)
+	if err != nil {
+		log.Printf("Failed to load exchange rates from %s: %v", path, err)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to load exchange rates", "details": err.Error()})
+	}
+	rates, err := h.currencies.Rates()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch exchange rates"})
+	}
+	return c.JSON(fiber.Map{"base": services.BaseCurrency(), "rates": rates, "loaded": n})
+}
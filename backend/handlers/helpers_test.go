@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/Otabek228101/mehmon/database"
+	"github.com/Otabek228101/mehmon/models"
+	"github.com/Otabek228101/mehmon/repository"
+	"github.com/Otabek228101/mehmon/services"
+	"github.com/glebarez/sqlite"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("test database handle: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	database.DB = db
+	database.Migrate()
+}
+
+// failCreates makes every INSERT into table fail while the returned flag is set.
+func failCreates(t *testing.T, table string) *bool {
+	t.Helper()
+	fail := new(bool)
+	err := database.DB.Callback().Create().Before("gorm:create").Register("test:fail_"+table, func(db *gorm.DB) {
+		if *fail && db.Statement.Table == table {
+			db.AddError(errors.New("forced insert failure"))
+		}
+	})
+	if err != nil {
+		t.Fatalf("register callback: %v", err)
+	}
+	return fail
+}
+
+func newTestApp() *fiber.App {
+	app := fiber.New()
+	api := app.Group("/api")
+	store := repository.NewGormStore(database.DB)
+	receipts := NewReceiptHandler(services.NewReceiptService(store))
+	proposals := NewProposalHandler(services.NewProposalService(store))
+
+	api.Post("/receipts", receipts.Create)
+	api.Get("/receipts/search", receipts.Search)
+	api.Get("/receipts/:id", receipts.Get)
+	api.Put("/receipts/:id", receipts.Update)
+	api.Delete("/receipts/:id", receipts.Delete)
+
+	api.Post("/proposals", proposals.Create)
+	api.Get("/proposals/:id", proposals.Get)
+	api.Put("/proposals/:id", proposals.Update)
+	api.Delete("/proposals/:id", proposals.Delete)
+
+	api.Get("/clients", NewClientHandler(services.NewClientService(store), services.NewCurrencyService(store)).List)
+	return app
+}
+
+func doJSON(t *testing.T, app *fiber.App, method, path string, body any, out any) int {
+	t.Helper()
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			t.Fatalf("marshal body: %v", err)
+		}
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("decode %s %s: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func createTestHotel(t *testing.T) models.Hotel {
+	t.Helper()
+	hotel := models.Hotel{Name: "Test Hotel", City: "BARI", Address: "Via Roma 1", Type: "hotel", Stars: 4}
+	if err := database.DB.Create(&hotel).Error; err != nil {
+		t.Fatalf("create hotel: %v", err)
+	}
+	return hotel
+}
+
+func countRows(t *testing.T, model any) int64 {
+	t.Helper()
+	var n int64
+	if err := database.DB.Model(model).Count(&n).Error; err != nil {
+		t.Fatalf("count rows: %v", err)
+	}
+	return n
+}
+
+func itoa(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Otabek228101/mehmon/repository"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 200
+)
+
+type sortKind int
+
+const (
+	sortString sortKind = iota
+	sortNumber
+	sortTime
+)
+
+type sortColumn struct {
+	Column string
+	Kind   sortKind
+}
+
+type listParams struct {
+	Page   int
+	Limit  int
+	Sort   string
+	Column sortColumn
+	Desc   bool
+	Cursor *listCursor
+}
+
+// listCursor marks the last row of a page for keyset pagination.
+type listCursor struct {
+	Value string `json:"v"`
+	ID    uint   `json:"id"`
+}
+
+func parseListParams(c *fiber.Ctx, columns map[string]sortColumn, defaultSort string, defaultDesc bool) (listParams, error) {
+	params := listParams{Page: 1, Limit: defaultPageLimit, Sort: defaultSort, Desc: defaultDesc}
+
+	if v := c.Query("page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return params, errors.New("page must be a positive integer")
+		}
+		params.Page = n
+	}
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return params, errors.New("limit must be a positive integer")
+		}
+		params.Limit = min(n, maxPageLimit)
+	}
+	if v := c.Query("sort"); v != "" {
+		params.Sort = v
+	}
+	column, ok := columns[params.Sort]
+	if !ok {
+		keys := make([]string, 0, len(columns))
+		for k := range columns {
+			keys = append(keys, k)
+		}
+		return params, fmt.Errorf("sort must be one of: %s", strings.Join(keys, ", "))
+	}
+	params.Column = column
+	switch strings.ToLower(c.Query("order")) {
+	case "":
+	case "asc":
+		params.Desc = false
+	case "desc":
+		params.Desc = true
+	default:
+		return params, errors.New("order must be 'asc' or 'desc'")
+	}
+
+	if v := c.Query("cursor"); v != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(v)
+		if err != nil {
+			return params, errors.New("invalid cursor")
+		}
+		var cursor listCursor
+		if err := json.Unmarshal(raw, &cursor); err != nil {
+			return params, errors.New("invalid cursor")
+		}
+		if _, err := column.parse(cursor.Value); err != nil {
+			return params, errors.New("invalid cursor")
+		}
+		params.Cursor = &cursor
+	}
+	return params, nil
+}
+
+func (s sortColumn) parse(v string) (any, error) {
+	switch s.Kind {
+	case sortNumber:
+		return strconv.ParseFloat(v, 64)
+	case sortTime:
+		return time.Parse(time.RFC3339Nano, v)
+	}
+	return v, nil
+}
+
+func (s sortColumn) format(v any) string {
+	switch v := v.(type) {
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+// repoPage is the page to ask a repository for; with a cursor it seeks past the cursor row
+// instead of using an offset.
+func (p listParams) repoPage() repository.Page {
+	page := repository.Page{Sort: p.Column.Column, Desc: p.Desc, Offset: (p.Page - 1) * p.Limit, Limit: p.Limit}
+	if p.Cursor != nil {
+		value, _ := p.Column.parse(p.Cursor.Value)
+		page.After = &repository.Cursor{Value: value, ID: p.Cursor.ID}
+	}
+	return page
+}
+
+func (p listParams) nextCursor(count int, value any, id uint) string {
+	if count < p.Limit {
+		return ""
+	}
+	raw, _ := json.Marshal(listCursor{Value: p.Column.format(value), ID: id})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func (p listParams) page(data any, total int64, nextCursor string) fiber.Map {
+	totalPages := (total + int64(p.Limit) - 1) / int64(p.Limit)
+	if totalPages == 0 {
+		totalPages = 1
+	}
+	return fiber.Map{
+		"data":       data,
+		"page":       p.Page,
+		"limit":      p.Limit,
+		"total":      total,
+		"totalPages": totalPages,
+		"nextCursor": nextCursor,
+	}
+}
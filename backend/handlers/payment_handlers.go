@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"github.com/Otabek228101/mehmon/middleware"
+	"github.com/Otabek228101/mehmon/models"
+	"github.com/Otabek228101/mehmon/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// receiptWithBalance is the receipt's own JSON plus its computed total, paid and balance.
+type receiptWithBalance struct {
+	models.Receipt
+	services.ReceiptBalance
+}
+
+func (h *ReceiptHandler) Payments(c *fiber.Ctx) error {
+	payments, err := h.receipts.Payments(idParam(c))
+	if err != nil {
+		return serviceFailed(c, err, "Receipt not found", "Failed to fetch payments")
+	}
+	return c.JSON(payments)
+}
+
+func (h *ReceiptHandler) AddPayment(c *fiber.Ctx) error {
+	var request models.PaymentRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+	}
+
+	payment, err := h.receipts.AddPayment(middleware.CurrentUser(c), idParam(c), request)
+	if err != nil {
+		return serviceFailed(c, err, "Receipt not found", "Failed to create payment")
+	}
+	return c.Status(201).JSON(payment)
+}
+
+func (h *ReceiptHandler) DeletePayment(c *fiber.Ctx) error {
+	paymentID, err := c.ParamsInt("paymentId")
+	if err != nil || paymentID <= 0 {
+		paymentID = 0
+	}
+	if err := h.receipts.DeletePayment(middleware.CurrentUser(c), idParam(c), uint(paymentID)); err != nil {
+		return serviceFailed(c, err, "Payment not found", "Failed to delete payment")
+	}
+	return c.JSON(fiber.Map{
+		"message": "Payment deleted successfully",
+	})
+}
@@ -1,197 +1,147 @@
 package handlers
 
 import (
-	"bytes"
-	"io/ioutil"
+	"errors"
+	"fmt"
 	"log"
-	"time"
+	"strconv"
 
-	"github.com/Otabek228101/mehmon/database"
+	"github.com/Otabek228101/mehmon/middleware"
 	"github.com/Otabek228101/mehmon/models"
+	"github.com/Otabek228101/mehmon/repository"
 	"github.com/Otabek228101/mehmon/services"
 	"github.com/gofiber/fiber/v2"
 )
 
-func CreateProposal(c *fiber.Ctx) error {
-	bodyBytes := c.Body()
-	bodyReader := bytes.NewReader(bodyBytes)
-	body, err := ioutil.ReadAll(bodyReader)
-	if err != nil {
-		log.Printf("Failed to read request body: %v", err)
-		return c.Status(400).JSON(fiber.Map{"error": "Unable to read request data"})
-	}
-	log.Printf("Received request body: %s", string(body))
+type ProposalHandler struct {
+	proposals *services.ProposalService
+}
 
-	var request models.ProposalRequest
-	if err := c.BodyParser(&request); err != nil {
-		log.Printf("Error parsing proposal request: %v, Body: %s", err, string(body))
-		return c.Status(400).JSON(fiber.Map{"error": "Invalid request data"})
-	}
+func NewProposalHandler(proposals *services.ProposalService) *ProposalHandler {
+	return &ProposalHandler{proposals: proposals}
+}
 
-	checkIn, err := time.Parse("2006-01-02", request.CheckIn)
-	if err != nil {
-		log.Printf("Invalid check-in date format: %v", err)
-		return c.Status(400).JSON(fiber.Map{"error": "Invalid check-in date format (YYYY-MM-DD)"})
+// PDF renders the proposal with up to ?images= photos of its hotel, 3 by default.
+func (h *ProposalHandler) PDF(c *fiber.Ctx) error {
+	images := 3
+	if n, err := strconv.Atoi(c.Query("images")); err == nil && n >= 0 {
+		images = n
 	}
 
-	checkOut, err := time.Parse("2006-01-02", request.CheckOut)
+	proposal, pdf, err := h.proposals.PDF(idParam(c), images)
 	if err != nil {
-		log.Printf("Invalid check-out date format: %v", err)
-		return c.Status(400).JSON(fiber.Map{"error": "Invalid check-out date format (YYYY-MM-DD)"})
-	}
-
-	if checkOut.Before(checkIn) {
-		log.Printf("Check-out %v is before check-in %v", checkOut, checkIn)
-		return c.Status(400).JSON(fiber.Map{"error": "Check-out date must be after check-in date"})
-	}
-
-	if len(request.Rooms) == 0 {
-		log.Printf("No rooms provided in request")
-		return c.Status(400).JSON(fiber.Map{"error": "At least one room is required"})
-	}
-	for i, room := range request.Rooms {
-		if room.Count <= 0 {
-			log.Printf("Invalid room count at index %d: %v", i, room.Count)
-			return c.Status(400).JSON(fiber.Map{"error": "Room count must be greater than 0"})
-		}
-	}
-
-	var hotel models.Hotel
-	if err := database.DB.First(&hotel, request.HotelID).Error; err != nil {
-		log.Printf("Hotel with ID %d not found: %v", request.HotelID, err)
-		return c.Status(404).JSON(fiber.Map{"error": "Hotel not found"})
-	}
-
-	proposal := models.Proposal{
-		ProposalNumber: services.GenerateProposalNumber(),
-		ClientName:     request.ClientName,
-		Guests:         request.Guests,
-		CheckIn:        checkIn,
-		CheckOut:       checkOut,
-		Price:          request.Price,
-		Breakfast:      request.Breakfast,
-		FreeCancel:     request.FreeCancel,
-		HotelID:        request.HotelID,
-	}
-
-	if err := database.DB.Create(&proposal).Error; err != nil {
-		log.Printf("Failed to create proposal in DB: %v", err)
-		return c.Status(500).JSON(fiber.Map{"error": "Failed to create proposal"})
-	}
-
-	for _, reqRoom := range request.Rooms {
-		room := models.ProposalRoom{
-			ProposalID: proposal.ID,
-			Count:      reqRoom.Count,
-		}
-		if err := database.DB.Create(&room).Error; err != nil {
-			log.Printf("Failed to create room for proposal %d: %v", proposal.ID, err)
-		}
+		return serviceFailed(c, err, "Proposal not found", "Failed to render proposal PDF")
 	}
 
-	database.DB.Preload("Hotel").Preload("Rooms").First(&proposal, proposal.ID)
-	return c.Status(201).JSON(proposal)
+	c.Set(fiber.HeaderContentType, "application/pdf")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="proposal_%s.pdf"`, proposal.ProposalNumber))
+	return c.Send(pdf)
 }
 
-func GetProposals(c *fiber.Ctx) error {
-	var proposals []models.Proposal
-	if err := database.DB.Preload("Hotel").Preload("Rooms").Find(&proposals).Error; err != nil {
+func (h *ProposalHandler) List(c *fiber.Ctx) error {
+	status := c.Query("status")
+	if status != "" && !services.IsProposalStatus(status) {
+		return c.Status(400).JSON(fiber.Map{"error": "Unknown proposal status"})
+	}
+	proposals, err := h.proposals.List(status)
+	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch proposals"})
 	}
 	return c.JSON(proposals)
 }
 
-func GetProposal(c *fiber.Ctx) error {
-	id := c.Params("id")
-	var proposal models.Proposal
-
-	if err := database.DB.Preload("Hotel").Preload("Rooms").First(&proposal, id).Error; err != nil {
-		return c.Status(404).JSON(fiber.Map{"error": "Proposal not found"})
-	}
-
-	return c.JSON(proposal)
-}
-
-func UpdateProposal(c *fiber.Ctx) error {
-	id := c.Params("id")
-	var proposal models.Proposal
-
-	if err := database.DB.First(&proposal, id).Error; err != nil {
-		return c.Status(404).JSON(fiber.Map{"error": "Proposal not found"})
-	}
-
+func (h *ProposalHandler) Create(c *fiber.Ctx) error {
 	var request models.ProposalRequest
 	if err := c.BodyParser(&request); err != nil {
+		log.Printf("Error parsing proposal request: %v", err)
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid request data"})
 	}
 
-	checkIn, err := time.Parse("2006-01-02", request.CheckIn)
-	if err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "Invalid check-in date format (YYYY-MM-DD)"})
+	proposal, err := h.proposals.Create(middleware.CurrentUser(c), request)
+	if errors.Is(err, services.ErrHotelNotFound) {
+		return c.Status(404).JSON(fiber.Map{"error": "Hotel not found"})
 	}
-
-	checkOut, err := time.Parse("2006-01-02", request.CheckOut)
 	if err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "Invalid check-out date format (YYYY-MM-DD)"})
+		return serviceFailed(c, err, "Proposal not found", "Failed to create proposal")
 	}
+	return c.Status(201).JSON(proposal)
+}
 
-	if checkOut.Before(checkIn) {
-		return c.Status(400).JSON(fiber.Map{"error": "Check-out date must be after check-in date"})
+func (h *ProposalHandler) Update(c *fiber.Ctx) error {
+	var request models.ProposalRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request data"})
 	}
 
-	if len(request.Rooms) == 0 {
-		return c.Status(400).JSON(fiber.Map{"error": "At least one room is required"})
+	proposal, err := h.proposals.Update(middleware.CurrentUser(c), idParam(c), request)
+	if errors.Is(err, services.ErrHotelNotFound) {
+		return c.Status(404).JSON(fiber.Map{"error": "Hotel not found"})
 	}
-	for _, room := range request.Rooms {
-		if room.Count <= 0 {
-			return c.Status(400).JSON(fiber.Map{"error": "Room count must be greater than 0"})
-		}
+	if err != nil {
+		return serviceFailed(c, err, "Proposal not found", "Failed to update proposal")
 	}
+	return c.JSON(proposal)
+}
 
-	var hotel models.Hotel
-	if err := database.DB.First(&hotel, request.HotelID).Error; err != nil {
+func (h *ProposalHandler) Convert(c *fiber.Ctx) error {
+	receipt, err := h.proposals.Convert(middleware.CurrentUser(c), idParam(c))
+	var converted *services.ConvertedError
+	switch {
+	case errors.As(err, &converted):
+		return c.Status(409).JSON(fiber.Map{
+			"error":     "Proposal already converted",
+			"receiptId": converted.ReceiptID,
+		})
+	case errors.Is(err, services.ErrNotAccepted):
+		return c.Status(409).JSON(fiber.Map{"error": "Only accepted proposals can be converted"})
+	case errors.Is(err, services.ErrHotelNotFound):
 		return c.Status(404).JSON(fiber.Map{"error": "Hotel not found"})
+	case err != nil:
+		return serviceFailed(c, err, "Proposal not found", "Failed to convert proposal")
 	}
+	return c.Status(201).JSON(receipt)
+}
 
-	proposal.ClientName = request.ClientName
-	proposal.Guests = request.Guests
-	proposal.CheckIn = checkIn
-	proposal.CheckOut = checkOut
-	proposal.Price = request.Price
-	proposal.Breakfast = request.Breakfast
-	proposal.FreeCancel = request.FreeCancel
-	proposal.HotelID = request.HotelID
-
-	if err := database.DB.Save(&proposal).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Failed to update proposal"})
+func (h *ProposalHandler) Get(c *fiber.Ctx) error {
+	proposal, err := h.proposals.Get(idParam(c))
+	if err != nil {
+		return serviceFailed(c, err, "Proposal not found", "Failed to fetch proposal")
 	}
+	return c.JSON(proposal)
+}
 
-	database.DB.Where("proposal_id = ?", proposal.ID).Delete(&models.ProposalRoom{})
-
-	for _, reqRoom := range request.Rooms {
-		room := models.ProposalRoom{
-			ProposalID: proposal.ID,
-			Count:      reqRoom.Count,
-		}
-		if err := database.DB.Create(&room).Error; err != nil {
-			log.Printf("Failed to create room for proposal %d: %v", proposal.ID, err)
-		}
+func (h *ProposalHandler) UpdateStatus(c *fiber.Ctx) error {
+	var request models.ProposalStatusRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request data"})
 	}
 
-	database.DB.Preload("Hotel").Preload("Rooms").First(&proposal, proposal.ID)
+	proposal, err := h.proposals.ChangeStatus(middleware.CurrentUser(c), idParam(c), request)
+	var transition *services.TransitionError
+	switch {
+	case errors.As(err, &transition):
+		return c.Status(409).JSON(fiber.Map{
+			"error": fmt.Sprintf("Cannot change proposal status from %s to %s", transition.From, transition.To),
+		})
+	case errors.Is(err, repository.ErrConflict):
+		return c.Status(409).JSON(fiber.Map{"error": "Proposal status was changed concurrently"})
+	case err != nil:
+		return serviceFailed(c, err, "Proposal not found", "Failed to update proposal status")
+	}
 	return c.JSON(proposal)
 }
 
-func DeleteProposal(c *fiber.Ctx) error {
-	id := c.Params("id")
-
-	if err := database.DB.Where("proposal_id = ?", id).Delete(&models.ProposalRoom{}).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Failed to delete rooms"})
+func (h *ProposalHandler) Delete(c *fiber.Ctx) error {
+	if err := h.proposals.Delete(middleware.CurrentUser(c), idParam(c)); err != nil {
+		return serviceFailed(c, err, "Proposal not found", "Failed to delete proposal")
 	}
+	return c.JSON(fiber.Map{"message": "Proposal deleted successfully"})
+}
 
-	if err := database.DB.Delete(&models.Proposal{}, id).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Failed to delete proposal"})
+func (h *ProposalHandler) Restore(c *fiber.Ctx) error {
+	proposal, err := h.proposals.Restore(middleware.CurrentUser(c), idParam(c))
+	if err != nil {
+		return serviceFailed(c, err, "Deleted proposal not found", "Failed to restore proposal")
 	}
-
-	return c.JSON(fiber.Map{"message": "Proposal deleted successfully"})
+	return c.JSON(proposal)
 }
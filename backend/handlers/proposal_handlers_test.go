@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Otabek228101/mehmon/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+func proposalPayload(hotelID uint, client string, rooms ...int) fiber.Map {
+	reqRooms := []fiber.Map{}
+	for _, count := range rooms {
+		reqRooms = append(reqRooms, fiber.Map{"count": count})
+	}
+	return fiber.Map{
+		"clientName": client,
+		"guests":     2,
+		"checkIn":    "2026-06-01",
+		"checkOut":   "2026-06-04",
+		"price":      450,
+		"hotelId":    hotelID,
+		"rooms":      reqRooms,
+	}
+}
+
+func TestCreateProposalRollsBackOnRoomFailure(t *testing.T) {
+	setupTestDB(t)
+	app := newTestApp()
+	hotel := createTestHotel(t)
+	fail := failCreates(t, "proposal_rooms")
+	*fail = true
+
+	if status := doJSON(t, app, http.MethodPost, "/api/proposals", proposalPayload(hotel.ID, "Ann", 1, 2), nil); status != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", status)
+	}
+	if n := countRows(t, &models.Proposal{}); n != 0 {
+		t.Fatalf("proposals = %d, want 0 after rollback", n)
+	}
+	if n := countRows(t, &models.ProposalStatusChange{}); n != 0 {
+		t.Fatalf("status changes = %d, want 0 after rollback", n)
+	}
+}
+
+func TestUpdateProposalKeepsRoomsOnFailure(t *testing.T) {
+	setupTestDB(t)
+	app := newTestApp()
+	hotel := createTestHotel(t)
+	fail := failCreates(t, "proposal_rooms")
+
+	var created models.Proposal
+	if status := doJSON(t, app, http.MethodPost, "/api/proposals", proposalPayload(hotel.ID, "Ann", 1, 2), &created); status != http.StatusCreated {
+		t.Fatalf("status = %d, want 201", status)
+	}
+
+	*fail = true
+	path := "/api/proposals/" + itoa(created.ID)
+	if status := doJSON(t, app, http.MethodPut, path, proposalPayload(hotel.ID, "Bob", 3), nil); status != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", status)
+	}
+
+	*fail = false
+	var got models.Proposal
+	doJSON(t, app, http.MethodGet, path, nil, &got)
+	if got.ClientName != "Ann" {
+		t.Fatalf("client name = %q, want Ann", got.ClientName)
+	}
+	if len(got.Rooms) != 2 {
+		t.Fatalf("rooms = %d, want the original 2", len(got.Rooms))
+	}
+}
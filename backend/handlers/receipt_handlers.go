@@ -1,269 +1,297 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"time"
 
-	"github.com/Otabek228101/mehmon/database"
+	"github.com/Otabek228101/mehmon/middleware"
 	"github.com/Otabek228101/mehmon/models"
+	"github.com/Otabek228101/mehmon/repository"
 	"github.com/Otabek228101/mehmon/services"
 	"github.com/gofiber/fiber/v2"
 )
 
-type ReceiptRequest struct {
-	ClientName  string            `json:"clientName"`
-	ClientEmail string            `json:"clientEmail"`
-	ClientPhone string            `json:"clientPhone"`
-	ReceiptDate string            `json:"receiptDate"`
-	AmountPaid  float64           `json:"amountPaid"`
-	Activities  []ActivityRequest `json:"activities"`
+var receiptSortColumns = map[string]sortColumn{
+	"date":   {Column: "receipt_date", Kind: sortTime},
+	"amount": {Column: "amount_paid", Kind: sortNumber},
+	"number": {Column: "receipt_number", Kind: sortString},
 }
 
-type ActivityRequest struct {
-	Type            string  `json:"type"`
-	PropertyName    string  `json:"propertyName"`
-	PropertyAddress string  `json:"propertyAddress"`
-	CheckIn         *string `json:"checkIn"`
-	CheckOut        *string `json:"checkOut"`
-	Amount          float64 `json:"amount"`
-	PickupLocation  string  `json:"pickupLocation"`
-	DropoffLocation string  `json:"dropoffLocation"`
-	TransferType    string  `json:"transferType"`
-	Description     string  `json:"description"`
+func receiptSortValue(receipt models.Receipt, sort string) any {
+	switch sort {
+	case "amount":
+		return receipt.AmountPaid
+	case "number":
+		return receipt.ReceiptNumber
+	}
+	return receipt.ReceiptDate
 }
 
-func parseTimeString(timeStr *string) *time.Time {
-	if timeStr == nil || *timeStr == "" {
-		return nil
+func parseDateQuery(value string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
 	}
+	return time.Parse(time.RFC3339, value)
+}
 
-	if t, err := time.Parse(time.RFC3339Nano, *timeStr); err == nil {
-		return &t
+// receiptFilter adds the date, amount and activity type filters shared by the list, search
+// and report endpoints to filter.
+func receiptFilter(c *fiber.Ctx, filter repository.ReceiptFilter) (repository.ReceiptFilter, error) {
+	if v := c.Query("from"); v != "" {
+		from, err := parseDateQuery(v)
+		if err != nil {
+			return filter, fmt.Errorf("invalid from date: %s", v)
+		}
+		filter.From = &from
 	}
-
-	if t, err := time.Parse(time.RFC3339, *timeStr); err == nil {
-		return &t
+	if v := c.Query("to"); v != "" {
+		to, err := parseDateQuery(v)
+		if err != nil {
+			return filter, fmt.Errorf("invalid to date: %s", v)
+		}
+		if len(v) == len("2006-01-02") {
+			before := to.AddDate(0, 0, 1)
+			filter.Before = &before
+		} else {
+			filter.To = &to
+		}
 	}
-
-	if t, err := time.Parse("2006-01-02", *timeStr); err == nil {
-		return &t
+	if v := c.Query("minAmount"); v != "" {
+		amount, err := models.ParseMoney(v)
+		if err != nil {
+			return filter, fmt.Errorf("invalid minAmount: %s", v)
+		}
+		filter.MinAmount = &amount
 	}
-
-	if t, err := time.Parse("2006-01-02T15:04:05", *timeStr); err == nil {
-		return &t
+	if v := c.Query("maxAmount"); v != "" {
+		amount, err := models.ParseMoney(v)
+		if err != nil {
+			return filter, fmt.Errorf("invalid maxAmount: %s", v)
+		}
+		filter.MaxAmount = &amount
 	}
-
-	return nil
+	filter.ActivityType = c.Query("type")
+	return filter, nil
 }
 
-func CreateReceipt(c *fiber.Ctx) error {
-	var request ReceiptRequest
-
-	if err := c.BodyParser(&request); err != nil {
-		log.Printf("Error parsing request body: %v", err)
-		return c.Status(400).JSON(fiber.Map{
-			"error":   "Invalid request data",
-			"details": err.Error(),
-		})
-	}
-
-	log.Printf("Received request: %+v", request)
-
-	receiptDate, err := time.Parse(time.RFC3339Nano, request.ReceiptDate)
+func publicReceiptURL(c *fiber.Ctx, receiptID uint, suffix string) (string, error) {
+	sig, err := services.SignReceiptLink(receiptID)
 	if err != nil {
-		receiptDate, err = time.Parse("2006-01-02", request.ReceiptDate)
-		if err != nil {
-			log.Printf("Error parsing receipt date: %v", err)
-			return c.Status(400).JSON(fiber.Map{
-				"error":   "Invalid receipt date format",
-				"details": "Expected format: YYYY-MM-DD or ISO 8601 (with or without milliseconds)",
-			})
-		}
+		return "", err
 	}
+	return fmt.Sprintf("%s/api/public/receipts/%d%s?sig=%s", c.BaseURL(), receiptID, suffix, sig), nil
+}
 
-	receipt := models.Receipt{
-		ReceiptNumber: services.GenerateReceiptNumber(),
-		ClientName:    request.ClientName,
-		ClientEmail:   request.ClientEmail,
-		ClientPhone:   request.ClientPhone,
-		ReceiptDate:   receiptDate,
-		AmountPaid:    request.AmountPaid,
+func sendReceiptPDF(c *fiber.Ctx, receipt models.Receipt) error {
+	link, err := publicReceiptURL(c, receipt.ID, "/pdf")
+	if err != nil {
+		return serviceFailed(c, err, "Receipt not found", "Failed to render receipt PDF")
 	}
-
-	log.Printf("Receipt before save: %+v", receipt)
-
-	if err := database.DB.Create(&receipt).Error; err != nil {
-		log.Printf("Error creating receipt: %v", err)
+	pdf, err := services.RenderReceiptPDF(receipt, link)
+	if err != nil {
+		log.Printf("Error rendering receipt PDF: %v", err)
 		return c.Status(500).JSON(fiber.Map{
-			"error":   "Failed to create receipt",
-			"details": err.Error(),
+			"error": "Failed to render receipt PDF",
 		})
 	}
 
-	log.Printf("Receipt created with ID: %d", receipt.ID)
-
-	var activities []models.Activity
-	for _, actReq := range request.Activities {
-		activity := models.Activity{
-			ReceiptID:       receipt.ID,
-			Type:            actReq.Type,
-			PropertyName:    actReq.PropertyName,
-			PropertyAddress: actReq.PropertyAddress,
-			CheckIn:         parseTimeString(actReq.CheckIn),
-			CheckOut:        parseTimeString(actReq.CheckOut),
-			Amount:          actReq.Amount,
-			PickupLocation:  actReq.PickupLocation,
-			DropoffLocation: actReq.DropoffLocation,
-			TransferType:    actReq.TransferType,
-			Description:     actReq.Description,
-		}
+	c.Set(fiber.HeaderContentType, "application/pdf")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="receipt_%s.pdf"`, receipt.ReceiptNumber))
+	return c.Send(pdf)
+}
 
-		if err := database.DB.Create(&activity).Error; err != nil {
-			log.Printf("Error creating activity: %v", err)
-			database.DB.Delete(&receipt)
-			return c.Status(500).JSON(fiber.Map{
-				"error":   "Failed to create activity",
-				"details": err.Error(),
-			})
-		}
-		activities = append(activities, activity)
-	}
+type ReceiptHandler struct {
+	receipts *services.ReceiptService
+}
 
-	database.DB.Preload("Activities").First(&receipt, receipt.ID)
-	return c.Status(201).JSON(receipt)
+func NewReceiptHandler(receipts *services.ReceiptService) *ReceiptHandler {
+	return &ReceiptHandler{receipts: receipts}
 }
 
-func GetReceipts(c *fiber.Ctx) error {
-	var receipts []models.Receipt
-	if err := database.DB.Preload("Activities").Find(&receipts).Error; err != nil {
+// list answers a page of the receipts matching filter and the query string filters.
+func (h *ReceiptHandler) list(c *fiber.Ctx, filter repository.ReceiptFilter) error {
+	params, err := parseListParams(c, receiptSortColumns, "date", true)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	filter, err = receiptFilter(c, filter)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	receipts, total, err := h.receipts.List(filter, params.repoPage())
+	if errors.Is(err, services.ErrHotelNotFound) {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Hotel not found",
+		})
+	}
+	if err != nil {
 		log.Printf("Error fetching receipts: %v", err)
 		return c.Status(500).JSON(fiber.Map{
 			"error": "Failed to fetch receipts",
 		})
 	}
-	return c.JSON(receipts)
+
+	next := ""
+	if len(receipts) > 0 {
+		last := receipts[len(receipts)-1]
+		next = params.nextCursor(len(receipts), receiptSortValue(last, params.Sort), last.ID)
+	}
+	return c.JSON(params.page(receipts, total, next))
 }
 
-func GetReceipt(c *fiber.Ctx) error {
-	id := c.Params("id")
-	var receipt models.Receipt
+func (h *ReceiptHandler) List(c *fiber.Ctx) error {
+	return h.list(c, repository.ReceiptFilter{})
+}
 
-	if err := database.DB.Preload("Activities").First(&receipt, id).Error; err != nil {
-		log.Printf("Error fetching receipt: %v", err)
+func (h *ReceiptHandler) HotelReceipts(c *fiber.Ctx) error {
+	id := idParam(c)
+	if id == 0 {
 		return c.Status(404).JSON(fiber.Map{
-			"error": "Receipt not found",
+			"error": "Hotel not found",
 		})
 	}
-
-	return c.JSON(receipt)
+	return h.list(c, repository.ReceiptFilter{HotelID: id})
 }
 
-func SearchReceipts(c *fiber.Ctx) error {
+func (h *ReceiptHandler) Search(c *fiber.Ctx) error {
 	query := c.Query("q")
 	if query == "" {
 		return c.Status(400).JSON(fiber.Map{
 			"error": "Search query is required",
 		})
 	}
+	return h.list(c, repository.ReceiptFilter{Query: query})
+}
 
-	var receipts []models.Receipt
+func (h *ReceiptHandler) PDF(c *fiber.Ctx) error {
+	receipt, _, err := h.receipts.Get(idParam(c))
+	if err != nil {
+		return serviceFailed(c, err, "Receipt not found", "Failed to fetch receipt")
+	}
+	return sendReceiptPDF(c, receipt)
+}
 
-	searchPattern := "%" + query + "%"
-	if err := database.DB.Preload("Activities").Where(
-		"receipt_number ILIKE ? OR client_name ILIKE ? OR client_email ILIKE ? OR client_phone ILIKE ?",
-		searchPattern, searchPattern, searchPattern, searchPattern,
-	).Find(&receipts).Error; err != nil {
-		log.Printf("Error searching receipts: %v", err)
-		return c.Status(500).JSON(fiber.Map{
-			"error": "Failed to search receipts",
-		})
+func (h *ReceiptHandler) ShareLink(c *fiber.Ctx) error {
+	receipt, _, err := h.receipts.Get(idParam(c))
+	if err != nil {
+		return serviceFailed(c, err, "Receipt not found", "Failed to fetch receipt")
+	}
+	link, err := publicReceiptURL(c, receipt.ID, "")
+	if err != nil {
+		return serviceFailed(c, err, "Receipt not found", "Failed to create share link")
 	}
+	pdfLink, err := publicReceiptURL(c, receipt.ID, "/pdf")
+	if err != nil {
+		return serviceFailed(c, err, "Receipt not found", "Failed to create share link")
+	}
+	return c.JSON(fiber.Map{
+		"url":    link,
+		"pdfUrl": pdfLink,
+	})
+}
 
-	return c.JSON(receipts)
+// signedReceipt loads the receipt of a public link. A link that is not signed is answered
+// like a missing receipt. Payments stay private to the agency.
+func (h *ReceiptHandler) signedReceipt(c *fiber.Ctx) (models.Receipt, error) {
+	id := idParam(c)
+	ok, err := services.VerifyReceiptLink(id, c.Query("sig"))
+	if err != nil {
+		return models.Receipt{}, err
+	}
+	if id == 0 || !ok {
+		return models.Receipt{}, repository.ErrNotFound
+	}
+	receipt, _, err := h.receipts.Get(id)
+	receipt.Payments = nil
+	return receipt, err
 }
 
-func UpdateReceipt(c *fiber.Ctx) error {
-	id := c.Params("id")
-	var receipt models.Receipt
+func (h *ReceiptHandler) PublicGet(c *fiber.Ctx) error {
+	receipt, err := h.signedReceipt(c)
+	if err != nil {
+		return serviceFailed(c, err, "Receipt not found", "Failed to fetch receipt")
+	}
+	return c.JSON(receipt)
+}
 
-	if err := database.DB.First(&receipt, id).Error; err != nil {
-		return c.Status(404).JSON(fiber.Map{
-			"error": "Receipt not found",
-		})
+func (h *ReceiptHandler) PublicPDF(c *fiber.Ctx) error {
+	receipt, err := h.signedReceipt(c)
+	if err != nil {
+		return serviceFailed(c, err, "Receipt not found", "Failed to fetch receipt")
 	}
+	return sendReceiptPDF(c, receipt)
+}
 
-	var request ReceiptRequest
+func (h *ReceiptHandler) Create(c *fiber.Ctx) error {
+	var request models.ReceiptRequest
 	if err := c.BodyParser(&request); err != nil {
+		log.Printf("Error parsing request body: %v", err)
 		return c.Status(400).JSON(fiber.Map{
-			"error": "Invalid request data",
+			"error":   "Invalid request data",
+			"details": err.Error(),
 		})
 	}
 
-	receiptDate, err := time.Parse(time.RFC3339Nano, request.ReceiptDate)
+	receipt, err := h.receipts.Create(middleware.CurrentUser(c), request)
 	if err != nil {
-		receiptDate, err = time.Parse("2006-01-02", request.ReceiptDate)
-		if err != nil {
-			return c.Status(400).JSON(fiber.Map{
-				"error": "Invalid receipt date format",
-			})
-		}
+		return serviceFailed(c, err, "Receipt not found", "Failed to create receipt")
 	}
+	return c.Status(201).JSON(receipt)
+}
 
-	receipt.ClientName = request.ClientName
-	receipt.ClientEmail = request.ClientEmail
-	receipt.ClientPhone = request.ClientPhone
-	receipt.ReceiptDate = receiptDate
-	receipt.AmountPaid = request.AmountPaid
-
-	database.DB.Where("receipt_id = ?", receipt.ID).Delete(&models.Activity{})
-
-	for _, actReq := range request.Activities {
-		activity := models.Activity{
-			ReceiptID:       receipt.ID,
-			Type:            actReq.Type,
-			PropertyName:    actReq.PropertyName,
-			PropertyAddress: actReq.PropertyAddress,
-			CheckIn:         parseTimeString(actReq.CheckIn),
-			CheckOut:        parseTimeString(actReq.CheckOut),
-			Amount:          actReq.Amount,
-			PickupLocation:  actReq.PickupLocation,
-			DropoffLocation: actReq.DropoffLocation,
-			TransferType:    actReq.TransferType,
-			Description:     actReq.Description,
-		}
-
-		database.DB.Create(&activity)
+func (h *ReceiptHandler) Update(c *fiber.Ctx) error {
+	var request models.ReceiptRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request data",
+		})
 	}
 
-	if err := database.DB.Save(&receipt).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": "Failed to update receipt",
+	receipt, err := h.receipts.Update(middleware.CurrentUser(c), idParam(c), request)
+	if errors.Is(err, services.ErrReceiptHasPayments) {
+		return c.Status(409).JSON(fiber.Map{
+			"error": "Receipt has payments; change them through its payments instead of amountPaid",
 		})
 	}
-
-	database.DB.Preload("Activities").First(&receipt, receipt.ID)
-
+	if errors.Is(err, services.ErrPaidCurrency) {
+		return c.Status(409).JSON(fiber.Map{
+			"error": "Receipt has payments; its currency cannot change",
+		})
+	}
+	if err != nil {
+		return serviceFailed(c, err, "Receipt not found", "Failed to update receipt")
+	}
 	return c.JSON(receipt)
 }
 
-func DeleteReceipt(c *fiber.Ctx) error {
-	id := c.Params("id")
-
-	if err := database.DB.Where("receipt_id = ?", id).Delete(&models.Activity{}).Error; err != nil {
-		log.Printf("Error deleting activities: %v", err)
-		return c.Status(500).JSON(fiber.Map{
-			"error": "Failed to delete activities",
-		})
+func (h *ReceiptHandler) Get(c *fiber.Ctx) error {
+	receipt, balance, err := h.receipts.Get(idParam(c))
+	if err != nil {
+		return serviceFailed(c, err, "Receipt not found", "Failed to fetch receipt")
 	}
+	return c.JSON(receiptWithBalance{Receipt: receipt, ReceiptBalance: balance})
+}
 
-	if err := database.DB.Delete(&models.Receipt{}, id).Error; err != nil {
-		log.Printf("Error deleting receipt: %v", err)
-		return c.Status(500).JSON(fiber.Map{
-			"error": "Failed to delete receipt",
-		})
+func (h *ReceiptHandler) Delete(c *fiber.Ctx) error {
+	if err := h.receipts.Delete(middleware.CurrentUser(c), idParam(c)); err != nil {
+		return serviceFailed(c, err, "Receipt not found", "Failed to delete receipt")
 	}
-
 	return c.JSON(fiber.Map{
 		"message": "Receipt deleted successfully",
 	})
 }
+
+func (h *ReceiptHandler) Restore(c *fiber.Ctx) error {
+	receipt, err := h.receipts.Restore(middleware.CurrentUser(c), idParam(c))
+	if err != nil {
+		return serviceFailed(c, err, "Deleted receipt not found", "Failed to restore receipt")
+	}
+	return c.JSON(receipt)
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Otabek228101/mehmon/database"
+	"github.com/Otabek228101/mehmon/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+func receiptPayload(name string, activities ...fiber.Map) fiber.Map {
+	return fiber.Map{
+		"clientName":  name,
+		"clientEmail": "guest@example.com",
+		"clientPhone": "+998901234567",
+		"receiptDate": "2026-05-01",
+		"amountPaid":  300,
+		"activities":  activities,
+	}
+}
+
+func hotelActivity(name string) fiber.Map {
+	return fiber.Map{
+		"type":         "hotel",
+		"propertyName": name,
+		"checkIn":      "2026-05-10",
+		"checkOut":     "2026-05-12",
+		"amount":       150,
+	}
+}
+
+func TestCreateReceiptRollsBackOnActivityFailure(t *testing.T) {
+	setupTestDB(t)
+	app := newTestApp()
+	fail := failCreates(t, "activities")
+	*fail = true
+
+	status := doJSON(t, app, http.MethodPost, "/api/receipts", receiptPayload("Ann", hotelActivity("A"), hotelActivity("B")), nil)
+	if status != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", status)
+	}
+	if n := countRows(t, &models.Receipt{}); n != 0 {
+		t.Fatalf("receipts = %d, want 0 after rollback", n)
+	}
+
+	*fail = false
+	var receipt models.Receipt
+	if status := doJSON(t, app, http.MethodPost, "/api/receipts", receiptPayload("Ann", hotelActivity("A")), &receipt); status != http.StatusCreated {
+		t.Fatalf("status = %d, want 201", status)
+	}
+	if receipt.ReceiptNumber != "M00001" {
+		t.Fatalf("receipt number = %q, want M00001 to be reused after rollback", receipt.ReceiptNumber)
+	}
+	if len(receipt.Activities) != 1 {
+		t.Fatalf("activities = %d, want 1", len(receipt.Activities))
+	}
+}
+
+func TestUpdateReceiptKeepsDataOnActivityFailure(t *testing.T) {
+	setupTestDB(t)
+	app := newTestApp()
+	fail := failCreates(t, "activities")
+
+	var created models.Receipt
+	doJSON(t, app, http.MethodPost, "/api/receipts", receiptPayload("Ann", hotelActivity("A"), hotelActivity("B")), &created)
+
+	*fail = true
+	path := "/api/receipts/" + itoa(created.ID)
+	if status := doJSON(t, app, http.MethodPut, path, receiptPayload("Bob", hotelActivity("C")), nil); status != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", status)
+	}
+
+	*fail = false
+	var got models.Receipt
+	doJSON(t, app, http.MethodGet, path, nil, &got)
+	if got.ClientName != "Ann" {
+		t.Fatalf("client name = %q, want Ann", got.ClientName)
+	}
+	if len(got.Activities) != 2 {
+		t.Fatalf("activities = %d, want the original 2", len(got.Activities))
+	}
+}
+
+func TestSearchReceiptsIgnoresCase(t *testing.T) {
+	setupTestDB(t)
+	app := newTestApp()
+	for _, name := range []string{"Ann Lee", "Bob Stone"} {
+		if status := doJSON(t, app, http.MethodPost, "/api/receipts", receiptPayload(name, hotelActivity("A")), nil); status != http.StatusCreated {
+			t.Fatalf("create %s: status %d", name, status)
+		}
+	}
+
+	var page struct {
+		Data  []models.Receipt `json:"data"`
+		Total int64            `json:"total"`
+	}
+	if status := doJSON(t, app, http.MethodGet, "/api/receipts/search?q=ANN", nil, &page); status != http.StatusOK {
+		t.Fatalf("status = %d, want 200", status)
+	}
+	if page.Total != 1 || page.Data[0].ClientName != "Ann Lee" {
+		t.Fatalf("search ANN = %+v, want Ann Lee only", page.Data)
+	}
+}
+
+func TestUpdateReceiptLeavesPaymentsAlone(t *testing.T) {
+	setupTestDB(t)
+	app := newTestApp()
+	var receipt models.Receipt
+	if status := doJSON(t, app, http.MethodPost, "/api/receipts", receiptPayload("Ann", hotelActivity("A")), &receipt); status != http.StatusCreated {
+		t.Fatalf("create: status %d", status)
+	}
+	path := "/api/receipts/" + itoa(receipt.ID)
+	paid := func() models.Money {
+		t.Helper()
+		var total models.Money
+		if err := database.DB.Model(&models.Payment{}).Where("receipt_id = ?", receipt.ID).
+			Select("COALESCE(SUM(amount), 0)").Scan(&total).Error; err != nil {
+			t.Fatalf("sum payments: %v", err)
+		}
+		return total
+	}
+
+	omitted := receiptPayload("Ann Lee", hotelActivity("A"))
+	delete(omitted, "amountPaid")
+	if status := doJSON(t, app, http.MethodPut, path, omitted, nil); status != http.StatusOK {
+		t.Fatalf("update without amountPaid: status %d", status)
+	}
+	if total := paid(); total.Cents() != 30000 {
+		t.Fatalf("payments = %v after omitting amountPaid, want 300", total)
+	}
+
+	unchanged := receiptPayload("Ann Lee", hotelActivity("A"))
+	if status := doJSON(t, app, http.MethodPut, path, unchanged, nil); status != http.StatusOK {
+		t.Fatalf("update with the same amountPaid: status %d", status)
+	}
+	stale := receiptPayload("Ann Lee", hotelActivity("A"))
+	stale["amountPaid"] = 100
+	if status := doJSON(t, app, http.MethodPut, path, stale, nil); status != http.StatusConflict {
+		t.Fatalf("update with a different amountPaid: status %d, want 409", status)
+	}
+	if total := paid(); total.Cents() != 30000 {
+		t.Fatalf("payments = %v, want 300 to be untouched", total)
+	}
+}
@@ -10,136 +10,117 @@ import (
 	"strconv"
 	"strings"
 
-	"github.com/Otabek228101/mehmon/database"
+	"github.com/Otabek228101/mehmon/config"
+	"github.com/Otabek228101/mehmon/middleware"
 	"github.com/Otabek228101/mehmon/models"
+	"github.com/Otabek228101/mehmon/services"
 	"github.com/gofiber/fiber/v2"
 )
 
-func GetHotels(c *fiber.Ctx) error {
-	city := c.Query("city")
-	var hotels []models.Hotel
-	query := database.DB
-	if city != "" {
-		query = query.Where("city ILIKE ?", "%"+city+"%")
-	}
-	if err := query.Preload("Images").Find(&hotels).Error; err != nil {
+type HotelHandler struct {
+	hotels *services.HotelService
+}
+
+func NewHotelHandler(hotels *services.HotelService) *HotelHandler {
+	return &HotelHandler{hotels: hotels}
+}
+
+func (h *HotelHandler) List(c *fiber.Ctx) error {
+	hotels, err := h.hotels.List(c.Query("city"))
+	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch hotels"})
 	}
 	return c.JSON(hotels)
 }
 
-func GetHotelByID(c *fiber.Ctx) error {
-	id := c.Params("id")
-	var hotel models.Hotel
-	if err := database.DB.Preload("Images").First(&hotel, id).Error; err != nil {
-		return c.Status(404).JSON(fiber.Map{"error": "Hotel not found"})
+func (h *HotelHandler) Get(c *fiber.Ctx) error {
+	hotel, err := h.hotels.Get(idParam(c))
+	if err != nil {
+		return serviceFailed(c, err, "Hotel not found", "Failed to fetch hotel")
 	}
 	return c.JSON(hotel)
 }
 
-func CreateHotel(c *fiber.Ctx) error {
+func (h *HotelHandler) Create(c *fiber.Ctx) error {
 	var req models.CreateHotelRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid request data"})
 	}
-	if req.Name == "" || req.Address == "" || req.City == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "Name, Address, and City are required"})
-	}
-	if req.Stars < 1 || req.Stars > 5 {
-		return c.Status(400).JSON(fiber.Map{"error": "Stars must be between 1 and 5"})
-	}
-	if req.Type != "hotel" && req.Type != "apartment" {
-		return c.Status(400).JSON(fiber.Map{"error": "Type must be 'hotel' or 'apartment'"})
-	}
-	hotel := models.Hotel{
-		Name:         req.Name,
-		City:         req.City,
-		GroupName:    req.GroupName,
-		Type:         req.Type,
-		Stars:        req.Stars,
-		Address:      req.Address,
-		LocationLink: req.LocationLink,
-		WebsiteLink:  req.WebsiteLink,
-		Breakfast:    req.Breakfast,
-	}
-	if err := database.DB.Create(&hotel).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Failed to create hotel"})
+	hotel, err := h.hotels.Create(middleware.CurrentUser(c), req)
+	if err != nil {
+		return serviceFailed(c, err, "Hotel not found", "Failed to create hotel")
 	}
 	return c.Status(201).JSON(hotel)
 }
 
-func UpdateHotel(c *fiber.Ctx) error {
-	id := c.Params("id")
-	var hotel models.Hotel
-	if err := database.DB.First(&hotel, id).Error; err != nil {
-		return c.Status(404).JSON(fiber.Map{"error": "Hotel not found"})
-	}
+func (h *HotelHandler) Update(c *fiber.Ctx) error {
 	var req models.CreateHotelRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid request data"})
 	}
-	hotel.Name = req.Name
-	hotel.City = req.City
-	hotel.GroupName = req.GroupName
-	hotel.Type = req.Type
-	hotel.Stars = req.Stars
-	hotel.Address = req.Address
-	hotel.LocationLink = req.LocationLink
-	hotel.WebsiteLink = req.WebsiteLink
-	hotel.Breakfast = req.Breakfast
-	if err := database.DB.Save(&hotel).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Failed to update hotel"})
+	hotel, err := h.hotels.Update(middleware.CurrentUser(c), idParam(c), req)
+	if err != nil {
+		return serviceFailed(c, err, "Hotel not found", "Failed to update hotel")
 	}
 	return c.JSON(hotel)
 }
 
-func DeleteHotel(c *fiber.Ctx) error {
-	id := c.Params("id")
-	if err := database.DB.Delete(&models.Hotel{}, id).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Failed to delete hotel"})
+func (h *HotelHandler) Delete(c *fiber.Ctx) error {
+	if err := h.hotels.Delete(middleware.CurrentUser(c), idParam(c)); err != nil {
+		return serviceFailed(c, err, "Hotel not found", "Failed to delete hotel")
 	}
 	return c.JSON(fiber.Map{"message": "Hotel deleted successfully"})
 }
 
-func GetCarRentals(c *fiber.Ctx) error {
-	var carRentals []models.CarRental
-	if err := database.DB.Find(&carRentals).Error; err != nil {
+type CarRentalHandler struct {
+	rentals *services.CarRentalService
+}
+
+func NewCarRentalHandler(rentals *services.CarRentalService) *CarRentalHandler {
+	return &CarRentalHandler{rentals: rentals}
+}
+
+func (h *CarRentalHandler) List(c *fiber.Ctx) error {
+	rentals, err := h.rentals.List()
+	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch car rentals"})
 	}
-	return c.JSON(carRentals)
+	return c.JSON(rentals)
 }
 
-func CreateCarRental(c *fiber.Ctx) error {
-	var carRental models.CarRental
-	if err := c.BodyParser(&carRental); err != nil {
+func (h *CarRentalHandler) Create(c *fiber.Ctx) error {
+	var rental models.CarRental
+	if err := c.BodyParser(&rental); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid request data"})
 	}
-	if carRental.Name == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "Name is required"})
-	}
-	if err := database.DB.Create(&carRental).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Failed to create car rental"})
+	rental, err := h.rentals.Create(middleware.CurrentUser(c), rental)
+	if err != nil {
+		return serviceFailed(c, err, "Car rental not found", "Failed to create car rental")
 	}
-	return c.Status(201).JSON(carRental)
+	return c.Status(201).JSON(rental)
 }
 
-func DeleteCarRental(c *fiber.Ctx) error {
-	id := c.Params("id")
-	var carRental models.CarRental
-	if err := database.DB.First(&carRental, id).Error; err != nil {
-		return c.Status(404).JSON(fiber.Map{"error": "Car rental not found"})
-	}
-	if err := database.DB.Delete(&carRental).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Failed to delete car rental"})
+func (h *CarRentalHandler) Delete(c *fiber.Ctx) error {
+	if err := h.rentals.Delete(middleware.CurrentUser(c), idParam(c)); err != nil {
+		return serviceFailed(c, err, "Car rental not found", "Failed to delete car rental")
 	}
 	return c.JSON(fiber.Map{"message": "Car rental deleted successfully"})
 }
 
-func UploadHotelImages(c *fiber.Ctx) error {
-	id := c.Params("id")
-	var hotel models.Hotel
-	if err := database.DB.First(&hotel, id).Error; err != nil {
-		return c.Status(404).JSON(fiber.Map{"error": "Hotel not found"})
+// imageJSON is how an image is listed, with the path it is served under.
+func imageJSON(image models.HotelImage) fiber.Map {
+	return fiber.Map{
+		"id":        image.ID,
+		"path":      fmt.Sprintf("/uploads/hotels/%d/%s", image.HotelID, filepath.Base(image.Path)),
+		"mime":      image.Mime,
+		"sortOrder": image.SortOrder,
+	}
+}
+
+func (h *HotelHandler) UploadImages(c *fiber.Ctx) error {
+	hotel, err := h.hotels.Get(idParam(c))
+	if err != nil {
+		return serviceFailed(c, err, "Hotel not found", "Failed to fetch hotel")
 	}
 	form, err := c.MultipartForm()
 	if err != nil {
@@ -155,12 +136,26 @@ func UploadHotelImages(c *fiber.Ctx) error {
 	if len(files) == 0 {
 		return c.Status(400).JSON(fiber.Map{"error": "No files"})
 	}
-	dir := filepath.Join("uploads", "hotels", id)
+	cfg := config.Current
+	if len(files) > cfg.UploadMaxFiles {
+		return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("At most %d files can be uploaded at once", cfg.UploadMaxFiles)})
+	}
+	for _, fh := range files {
+		if fh.Size > cfg.UploadMaxFileSize {
+			return c.Status(413).JSON(fiber.Map{
+				"error": fmt.Sprintf("%s is larger than %s", fh.Filename, config.FormatSize(cfg.UploadMaxFileSize)),
+			})
+		}
+	}
+	id := strconv.FormatUint(uint64(hotel.ID), 10)
+	dir := filepath.Join(cfg.UploadDir, "hotels", id)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "mkdir failed"})
 	}
-	var maxSort int
-	database.DB.Model(&models.HotelImage{}).Where("hotel_id = ?", hotel.ID).Select("COALESCE(MAX(sort_order),0)").Scan(&maxSort)
+	maxSort, err := h.hotels.LastImageSort(hotel.ID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch images"})
+	}
 	created := []models.HotelImage{}
 	for _, fh := range files {
 		ext := strings.ToLower(filepath.Ext(fh.Filename))
@@ -182,65 +177,38 @@ func UploadHotelImages(c *fiber.Ctx) error {
 			mime = "image/webp"
 		}
 		img := models.HotelImage{HotelID: hotel.ID, Path: path, Mime: mime, SortOrder: nextSort}
-		if err := database.DB.Create(&img).Error; err == nil {
+		if err := h.hotels.AddImage(middleware.CurrentUser(c), &img); err == nil {
 			created = append(created, img)
 		}
 	}
 	sort.Slice(created, func(i, j int) bool { return created[i].SortOrder < created[j].SortOrder })
 	out := []fiber.Map{}
 	for _, v := range created {
-		out = append(out, fiber.Map{
-			"id":        v.ID,
-			"path":      "/uploads/hotels/" + id + "/" + filepath.Base(v.Path),
-			"mime":      v.Mime,
-			"sortOrder": v.SortOrder,
-		})
+		out = append(out, imageJSON(v))
 	}
 	return c.JSON(fiber.Map{"uploaded": out})
 }
 
-func GetHotelImages(c *fiber.Ctx) error {
-	id := c.Params("id")
-	var hotel models.Hotel
-	if err := database.DB.First(&hotel, id).Error; err != nil {
-		return c.Status(404).JSON(fiber.Map{"error": "Hotel not found"})
-	}
-	var imgs []models.HotelImage
-	if err := database.DB.Where("hotel_id = ?", hotel.ID).Order("sort_order asc, id asc").Find(&imgs).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch images"})
+func (h *HotelHandler) Images(c *fiber.Ctx) error {
+	imgs, err := h.hotels.Images(idParam(c), 0)
+	if err != nil {
+		return serviceFailed(c, err, "Hotel not found", "Failed to fetch images")
 	}
 	out := []fiber.Map{}
 	for _, v := range imgs {
-		out = append(out, fiber.Map{
-			"id":        v.ID,
-			"path":      "/uploads/hotels/" + id + "/" + filepath.Base(v.Path),
-			"mime":      v.Mime,
-			"sortOrder": v.SortOrder,
-		})
+		out = append(out, imageJSON(v))
 	}
 	return c.JSON(fiber.Map{"images": out})
 }
 
-func GetHotelImagesBase64(c *fiber.Ctx) error {
-	id := c.Params("id")
-	limitStr := c.Query("limit")
+func (h *HotelHandler) ImagesBase64(c *fiber.Ctx) error {
 	limit := 0
-	if limitStr != "" {
-		if n, err := strconv.Atoi(limitStr); err == nil && n > 0 {
-			limit = n
-		}
-	}
-	var hotel models.Hotel
-	if err := database.DB.First(&hotel, id).Error; err != nil {
-		return c.Status(404).JSON(fiber.Map{"error": "Hotel not found"})
-	}
-	var imgs []models.HotelImage
-	tx := database.DB.Where("hotel_id = ?", hotel.ID).Order("sort_order asc, id asc")
-	if limit > 0 {
-		tx = tx.Limit(limit)
+	if n, err := strconv.Atoi(c.Query("limit")); err == nil && n > 0 {
+		limit = n
 	}
-	if err := tx.Find(&imgs).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch images"})
+	imgs, err := h.hotels.Images(idParam(c), limit)
+	if err != nil {
+		return serviceFailed(c, err, "Hotel not found", "Failed to fetch images")
 	}
 	out := []string{}
 	for _, v := range imgs {
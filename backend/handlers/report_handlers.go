@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Otabek228101/mehmon/models"
+	"github.com/Otabek228101/mehmon/repository"
+	"github.com/Otabek228101/mehmon/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// normalizeSums converts per-currency sums into target; currencies without a rate are reported separately.
+func normalizeSums(rates services.Rates, sums []services.CurrencySum, target string) fiber.Map {
+	var total models.Money
+	unconverted := []string{}
+	for _, s := range sums {
+		converted, err := rates.Convert(s.Amount, s.Currency, target)
+		if err != nil {
+			unconverted = append(unconverted, s.Currency)
+			continue
+		}
+		total = total.Add(converted)
+	}
+	sort.Strings(unconverted)
+	return fiber.Map{
+		"byCurrency":  sums,
+		"total":       total,
+		"unconverted": unconverted,
+	}
+}
+
+type ReportHandler struct {
+	reports    *services.ReportService
+	currencies *services.CurrencyService
+}
+
+func NewReportHandler(reports *services.ReportService, currencies *services.CurrencyService) *ReportHandler {
+	return &ReportHandler{reports: reports, currencies: currencies}
+}
+
+// reportQuery is what every report reads from the query string: the receipt filters and
+// the currency to report in.
+type reportQuery struct {
+	filter repository.ReceiptFilter
+	rates  services.Rates
+	target string
+}
+
+// query reads the report query; ?currency= defaults to the base currency. On failure it
+// returns the status to answer with.
+func (h *ReportHandler) query(c *fiber.Ctx) (reportQuery, int, error) {
+	var q reportQuery
+	var err error
+	if q.rates, err = h.currencies.Rates(); err != nil {
+		return q, 500, errors.New("Failed to fetch exchange rates")
+	}
+	if q.target, err = services.ResolveCurrency(q.rates, c.Query("currency"), services.BaseCurrency()); err != nil {
+		return q, 400, err
+	}
+	if q.filter, err = receiptFilter(c, repository.ReceiptFilter{}); err != nil {
+		return q, 400, err
+	}
+	return q, 200, nil
+}
+
+func (h *ReportHandler) Totals(c *fiber.Ctx) error {
+	q, status, err := h.query(c)
+	if err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	totals, err := h.reports.Totals(q.filter)
+	if err != nil {
+		log.Printf("Error summing receipts: %v", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to compute totals"})
+	}
+
+	return c.JSON(fiber.Map{
+		"currency": q.target,
+		"receipts": totals.Receipts,
+		"paid":     normalizeSums(q.rates, totals.Paid, q.target),
+		"billed":   normalizeSums(q.rates, totals.Billed, q.target),
+	})
+}
+
+// Outstanding lists receipts whose activities cost more than has been paid, oldest first.
+func (h *ReportHandler) Outstanding(c *fiber.Ctx) error {
+	q, status, err := h.query(c)
+	if err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	rows, owed, err := h.reports.Outstanding(q.filter, q.rates)
+	if err != nil {
+		log.Printf("Error fetching receipts: %v", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to compute outstanding balances"})
+	}
+
+	return c.JSON(fiber.Map{
+		"currency":    q.target,
+		"receipts":    rows,
+		"outstanding": normalizeSums(q.rates, owed, q.target),
+	})
+}
+
+func (h *ReportHandler) Monthly(c *fiber.Ctx) error {
+	return h.send(c, services.ReportByMonth)
+}
+
+func (h *ReportHandler) Hotels(c *fiber.Ctx) error {
+	return h.send(c, services.ReportByHotel)
+}
+
+func (h *ReportHandler) Cities(c *fiber.Ctx) error {
+	return h.send(c, services.ReportByCity)
+}
+
+func (h *ReportHandler) ActivityTypes(c *fiber.Ctx) error {
+	return h.send(c, services.ReportByActivityType)
+}
+
+func (h *ReportHandler) Agents(c *fiber.Ctx) error {
+	return h.send(c, services.ReportByAgent)
+}
+
+// send builds a breakdown over the receipts selected by the usual receipt filters and
+// sends it as JSON, or as CSV with ?format=csv.
+func (h *ReportHandler) send(c *fiber.Ctx, dimension string) error {
+	format := c.Query("format", "json")
+	if format != "json" && format != "csv" {
+		return c.Status(400).JSON(fiber.Map{"error": "format must be json or csv"})
+	}
+	q, status, err := h.query(c)
+	if err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	rows, err := h.reports.Build(q.filter, dimension, q.rates, q.target)
+	if err != nil {
+		log.Printf("Error building %s report: %v", dimension, err)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to build report"})
+	}
+
+	if format == "csv" {
+		data, err := reportCSV(dimension, rows)
+		if err != nil {
+			log.Printf("Error writing %s report: %v", dimension, err)
+			return c.Status(500).JSON(fiber.Map{"error": "Failed to build report"})
+		}
+		c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+		c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="report_%s.csv"`, dimension))
+		return c.Send(data)
+	}
+	return c.JSON(fiber.Map{
+		"groupBy":  dimension,
+		"currency": q.target,
+		"rows":     rows,
+	})
+}
+
+// csvText keeps a text cell from being read as a formula by spreadsheets, which evaluate
+// cells starting with =, +, - or @.
+func csvText(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+func reportCSV(dimension string, rows []services.ReportRow) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	header := []string{dimension}
+	if dimension == services.ReportByHotel {
+		header = append(header, "city")
+	}
+	header = append(header, "receipts", "activities", "room_nights", "revenue", "paid", "unconverted")
+	if err := w.Write(header); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		record := []string{csvText(row.Label)}
+		if dimension == services.ReportByHotel {
+			record = append(record, csvText(row.City))
+		}
+		record = append(record,
+			strconv.Itoa(row.Receipts),
+			strconv.Itoa(row.Activities),
+			strconv.Itoa(row.RoomNights),
+			row.Revenue.String(),
+			row.Paid.String(),
+			strings.Join(row.Unconverted, " "),
+		)
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Otabek228101/mehmon/models"
+	"github.com/Otabek228101/mehmon/services"
+)
+
+func TestReportCSVNeutralizesFormulas(t *testing.T) {
+	rows := []services.ReportRow{
+		{Label: `=HYPERLINK("http://evil.example","x")`, City: "@SUM(A1)", Revenue: models.MoneyFromCents(-1250)},
+		{Label: "Palazzo", City: "-BARI"},
+	}
+	data, err := reportCSV(services.ReportByHotel, rows)
+	if err != nil {
+		t.Fatalf("reportCSV: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("csv = %q, want a header and 2 rows", data)
+	}
+	if want := `"'=HYPERLINK(""http://evil.example"",""x"")",'@SUM(A1),0,0,0,-12.50,`; !strings.HasPrefix(lines[1], want) {
+		t.Errorf("row 1 = %q, want prefix %q", lines[1], want)
+	}
+	if want := "Palazzo,'-BARI,"; !strings.HasPrefix(lines[2], want) {
+		t.Errorf("row 2 = %q, want prefix %q", lines[2], want)
+	}
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/Otabek228101/mehmon/models"
+	"github.com/Otabek228101/mehmon/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type SearchHandler struct {
+	search *services.SearchService
+}
+
+func NewSearchHandler(search *services.SearchService) *SearchHandler {
+	return &SearchHandler{search: search}
+}
+
+func (h *SearchHandler) Search(c *fiber.Ctx) error {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "Search query is required"})
+	}
+
+	var types []string
+	if v := c.Query("types"); v != "" {
+		for _, t := range strings.Split(v, ",") {
+			t = strings.TrimSpace(t)
+			if t != models.SearchHitReceipt && t != models.SearchHitActivity && t != models.SearchHitProposal {
+				return c.Status(400).JSON(fiber.Map{"error": "types must be a comma separated list of receipt, activity, proposal"})
+			}
+			types = append(types, t)
+		}
+	}
+
+	limit := 50
+	if n, err := strconv.Atoi(c.Query("limit")); err == nil && n > 0 {
+		limit = min(n, maxPageLimit)
+	}
+
+	hits, err := h.search.Search(query, types, limit)
+	if err != nil {
+		log.Printf("Error searching %q: %v", query, err)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to search"})
+	}
+	return c.JSON(fiber.Map{"query": query, "hits": hits})
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+
+	"github.com/Otabek228101/mehmon/repository"
+	"github.com/gofiber/fiber/v2"
+)
+
+// idParam reads the :id route parameter. Anything that is not a positive integer comes back
+// as 0, which no record has, so it is answered like a missing record.
+func idParam(c *fiber.Ctx) uint {
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return 0
+	}
+	return uint(id)
+}
+
+// serviceFailed answers an error returned by a service: 404 with notFound for a missing
+// record, 400 for validation errors and 500 with failed for anything else.
+func serviceFailed(c *fiber.Ctx, err error, notFound, failed string) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return c.Status(404).JSON(fiber.Map{"error": notFound})
+	}
+	if isValidationError(err) {
+		return validationFailed(c, err)
+	}
+	log.Printf("%s: %v", failed, err)
+	return c.Status(500).JSON(fiber.Map{"error": failed})
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"github.com/Otabek228101/mehmon/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type TrashHandler struct {
+	receipts  *services.ReceiptService
+	proposals *services.ProposalService
+}
+
+func NewTrashHandler(receipts *services.ReceiptService, proposals *services.ProposalService) *TrashHandler {
+	return &TrashHandler{receipts: receipts, proposals: proposals}
+}
+
+func (h *TrashHandler) List(c *fiber.Ctx) error {
+	receipts, err := h.receipts.Trash()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch deleted receipts"})
+	}
+
+	proposals, err := h.proposals.Trash()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch deleted proposals"})
+	}
+
+	return c.JSON(fiber.Map{
+		"receipts":  receipts,
+		"proposals": proposals,
+	})
+}
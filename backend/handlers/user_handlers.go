@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/Otabek228101/mehmon/middleware"
+	"github.com/Otabek228101/mehmon/models"
+	"github.com/Otabek228101/mehmon/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type UserHandler struct {
+	users *services.UserService
+}
+
+func NewUserHandler(users *services.UserService) *UserHandler {
+	return &UserHandler{users: users}
+}
+
+// userFailed answers the user service's own refusals before falling back to serviceFailed.
+func userFailed(c *fiber.Ctx, err error, failed string) error {
+	switch {
+	case errors.Is(err, services.ErrEmailTaken):
+		return c.Status(409).JSON(fiber.Map{"error": "A user with this email already exists"})
+	case errors.Is(err, services.ErrOwnAdminRole):
+		return c.Status(400).JSON(fiber.Map{"error": "You cannot remove your own admin role"})
+	case errors.Is(err, services.ErrDeleteSelf):
+		return c.Status(400).JSON(fiber.Map{"error": "You cannot delete your own account"})
+	}
+	return serviceFailed(c, err, "User not found", failed)
+}
+
+func (h *UserHandler) List(c *fiber.Ctx) error {
+	users, err := h.users.List()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch users"})
+	}
+	return c.JSON(users)
+}
+
+func (h *UserHandler) Create(c *fiber.Ctx) error {
+	var req models.UserRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request data"})
+	}
+	user, err := h.users.Create(middleware.CurrentUser(c), req)
+	if err != nil {
+		return userFailed(c, err, "Failed to create user")
+	}
+	return c.Status(201).JSON(user)
+}
+
+func (h *UserHandler) Update(c *fiber.Ctx) error {
+	var req models.UserRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request data"})
+	}
+	user, err := h.users.Update(middleware.CurrentUser(c), idParam(c), req)
+	if err != nil {
+		return userFailed(c, err, "Failed to update user")
+	}
+	return c.JSON(user)
+}
+
+func (h *UserHandler) Delete(c *fiber.Ctx) error {
+	if err := h.users.Delete(middleware.CurrentUser(c), idParam(c)); err != nil {
+		return userFailed(c, err, "Failed to delete user")
+	}
+	return c.JSON(fiber.Map{"message": "User deleted successfully"})
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/Otabek228101/mehmon/validation"
+	"github.com/gofiber/fiber/v2"
+)
+
+func isValidationError(err error) bool {
+	var fields validation.Errors
+	return errors.As(err, &fields)
+}
+
+// validationFailed answers 400, listing field errors when err came from a Validate method.
+func validationFailed(c *fiber.Ctx, err error) error {
+	var fields validation.Errors
+	if errors.As(err, &fields) {
+		return c.Status(400).JSON(fiber.Map{
+			"error":  "Validation failed",
+			"fields": fields,
+		})
+	}
+	return c.Status(400).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
@@ -1,67 +1,222 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
 
+	"github.com/Otabek228101/mehmon/config"
 	"github.com/Otabek228101/mehmon/database"
 	"github.com/Otabek228101/mehmon/handlers"
+	"github.com/Otabek228101/mehmon/middleware"
+	"github.com/Otabek228101/mehmon/repository"
+	"github.com/Otabek228101/mehmon/services"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/joho/godotenv"
+	"gorm.io/gorm/logger"
 )
 
+// command is a subcommand of the binary; run receives the arguments after its name.
+type command struct {
+	args    string
+	summary string
+	run     func(args []string) error
+}
+
+var commands = map[string]command{
+	"serve":       {"", "start the HTTP server (the default)", serve},
+	"migrate":     {migrateArgs, "apply, revert or list schema migrations", runMigrate},
+	"seed":        {"", "add demo hotels, car rentals and documents to empty tables", runSeed},
+	"create-user": {"-email E [-name N] [-role R] [-password P]", "create a user; the password is read from stdin when omitted", runCreateUser},
+	"renumber":    {"", "resync receipt and proposal number counters with the numbers issued", runRenumber},
+	"export":      {"[-o FILE]", "write the catalog and documents as JSON, to stdout by default", runExport},
+	"import":      {"FILE", "load a JSON export, overwriting records with the same ids (- reads stdin)", runImport},
+}
+
+var commandOrder = []string{"serve", "migrate", "seed", "create-user", "renumber", "export", "import"}
+
+func usage() {
+	w := tabwriter.NewWriter(os.Stderr, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "usage: mehmon [-config FILE] [-listen ADDR] [-dsn DSN] [-log-level LEVEL] [-upload-dir DIR] <command> [arguments]")
+	fmt.Fprintln(w)
+	for _, name := range commandOrder {
+		fmt.Fprintf(w, "  %s %s\t%s\n", name, commands[name].args, commands[name].summary)
+	}
+	w.Flush()
+}
+
 func main() {
-	err := godotenv.Load()
+	cfg, args, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		usage()
+		os.Exit(2)
+	}
 	if err != nil {
-		log.Fatal("Error loading .env file")
+		log.Fatal("Invalid configuration: ", err)
 	}
+	config.Current = cfg
+
+	name := "serve"
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		if name != "help" && name != "-h" && name != "--help" {
+			fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		}
+		usage()
+		os.Exit(2)
+	}
+
 	database.Connect()
+	if name != "serve" {
+		// Keep SQL logging off stdout, which carries command output such as exports.
+		database.DB.Logger = database.DB.Logger.LogMode(logger.Silent)
+	}
+	if err := cmd.run(args); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func serve(args []string) error {
+	if len(args) > 0 {
+		return errors.New("serve takes no arguments")
+	}
 	database.Migrate()
-	database.SeedData()
+	database.EnsureAdmin()
+
+	if n, err := services.LinkClients(database.DB); err != nil {
+		log.Printf("Failed to link receipts to clients: %v", err)
+	} else if n > 0 {
+		log.Printf("Linked %d receipts to clients", n)
+	}
+
+	cfg := config.Current
+	if path := cfg.ExchangeRatesFile; path != "" {
+		if n, err := services.NewCurrencyService(repository.NewGormStore(database.DB)).LoadFile(path); err != nil {
+			log.Printf("Failed to load exchange rates from %s: %v", path, err)
+		} else {
+			log.Printf("Loaded %d exchange rates from %s", n, path)
+		}
+	}
 
-	app := fiber.New()
+	app := fiber.New(fiber.Config{BodyLimit: cfg.BodyLimit()})
 
-	app.Static("/uploads", "./uploads")
+	app.Static("/uploads", cfg.UploadDir)
 
 	app.Use(cors.New(cors.Config{
-		AllowOrigins: "*",
+		AllowOrigins: strings.Join(cfg.CORSOrigins, ","),
 		AllowMethods: "GET,POST,PUT,DELETE",
-		AllowHeaders: "Origin, Content-Type, Accept",
+		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
 	}))
 
 	setupRoutes(app)
 
-	log.Printf("Server starting on port 8080")
-	log.Fatal(app.Listen(":8080"))
+	log.Printf("Server starting on %s", cfg.ListenAddr)
+	return app.Listen(cfg.ListenAddr)
 }
 
 func setupRoutes(app *fiber.App) {
+	can := middleware.Require
 	api := app.Group("/api")
 
-	api.Post("/receipts", handlers.CreateReceipt)
-	api.Get("/receipts", handlers.GetReceipts)
-	api.Get("/receipts/search", handlers.SearchReceipts)
-	api.Get("/receipts/:id", handlers.GetReceipt)
-	api.Put("/receipts/:id", handlers.UpdateReceipt)
-	api.Delete("/receipts/:id", handlers.DeleteReceipt)
-
-	api.Get("/hotels", handlers.GetHotels)
-	api.Get("/hotels/:id", handlers.GetHotelByID)
-	api.Post("/hotels", handlers.CreateHotel)
-	api.Put("/hotels/:id", handlers.UpdateHotel)
-	api.Delete("/hotels/:id", handlers.DeleteHotel)
-
-	api.Post("/hotels/:id/images", handlers.UploadHotelImages)
-	api.Get("/hotels/:id/images", handlers.GetHotelImages)
-	api.Get("/hotels/:id/images/base64", handlers.GetHotelImagesBase64)
-
-	api.Post("/proposals", handlers.CreateProposal)
-	api.Get("/proposals", handlers.GetProposals)
-	api.Get("/proposals/:id", handlers.GetProposal)
-	api.Put("/proposals/:id", handlers.UpdateProposal)
-	api.Delete("/proposals/:id", handlers.DeleteProposal)
-
-	api.Get("/car-rentals", handlers.GetCarRentals)
-	api.Post("/car-rentals", handlers.CreateCarRental)
-	api.Delete("/car-rentals/:id", handlers.DeleteCarRental)
+	store := repository.NewGormStore(database.DB)
+	receiptService := services.NewReceiptService(store)
+	proposalService := services.NewProposalService(store)
+	currencyService := services.NewCurrencyService(store)
+	authService := services.NewAuthService(store)
+
+	auth := handlers.NewAuthHandler(authService)
+	users := handlers.NewUserHandler(services.NewUserService(store))
+	audit := handlers.NewAuditHandler(services.NewAuditService(store))
+	search := handlers.NewSearchHandler(services.NewSearchService(store))
+	receipts := handlers.NewReceiptHandler(receiptService)
+	proposals := handlers.NewProposalHandler(proposalService)
+	hotels := handlers.NewHotelHandler(services.NewHotelService(store))
+	carRentals := handlers.NewCarRentalHandler(services.NewCarRentalService(store))
+	clients := handlers.NewClientHandler(services.NewClientService(store), currencyService)
+	trash := handlers.NewTrashHandler(receiptService, proposalService)
+	currencies := handlers.NewCurrencyHandler(currencyService)
+	reports := handlers.NewReportHandler(services.NewReportService(store), currencyService)
+
+	api.Post("/auth/login", auth.Login)
+	api.Post("/auth/logout", auth.Logout)
+	api.Get("/public/receipts/:id", receipts.PublicGet)
+	api.Get("/public/receipts/:id/pdf", receipts.PublicPDF)
+
+	api.Use(middleware.RequireAuth(authService))
+
+	api.Get("/auth/me", auth.Me)
+
+	api.Get("/users", can(middleware.ManageUsers), users.List)
+	api.Post("/users", can(middleware.ManageUsers), users.Create)
+	api.Put("/users/:id", can(middleware.ManageUsers), users.Update)
+	api.Delete("/users/:id", can(middleware.ManageUsers), users.Delete)
+
+	api.Get("/audit", can(middleware.ViewAudit), audit.List)
+
+	api.Get("/search", can(middleware.ViewDocuments), search.Search)
+
+	api.Post("/receipts", can(middleware.WriteDocuments), receipts.Create)
+	api.Get("/receipts", can(middleware.ViewDocuments), receipts.List)
+	api.Get("/receipts/search", can(middleware.ViewDocuments), receipts.Search)
+	api.Get("/receipts/:id", can(middleware.ViewDocuments), receipts.Get)
+	api.Get("/receipts/:id/pdf", can(middleware.ViewDocuments), receipts.PDF)
+	api.Get("/receipts/:id/share", can(middleware.ViewDocuments), receipts.ShareLink)
+	api.Put("/receipts/:id", can(middleware.WriteDocuments), receipts.Update)
+	api.Delete("/receipts/:id", can(middleware.DeleteDocuments), receipts.Delete)
+	api.Post("/receipts/:id/restore", can(middleware.DeleteDocuments), receipts.Restore)
+	api.Get("/receipts/:id/payments", can(middleware.ViewDocuments), receipts.Payments)
+	api.Post("/receipts/:id/payments", can(middleware.WriteDocuments), receipts.AddPayment)
+	api.Delete("/receipts/:id/payments/:paymentId", can(middleware.DeleteDocuments), receipts.DeletePayment)
+
+	api.Get("/hotels", can(middleware.ViewCatalog), hotels.List)
+	api.Get("/hotels/:id", can(middleware.ViewCatalog), hotels.Get)
+	api.Post("/hotels", can(middleware.EditCatalog), hotels.Create)
+	api.Put("/hotels/:id", can(middleware.EditCatalog), hotels.Update)
+	api.Delete("/hotels/:id", can(middleware.EditCatalog), hotels.Delete)
+
+	api.Post("/hotels/:id/images", can(middleware.EditCatalog), hotels.UploadImages)
+	api.Get("/hotels/:id/images", can(middleware.ViewCatalog), hotels.Images)
+	api.Get("/hotels/:id/images/base64", can(middleware.ViewCatalog), hotels.ImagesBase64)
+	api.Get("/hotels/:id/receipts", can(middleware.ViewDocuments), receipts.HotelReceipts)
+
+	api.Post("/proposals", can(middleware.WriteDocuments), proposals.Create)
+	api.Get("/proposals", can(middleware.ViewDocuments), proposals.List)
+	api.Get("/proposals/:id", can(middleware.ViewDocuments), proposals.Get)
+	api.Get("/proposals/:id/pdf", can(middleware.ViewDocuments), proposals.PDF)
+	api.Put("/proposals/:id", can(middleware.WriteDocuments), proposals.Update)
+	api.Post("/proposals/:id/status", can(middleware.WriteDocuments), proposals.UpdateStatus)
+	api.Post("/proposals/:id/convert", can(middleware.WriteDocuments), proposals.Convert)
+	api.Delete("/proposals/:id", can(middleware.DeleteDocuments), proposals.Delete)
+	api.Post("/proposals/:id/restore", can(middleware.DeleteDocuments), proposals.Restore)
+
+	api.Get("/clients", can(middleware.ViewDocuments), clients.List)
+	api.Get("/clients/:id", can(middleware.ViewDocuments), clients.Get)
+	api.Get("/clients/:id/history", can(middleware.ViewDocuments), clients.History)
+	api.Post("/clients", can(middleware.WriteDocuments), clients.Create)
+	api.Put("/clients/:id", can(middleware.WriteDocuments), clients.Update)
+	api.Delete("/clients/:id", can(middleware.DeleteDocuments), clients.Delete)
+
+	api.Get("/trash", can(middleware.DeleteDocuments), trash.List)
+
+	api.Get("/rates", can(middleware.ViewCatalog), currencies.Rates)
+	api.Post("/rates/reload", can(middleware.EditCatalog), currencies.Reload)
+
+	api.Get("/reports/totals", can(middleware.ViewDocuments), reports.Totals)
+	api.Get("/reports/outstanding", can(middleware.ViewDocuments), reports.Outstanding)
+	api.Get("/reports/monthly", can(middleware.ViewDocuments), reports.Monthly)
+	api.Get("/reports/hotels", can(middleware.ViewDocuments), reports.Hotels)
+	api.Get("/reports/cities", can(middleware.ViewDocuments), reports.Cities)
+	api.Get("/reports/activity-types", can(middleware.ViewDocuments), reports.ActivityTypes)
+	api.Get("/reports/agents", can(middleware.ViewDocuments), reports.Agents)
+
+	api.Get("/car-rentals", can(middleware.ViewCatalog), carRentals.List)
+	api.Post("/car-rentals", can(middleware.EditCatalog), carRentals.Create)
+	api.Delete("/car-rentals/:id", can(middleware.EditCatalog), carRentals.Delete)
 }
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/Otabek228101/mehmon/models"
+	"github.com/Otabek228101/mehmon/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	TokenCookie = "token"
+	userKey     = "user"
+)
+
+// RequireAuth rejects requests without a valid token from the cookie or an Authorization
+// header, and makes the user it was issued for the CurrentUser.
+func RequireAuth(auth *services.AuthService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		token := c.Cookies(TokenCookie)
+		if header := c.Get(fiber.HeaderAuthorization); strings.HasPrefix(header, "Bearer ") {
+			token = strings.TrimPrefix(header, "Bearer ")
+		}
+		if token == "" {
+			return c.Status(401).JSON(fiber.Map{"error": "Authentication required"})
+		}
+
+		user, err := auth.Authenticate(token)
+		if errors.Is(err, services.ErrNoSecret) {
+			log.Printf("Cannot verify tokens: %v", err)
+			return c.Status(500).JSON(fiber.Map{"error": "Failed to verify token"})
+		}
+		if err != nil {
+			return c.Status(401).JSON(fiber.Map{"error": "Invalid or expired token"})
+		}
+
+		c.Locals(userKey, &user)
+		return c.Next()
+	}
+}
+
+// CurrentUser returns the user set by RequireAuth, or nil on public routes.
+func CurrentUser(c *fiber.Ctx) *models.User {
+	user, _ := c.Locals(userKey).(*models.User)
+	return user
+}
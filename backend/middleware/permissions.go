@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"github.com/Otabek228101/mehmon/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+type Permission string
+
+const (
+	ViewDocuments   Permission = "documents:view"
+	WriteDocuments  Permission = "documents:write"
+	DeleteDocuments Permission = "documents:delete"
+	ViewCatalog     Permission = "catalog:view"
+	EditCatalog     Permission = "catalog:edit"
+	ViewAudit       Permission = "audit:view"
+	ManageUsers     Permission = "users:manage"
+)
+
+var agentPermissions = []Permission{ViewDocuments, WriteDocuments, ViewCatalog}
+
+var managerPermissions = append(append([]Permission{}, agentPermissions...), DeleteDocuments, EditCatalog, ViewAudit)
+
+var adminPermissions = append(append([]Permission{}, managerPermissions...), ManageUsers)
+
+var rolePermissions = map[string][]Permission{
+	models.RoleAgent:   agentPermissions,
+	models.RoleManager: managerPermissions,
+	models.RoleAdmin:   adminPermissions,
+}
+
+func HasPermission(role string, permission Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Require must run after RequireAuth.
+func Require(permission Permission) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user := CurrentUser(c)
+		if user == nil {
+			return c.Status(401).JSON(fiber.Map{"error": "Authentication required"})
+		}
+		if !HasPermission(user.Role, permission) {
+			return c.Status(403).JSON(fiber.Map{"error": "You do not have permission to perform this action"})
+		}
+		return c.Next()
+	}
+}
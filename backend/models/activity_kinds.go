@@ -0,0 +1,180 @@
+package models
+
+import (
+	"time"
+
+	"github.com/Otabek228101/mehmon/validation"
+)
+
+// Activity kinds. All kinds share the activities table and its flat JSON shape; the typed
+// views below say which columns each kind uses and which of them it requires.
+const (
+	ActivityHotel     = "hotel"
+	ActivityTransfer  = "transfer"
+	ActivityCarRental = "car_rental"
+	ActivityFlight    = "flight"
+	ActivityExcursion = "excursion"
+	ActivityInsurance = "insurance"
+	ActivityOther     = "other"
+)
+
+var ActivityKinds = []string{
+	ActivityHotel,
+	ActivityTransfer,
+	ActivityCarRental,
+	ActivityFlight,
+	ActivityExcursion,
+	ActivityInsurance,
+	ActivityOther,
+}
+
+var TransferTypes = []string{"airport_pickup", "airport_dropoff", "city_transfer", "hotel_transfer"}
+
+// ActivityDetails is the typed view of an Activity. Validate reports errors under the
+// activity's JSON field names.
+type ActivityDetails interface {
+	Kind() string
+	Validate(v *validation.Validator)
+}
+
+type HotelStay struct {
+	HotelID         *uint
+	PropertyName    string
+	PropertyAddress string
+	CheckIn         *time.Time
+	CheckOut        *time.Time
+}
+
+func (HotelStay) Kind() string { return ActivityHotel }
+
+func (h HotelStay) Validate(v *validation.Validator) {
+	if h.HotelID == nil {
+		v.Required("propertyName", h.PropertyName)
+	}
+	requireTime(v, "checkIn", h.CheckIn)
+	requireTime(v, "checkOut", h.CheckOut)
+}
+
+type Transfer struct {
+	TransferType    string
+	PickupLocation  string
+	DropoffLocation string
+	Date            *time.Time
+}
+
+func (Transfer) Kind() string { return ActivityTransfer }
+
+func (t Transfer) Validate(v *validation.Validator) {
+	if v.Required("transferType", t.TransferType) {
+		v.OneOf("transferType", t.TransferType, TransferTypes...)
+	}
+}
+
+// CarRentalUsage is a car rented for the trip; the rental details live in the operator comments.
+type CarRentalUsage struct {
+	CarRentalID     *uint
+	PickupLocation  string
+	DropoffLocation string
+	Comments        string
+}
+
+func (CarRentalUsage) Kind() string { return ActivityCarRental }
+
+func (c CarRentalUsage) Validate(v *validation.Validator) {
+	v.Required("pickupLocation", c.PickupLocation)
+	v.Required("dropoffLocation", c.DropoffLocation)
+	v.Required("description", c.Comments)
+}
+
+// Flight stores the airline in provider, the flight number in reference, the airports in
+// pickupLocation/dropoffLocation and departure/arrival in checkIn/checkOut.
+type Flight struct {
+	Airline      string
+	FlightNumber string
+	From         string
+	To           string
+	Departure    *time.Time
+	Arrival      *time.Time
+}
+
+func (Flight) Kind() string { return ActivityFlight }
+
+func (f Flight) Validate(v *validation.Validator) {
+	v.Required("provider", f.Airline)
+	v.Required("reference", f.FlightNumber)
+	v.Required("pickupLocation", f.From)
+	v.Required("dropoffLocation", f.To)
+	requireTime(v, "checkIn", f.Departure)
+}
+
+// Excursion stores its name in propertyName, the meeting point in propertyAddress, the
+// operator in provider and the day in checkIn.
+type Excursion struct {
+	Name         string
+	MeetingPoint string
+	Operator     string
+	Date         *time.Time
+}
+
+func (Excursion) Kind() string { return ActivityExcursion }
+
+func (e Excursion) Validate(v *validation.Validator) {
+	v.Required("propertyName", e.Name)
+	requireTime(v, "checkIn", e.Date)
+}
+
+// Insurance stores the insurer in provider, the policy number in reference and the
+// coverage period in checkIn/checkOut.
+type Insurance struct {
+	Insurer       string
+	PolicyNumber  string
+	CoverageStart *time.Time
+	CoverageEnd   *time.Time
+}
+
+func (Insurance) Kind() string { return ActivityInsurance }
+
+func (i Insurance) Validate(v *validation.Validator) {
+	v.Required("provider", i.Insurer)
+	v.Required("reference", i.PolicyNumber)
+	requireTime(v, "checkIn", i.CoverageStart)
+	requireTime(v, "checkOut", i.CoverageEnd)
+}
+
+type OtherService struct {
+	Description string
+}
+
+func (OtherService) Kind() string { return ActivityOther }
+
+func (o OtherService) Validate(v *validation.Validator) {
+	v.Required("description", o.Description)
+}
+
+// requireTime flags a missing date unless the field already failed to parse.
+func requireTime(v *validation.Validator, field string, t *time.Time) {
+	if t == nil && !v.Has(field) {
+		v.Add(field, validation.CodeRequired, "is required")
+	}
+}
+
+// Details returns the typed view for the activity's Type, or false for unknown types.
+func (a Activity) Details() (ActivityDetails, bool) {
+	switch a.Type {
+	case ActivityHotel:
+		return HotelStay{HotelID: a.HotelID, PropertyName: a.PropertyName, PropertyAddress: a.PropertyAddress, CheckIn: a.CheckIn, CheckOut: a.CheckOut}, true
+	case ActivityTransfer:
+		return Transfer{TransferType: a.TransferType, PickupLocation: a.PickupLocation, DropoffLocation: a.DropoffLocation, Date: a.CheckIn}, true
+	case ActivityCarRental:
+		return CarRentalUsage{CarRentalID: a.CarRentalID, PickupLocation: a.PickupLocation, DropoffLocation: a.DropoffLocation, Comments: a.Description}, true
+	case ActivityFlight:
+		return Flight{Airline: a.Provider, FlightNumber: a.Reference, From: a.PickupLocation, To: a.DropoffLocation, Departure: a.CheckIn, Arrival: a.CheckOut}, true
+	case ActivityExcursion:
+		return Excursion{Name: a.PropertyName, MeetingPoint: a.PropertyAddress, Operator: a.Provider, Date: a.CheckIn}, true
+	case ActivityInsurance:
+		return Insurance{Insurer: a.Provider, PolicyNumber: a.Reference, CoverageStart: a.CheckIn, CoverageEnd: a.CheckOut}, true
+	case ActivityOther:
+		return OtherService{Description: a.Description}, true
+	}
+	return nil, false
+}
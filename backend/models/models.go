@@ -1,20 +1,59 @@
 package models
 
 import (
+	"fmt"
+	"strings"
 	"time"
+
+	"github.com/Otabek228101/mehmon/validation"
+	"gorm.io/gorm"
 )
 
 type Receipt struct {
-	ID            uint       `json:"id" gorm:"primaryKey"`
-	ReceiptNumber string     `json:"receiptNumber" gorm:"column:receipt_number"`
-	ClientName    string     `json:"clientName" gorm:"column:client_name"`
-	ClientEmail   string     `json:"clientEmail" gorm:"column:client_email"`
-	ClientPhone   string     `json:"clientPhone" gorm:"column:client_phone"`
-	ReceiptDate   time.Time  `json:"receiptDate" gorm:"column:receipt_date"`
-	AmountPaid    float64    `json:"amountPaid" gorm:"column:amount_paid"`
-	CreatedAt     time.Time  `json:"createdAt"`
-	UpdatedAt     time.Time  `json:"updatedAt"`
-	Activities    []Activity `json:"activities" gorm:"foreignKey:ReceiptID"`
+	ID            uint           `json:"id" gorm:"primaryKey"`
+	ReceiptNumber string         `json:"receiptNumber" gorm:"column:receipt_number;uniqueIndex"`
+	ClientName    string         `json:"clientName" gorm:"column:client_name"`
+	ClientEmail   string         `json:"clientEmail" gorm:"column:client_email"`
+	ClientPhone   string         `json:"clientPhone" gorm:"column:client_phone"`
+	ReceiptDate   time.Time      `json:"receiptDate" gorm:"column:receipt_date"`
+	AmountPaid    Money          `json:"amountPaid" gorm:"column:amount_paid"`
+	Currency      string         `json:"currency" gorm:"column:currency;size:3;not null"`
+	ProposalID    *uint          `json:"proposalId" gorm:"column:proposal_id;index"`
+	ClientID      *uint          `json:"clientId" gorm:"column:client_id;index"`
+	CreatedByID   *uint          `json:"createdById" gorm:"column:created_by_id;index"`
+	CreatedAt     time.Time      `json:"createdAt"`
+	UpdatedAt     time.Time      `json:"updatedAt"`
+	DeletedAt     gorm.DeletedAt `json:"deletedAt" gorm:"index"`
+	Activities    []Activity     `json:"activities" gorm:"foreignKey:ReceiptID"`
+	Payments      []Payment      `json:"payments,omitempty" gorm:"foreignKey:ReceiptID"`
+	Client        *Client        `json:"-" gorm:"foreignKey:ClientID;constraint:OnDelete:SET NULL"`
+}
+
+// Payment is one installment against a receipt, in the receipt's currency.
+// Receipt.AmountPaid is kept equal to the sum of its payments.
+type Payment struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	ReceiptID uint      `json:"receiptId" gorm:"column:receipt_id;not null;index"`
+	PaidAt    time.Time `json:"date" gorm:"column:paid_at;not null"`
+	Method    string    `json:"method" gorm:"column:method;size:20;not null"`
+	Amount    Money     `json:"amount" gorm:"column:amount"`
+	Reference string    `json:"reference" gorm:"column:reference"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+const (
+	PaymentCash     = "cash"
+	PaymentCard     = "card"
+	PaymentTransfer = "transfer"
+)
+
+// NormalizePaymentMethod lowercases method and defaults it to cash.
+func NormalizePaymentMethod(method string) string {
+	method = strings.ToLower(strings.TrimSpace(method))
+	if method == "" {
+		return PaymentCash
+	}
+	return method
 }
 
 type Activity struct {
@@ -25,13 +64,20 @@ type Activity struct {
 	PropertyAddress string     `json:"propertyAddress" gorm:"column:property_address"`
 	CheckIn         *time.Time `json:"checkIn" gorm:"column:check_in"`
 	CheckOut        *time.Time `json:"checkOut" gorm:"column:check_out"`
-	Amount          float64    `json:"amount" gorm:"column:amount"`
+	Amount          Money      `json:"amount" gorm:"column:amount"`
+	Currency        string     `json:"currency" gorm:"column:currency;size:3;not null"`
 	PickupLocation  string     `json:"pickupLocation" gorm:"column:pickup_location"`
 	DropoffLocation string     `json:"dropoffLocation" gorm:"column:dropoff_location"`
 	TransferType    string     `json:"transferType" gorm:"column:transfer_type"`
 	Description     string     `json:"description" gorm:"column:description"`
+	Provider        string     `json:"provider" gorm:"column:provider"`
+	Reference       string     `json:"reference" gorm:"column:reference"`
+	HotelID         *uint      `json:"hotelId" gorm:"column:hotel_id;index"`
+	CarRentalID     *uint      `json:"carRentalId" gorm:"column:car_rental_id;index"`
 	CreatedAt       time.Time  `json:"createdAt"`
 	UpdatedAt       time.Time  `json:"updatedAt"`
+	Hotel           *Hotel     `json:"-" gorm:"foreignKey:HotelID;constraint:OnDelete:SET NULL"`
+	CarRental       *CarRental `json:"-" gorm:"foreignKey:CarRentalID;constraint:OnDelete:SET NULL"`
 }
 
 type Hotel struct {
@@ -64,20 +110,57 @@ type CarRental struct {
 }
 
 type Proposal struct {
-	ID             uint           `json:"id" gorm:"primaryKey"`
-	ProposalNumber string         `json:"proposalNumber" gorm:"column:proposal_number"`
-	ClientName     string         `json:"clientName" gorm:"column:client_name"`
-	Guests         int            `json:"guests" gorm:"column:guests"`
-	CheckIn        time.Time      `json:"checkIn" gorm:"column:check_in"`
-	CheckOut       time.Time      `json:"checkOut" gorm:"column:check_out"`
-	Price          float64        `json:"price" gorm:"column:price"`
-	Breakfast      bool           `json:"breakfast" gorm:"column:breakfast;default:false"`
-	FreeCancel     bool           `json:"freeCancel" gorm:"column:free_cancel;default:false"`
-	HotelID        uint           `json:"hotelId" gorm:"column:hotel_id"`
-	CreatedAt      time.Time      `json:"createdAt"`
-	UpdatedAt      time.Time      `json:"updatedAt"`
-	Hotel          *Hotel         `json:"hotel" gorm:"foreignKey:HotelID"`
-	Rooms          []ProposalRoom `json:"rooms" gorm:"foreignKey:ProposalID"`
+	ID             uint                   `json:"id" gorm:"primaryKey"`
+	ProposalNumber string                 `json:"proposalNumber" gorm:"column:proposal_number;uniqueIndex"`
+	ClientName     string                 `json:"clientName" gorm:"column:client_name"`
+	Guests         int                    `json:"guests" gorm:"column:guests"`
+	CheckIn        time.Time              `json:"checkIn" gorm:"column:check_in"`
+	CheckOut       time.Time              `json:"checkOut" gorm:"column:check_out"`
+	Price          Money                  `json:"price" gorm:"column:price"`
+	Currency       string                 `json:"currency" gorm:"column:currency;size:3;not null"`
+	Breakfast      bool                   `json:"breakfast" gorm:"column:breakfast;default:false"`
+	FreeCancel     bool                   `json:"freeCancel" gorm:"column:free_cancel;default:false"`
+	HotelID        uint                   `json:"hotelId" gorm:"column:hotel_id"`
+	ReceiptID      *uint                  `json:"receiptId" gorm:"column:receipt_id;index"`
+	ClientID       *uint                  `json:"clientId" gorm:"column:client_id;index"`
+	Status         string                 `json:"status" gorm:"column:status;default:draft;index"`
+	CreatedAt      time.Time              `json:"createdAt"`
+	UpdatedAt      time.Time              `json:"updatedAt"`
+	DeletedAt      gorm.DeletedAt         `json:"deletedAt" gorm:"index"`
+	Hotel          *Hotel                 `json:"hotel" gorm:"foreignKey:HotelID"`
+	Rooms          []ProposalRoom         `json:"rooms" gorm:"foreignKey:ProposalID"`
+	StatusHistory  []ProposalStatusChange `json:"statusHistory,omitempty" gorm:"foreignKey:ProposalID"`
+	Client         *Client                `json:"-" gorm:"foreignKey:ClientID;constraint:OnDelete:SET NULL"`
+}
+
+// Client is a customer. Receipts and proposals keep their own copy of the client's name
+// and contacts as they were when the document was issued. Email is stored lowercased and
+// phone as digits with an optional leading +, which is what duplicates are matched on.
+type Client struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"column:name;not null"`
+	Email     string    `json:"email" gorm:"column:email;index"`
+	Phone     string    `json:"phone" gorm:"column:phone;index"`
+	Notes     string    `json:"notes" gorm:"column:notes"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+const (
+	ProposalStatusDraft    = "draft"
+	ProposalStatusSent     = "sent"
+	ProposalStatusAccepted = "accepted"
+	ProposalStatusRejected = "rejected"
+	ProposalStatusExpired  = "expired"
+)
+
+type ProposalStatusChange struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	ProposalID uint      `json:"proposalId" gorm:"column:proposal_id;index"`
+	FromStatus string    `json:"fromStatus" gorm:"column:from_status"`
+	ToStatus   string    `json:"toStatus" gorm:"column:to_status"`
+	Note       string    `json:"note" gorm:"column:note"`
+	CreatedAt  time.Time `json:"createdAt"`
 }
 
 type ProposalRoom struct {
@@ -86,6 +169,82 @@ type ProposalRoom struct {
 	Count      int  `json:"count" gorm:"column:count"`
 }
 
+type User struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	Email        string    `json:"email" gorm:"column:email;uniqueIndex;not null"`
+	Name         string    `json:"name" gorm:"column:name"`
+	Role         string    `json:"role" gorm:"column:role;not null;default:agent"`
+	PasswordHash string    `json:"-" gorm:"column:password_hash;not null"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+const (
+	RoleAgent   = "agent"
+	RoleManager = "manager"
+	RoleAdmin   = "admin"
+)
+
+type AuditLog struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	ActorID    *uint     `json:"actorId" gorm:"column:actor_id;index"`
+	ActorEmail string    `json:"actorEmail" gorm:"column:actor_email"`
+	Entity     string    `json:"entity" gorm:"column:entity;index:idx_audit_entity"`
+	EntityID   uint      `json:"entityId" gorm:"column:entity_id;index:idx_audit_entity"`
+	Action     string    `json:"action" gorm:"column:action"`
+	Before     JSONText  `json:"before" gorm:"column:before_state;type:text"`
+	After      JSONText  `json:"after" gorm:"column:after_state;type:text"`
+	Diff       JSONText  `json:"diff" gorm:"column:diff;type:text"`
+	CreatedAt  time.Time `json:"createdAt" gorm:"index"`
+}
+
+// JSONText is a JSON document kept in a text column and emitted as raw JSON.
+type JSONText string
+
+func (j JSONText) MarshalJSON() ([]byte, error) {
+	if j == "" {
+		return []byte("null"), nil
+	}
+	return []byte(j), nil
+}
+
+func (j *JSONText) UnmarshalJSON(b []byte) error {
+	*j = JSONText(b)
+	return nil
+}
+
+type ExchangeRate struct {
+	Currency  string    `json:"currency" gorm:"column:currency;primaryKey;size:3"`
+	Rate      float64   `json:"rate" gorm:"column:rate;not null"`
+	Source    string    `json:"source" gorm:"column:source"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Search hit types.
+const (
+	SearchHitReceipt  = "receipt"
+	SearchHitActivity = "activity"
+	SearchHitProposal = "proposal"
+)
+
+// SearchHit is a receipt, activity or proposal found by free-text search. ReceiptID is set
+// for activities.
+type SearchHit struct {
+	Type      string  `json:"type"`
+	ID        uint    `json:"id"`
+	ReceiptID *uint   `json:"receiptId,omitempty"`
+	Number    string  `json:"number"`
+	Title     string  `json:"title"`
+	Snippet   string  `json:"snippet"`
+	Rank      float64 `json:"rank"`
+}
+
+type NumberSequence struct {
+	Name   string `json:"name" gorm:"column:name;primaryKey"`
+	Period string `json:"period" gorm:"column:period;primaryKey"`
+	Value  int    `json:"value" gorm:"column:value;not null"`
+}
+
 type CreateHotelRequest struct {
 	Name         string `json:"name"`
 	City         string `json:"city"`
@@ -98,18 +257,258 @@ type CreateHotelRequest struct {
 	Breakfast    bool   `json:"breakfast"`
 }
 
+func (r CreateHotelRequest) Validate() error {
+	v := validation.New()
+	v.Required("name", r.Name)
+	v.Required("city", r.City)
+	v.Required("address", r.Address)
+	v.Check(r.Stars >= 1 && r.Stars <= 5, "stars", validation.CodeOutOfRange, "must be between 1 and 5")
+	v.OneOf("type", r.Type, "hotel", "apartment")
+	return v.Err()
+}
+
 type ProposalRequest struct {
 	ClientName string        `json:"clientName"`
+	ClientID   *uint         `json:"clientId"`
 	Guests     int           `json:"guests"`
 	CheckIn    string        `json:"checkIn"`
 	CheckOut   string        `json:"checkOut"`
-	Price      float64       `json:"price"`
+	Price      Money         `json:"price"`
+	Currency   string        `json:"currency"`
 	Breakfast  bool          `json:"breakfast"`
 	FreeCancel bool          `json:"freeCancel"`
 	HotelID    uint          `json:"hotelId"`
 	Rooms      []RoomRequest `json:"rooms"`
 }
 
+func (r ProposalRequest) Validate() error {
+	v := validation.New()
+	if r.ClientID == nil {
+		v.Required("clientName", r.ClientName)
+	}
+	v.Check(r.Guests >= 1, "guests", validation.CodeOutOfRange, "must be at least 1")
+	v.Required("checkIn", r.CheckIn)
+	v.Required("checkOut", r.CheckOut)
+	checkIn, okIn := v.Date("checkIn", r.CheckIn)
+	checkOut, okOut := v.Date("checkOut", r.CheckOut)
+	if okIn && okOut {
+		v.Check(!checkOut.Before(checkIn), "checkOut", validation.CodeBeforeCheckIn, "must not be before check-in")
+	}
+	v.Check(r.Price.Sign() >= 0, "price", validation.CodeNegative, "must not be negative")
+	v.Currency("currency", r.Currency)
+	v.Check(r.HotelID != 0, "hotelId", validation.CodeRequired, "is required")
+	if v.Check(len(r.Rooms) > 0, "rooms", validation.CodeRequired, "at least one room is required") {
+		for i, room := range r.Rooms {
+			v.Index("rooms", i).Check(room.Count > 0, "count", validation.CodeOutOfRange, "must be greater than 0")
+		}
+	}
+	return v.Err()
+}
+
 type RoomRequest struct {
 	Count int `json:"count"`
 }
+
+// ReceiptRequest.AmountPaid is optional: a receipt without payments records it as the first
+// payment, made with PaymentMethod. Once payments exist they are changed through
+// /receipts/:id/payments instead.
+type ReceiptRequest struct {
+	ClientName    string            `json:"clientName"`
+	ClientEmail   string            `json:"clientEmail"`
+	ClientPhone   string            `json:"clientPhone"`
+	ReceiptDate   string            `json:"receiptDate"`
+	AmountPaid    *Money            `json:"amountPaid"`
+	PaymentMethod string            `json:"paymentMethod"`
+	Currency      string            `json:"currency"`
+	Activities    []ActivityRequest `json:"activities"`
+	ClientID      *uint             `json:"clientId"`
+}
+
+func (r ReceiptRequest) Validate() error {
+	v := validation.New()
+	// A picked client supplies the name when it is left empty. Email and phone are optional
+	// and only checked for format.
+	if r.ClientID == nil {
+		v.Required("clientName", r.ClientName)
+	}
+	v.Email("clientEmail", r.ClientEmail)
+	v.Phone("clientPhone", r.ClientPhone)
+	if v.Required("receiptDate", r.ReceiptDate) {
+		v.Date("receiptDate", r.ReceiptDate)
+	}
+	if r.AmountPaid != nil {
+		v.Check(r.AmountPaid.Sign() >= 0, "amountPaid", validation.CodeNegative, "must not be negative")
+	}
+	if r.PaymentMethod != "" {
+		v.OneOf("paymentMethod", NormalizePaymentMethod(r.PaymentMethod), PaymentCash, PaymentCard, PaymentTransfer)
+	}
+	v.Currency("currency", r.Currency)
+	for i, activity := range r.Activities {
+		activity.validate(v.Index("activities", i))
+	}
+	return v.Err()
+}
+
+type ActivityRequest struct {
+	Type            string  `json:"type"`
+	PropertyName    string  `json:"propertyName"`
+	PropertyAddress string  `json:"propertyAddress"`
+	CheckIn         *string `json:"checkIn"`
+	CheckOut        *string `json:"checkOut"`
+	Amount          Money   `json:"amount"`
+	Currency        string  `json:"currency"`
+	PickupLocation  string  `json:"pickupLocation"`
+	DropoffLocation string  `json:"dropoffLocation"`
+	TransferType    string  `json:"transferType"`
+	Description     string  `json:"description"`
+	Provider        string  `json:"provider"`
+	Reference       string  `json:"reference"`
+	HotelID         *uint   `json:"hotelId"`
+	CarRentalID     *uint   `json:"carRentalId"`
+}
+
+func (r ActivityRequest) validate(v *validation.Validator) {
+	v.Required("type", r.Type)
+	var checkIn, checkOut time.Time
+	okIn, okOut := false, false
+	if r.CheckIn != nil {
+		checkIn, okIn = v.Date("checkIn", *r.CheckIn)
+	}
+	if r.CheckOut != nil {
+		checkOut, okOut = v.Date("checkOut", *r.CheckOut)
+	}
+	if okIn && okOut {
+		v.Check(!checkOut.Before(checkIn), "checkOut", validation.CodeBeforeCheckIn, "must not be before check-in")
+	}
+	v.Check(r.Amount.Sign() >= 0, "amount", validation.CodeNegative, "must not be negative")
+	v.Currency("currency", r.Currency)
+	if r.HotelID != nil {
+		v.Check(r.Type == ActivityHotel, "hotelId", validation.CodeNotAllowed, "is only allowed on hotel activities")
+	}
+	if r.CarRentalID != nil {
+		v.Check(r.Type == ActivityCarRental, "carRentalId", validation.CodeNotAllowed, "is only allowed on car rental activities")
+	}
+
+	if r.Type == "" {
+		return
+	}
+	if details, ok := r.Activity().Details(); ok {
+		details.Validate(v)
+	} else {
+		v.OneOf("type", r.Type, ActivityKinds...)
+	}
+}
+
+// Activity builds the activity the request describes; dates that do not parse are left out.
+func (r ActivityRequest) Activity() Activity {
+	return Activity{
+		Type:            r.Type,
+		PropertyName:    r.PropertyName,
+		PropertyAddress: r.PropertyAddress,
+		CheckIn:         optionalTime(r.CheckIn),
+		CheckOut:        optionalTime(r.CheckOut),
+		Amount:          r.Amount,
+		Currency:        r.Currency,
+		PickupLocation:  r.PickupLocation,
+		DropoffLocation: r.DropoffLocation,
+		TransferType:    r.TransferType,
+		Description:     r.Description,
+		Provider:        r.Provider,
+		Reference:       r.Reference,
+		HotelID:         r.HotelID,
+		CarRentalID:     r.CarRentalID,
+	}
+}
+
+func optionalTime(value *string) *time.Time {
+	if value == nil || *value == "" {
+		return nil
+	}
+	t, err := validation.ParseTime(*value)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+type PaymentRequest struct {
+	Date      string `json:"date"`
+	Method    string `json:"method"`
+	Amount    Money  `json:"amount"`
+	Reference string `json:"reference"`
+}
+
+func (r PaymentRequest) Validate() error {
+	v := validation.New()
+	v.OneOf("method", NormalizePaymentMethod(r.Method), PaymentCash, PaymentCard, PaymentTransfer)
+	v.Check(!r.Amount.IsZero(), "amount", validation.CodeZero, "must not be zero")
+	v.Date("date", r.Date)
+	return v.Err()
+}
+
+type ClientRequest struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Phone string `json:"phone"`
+	Notes string `json:"notes"`
+}
+
+func (r ClientRequest) Validate() error {
+	v := validation.New()
+	v.Required("name", r.Name)
+	v.Email("email", strings.TrimSpace(r.Email))
+	v.Phone("phone", strings.TrimSpace(r.Phone))
+	return v.Err()
+}
+
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+func (r LoginRequest) Validate() error {
+	v := validation.New()
+	v.Required("email", r.Email)
+	v.Required("password", r.Password)
+	return v.Err()
+}
+
+type UserRequest struct {
+	Email    string `json:"email"`
+	Name     string `json:"name"`
+	Role     string `json:"role"`
+	Password string `json:"password"`
+}
+
+const minPasswordLength = 8
+
+// Validate checks a user request; creating requires email and password, while updates
+// only check the fields that are set.
+func (r UserRequest) Validate(creating bool) error {
+	v := validation.New()
+	if creating {
+		v.Required("email", r.Email)
+		v.Required("password", r.Password)
+	}
+	v.Email("email", strings.TrimSpace(r.Email))
+	if r.Role != "" {
+		v.OneOf("role", r.Role, RoleAgent, RoleManager, RoleAdmin)
+	}
+	if r.Password != "" {
+		v.Check(len(r.Password) >= minPasswordLength, "password", validation.CodeTooShort, fmt.Sprintf("must be at least %d characters", minPasswordLength))
+	}
+	return v.Err()
+}
+
+type ProposalStatusRequest struct {
+	Status string `json:"status"`
+	Note   string `json:"note"`
+}
+
+func (r ProposalStatusRequest) Validate() error {
+	v := validation.New()
+	if v.Required("status", r.Status) {
+		v.OneOf("status", r.Status, ProposalStatusDraft, ProposalStatusSent, ProposalStatusAccepted, ProposalStatusRejected, ProposalStatusExpired)
+	}
+	return v.Err()
+}
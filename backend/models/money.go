@@ -0,0 +1,161 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Money is an exact amount in minor units (hundredths). It is stored as numeric(14,2)
+// and encoded in JSON as a plain number so existing clients keep working. It is a struct
+// so that plain numbers are never mistaken for an amount; build one with MoneyFromCents,
+// MoneyFromFloat or ParseMoney.
+type Money struct {
+	cents int64
+}
+
+const maxCents = 99999999999999
+
+var moneyPattern = regexp.MustCompile(`^-?\d+(\.\d+)?$`)
+
+func MoneyFromCents(cents int64) Money {
+	return Money{cents}
+}
+
+func MoneyFromFloat(f float64) Money {
+	return Money{int64(math.Round(f * 100))}
+}
+
+// ParseMoney reads a plain decimal string such as "150" or "-12.5", rounding half away
+// from zero to two decimal places. Exponents, signs other than a leading minus, and
+// surrounding text are rejected.
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if !moneyPattern.MatchString(s) {
+		return Money{}, fmt.Errorf("invalid amount %q", s)
+	}
+	digits, negative := strings.CutPrefix(s, "-")
+	whole, fraction, _ := strings.Cut(digits, ".")
+	whole = strings.TrimLeft(whole, "0")
+	if len(whole) > 12 {
+		return Money{}, fmt.Errorf("amount %q out of range", s)
+	}
+	fraction += "000"
+	cents, _ := strconv.ParseInt(whole+fraction[:2], 10, 64)
+	if fraction[2] >= '5' {
+		cents++
+	}
+	if cents > maxCents {
+		return Money{}, fmt.Errorf("amount %q out of range", s)
+	}
+	if negative {
+		cents = -cents
+	}
+	return Money{cents}, nil
+}
+
+func (m Money) Cents() int64 {
+	return m.cents
+}
+
+func (m Money) Float64() float64 {
+	return float64(m.cents) / 100
+}
+
+func (m Money) IsZero() bool {
+	return m.cents == 0
+}
+
+// Sign returns -1, 0 or 1 for negative, zero and positive amounts.
+func (m Money) Sign() int {
+	switch {
+	case m.cents < 0:
+		return -1
+	case m.cents > 0:
+		return 1
+	}
+	return 0
+}
+
+func (m Money) Add(o Money) Money {
+	return Money{m.cents + o.cents}
+}
+
+func (m Money) Sub(o Money) Money {
+	return Money{m.cents - o.cents}
+}
+
+func (m Money) Less(o Money) bool {
+	return m.cents < o.cents
+}
+
+func (m Money) String() string {
+	sign := ""
+	v := m.cents
+	if v < 0 {
+		sign, v = "-", -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/100, v%100)
+}
+
+func (Money) GormDataType() string {
+	return "numeric(14,2)"
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		*m = Money{}
+		return nil
+	}
+	if strings.HasPrefix(s, `"`) {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+	}
+	v, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+func (m *Money) Scan(value any) error {
+	switch v := value.(type) {
+	case nil:
+		*m = Money{}
+	case int64:
+		*m = Money{v * 100}
+	case float64:
+		*m = MoneyFromFloat(v)
+	case []byte:
+		return m.scanString(string(v))
+	case string:
+		return m.scanString(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Money", value)
+	}
+	return nil
+}
+
+func (m *Money) scanString(s string) error {
+	v, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
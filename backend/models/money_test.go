@@ -0,0 +1,132 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in    string
+		cents int64
+	}{
+		{"0", 0},
+		{"150", 15000},
+		{" 150 ", 15000},
+		{"-12.5", -1250},
+		{"0.01", 1},
+		{"007.10", 710},
+		{"1.005", 101},
+		{"1.004", 100},
+		{"-1.005", -101},
+		{"999999999999.99", 99999999999999},
+	}
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.in)
+		if err != nil {
+			t.Errorf("ParseMoney(%q): %v", tt.in, err)
+			continue
+		}
+		if got.Cents() != tt.cents {
+			t.Errorf("ParseMoney(%q) = %d cents, want %d", tt.in, got.Cents(), tt.cents)
+		}
+	}
+
+	for _, in := range []string{"", "-", ".5", "5.", "+5", "1e3", "0x10", "1/2", "1,50", "12 USD", "NaN", "Inf", "--1", "1000000000000", "999999999999.995"} {
+		if got, err := ParseMoney(in); err == nil {
+			t.Errorf("ParseMoney(%q) = %v, want an error", in, got)
+		}
+	}
+}
+
+func TestMoneyScan(t *testing.T) {
+	tests := []struct {
+		in    any
+		cents int64
+	}{
+		{nil, 0},
+		{int64(150), 15000},
+		{int64(-3), -300},
+		{150.5, 15050},
+		{0.1 + 0.2, 30},
+		{[]byte("12.34"), 1234},
+		{"99.99", 9999},
+		{"-0.50", -50},
+	}
+	for _, tt := range tests {
+		m := MoneyFromCents(1)
+		if err := m.Scan(tt.in); err != nil {
+			t.Errorf("Scan(%#v): %v", tt.in, err)
+			continue
+		}
+		if m.Cents() != tt.cents {
+			t.Errorf("Scan(%#v) = %d cents, want %d", tt.in, m.Cents(), tt.cents)
+		}
+	}
+
+	for _, in := range []any{"abc", []byte("1e2"), true, int32(5)} {
+		var m Money
+		if err := m.Scan(in); err == nil {
+			t.Errorf("Scan(%#v) = %v, want an error", in, m)
+		}
+	}
+}
+
+func TestMoneyValue(t *testing.T) {
+	tests := []struct {
+		cents int64
+		want  string
+	}{
+		{0, "0.00"},
+		{5, "0.05"},
+		{15000, "150.00"},
+		{-1250, "-12.50"},
+		{-5, "-0.05"},
+	}
+	for _, tt := range tests {
+		got, err := MoneyFromCents(tt.cents).Value()
+		if err != nil || got != tt.want {
+			t.Errorf("Value of %d cents = %v, %v; want %q", tt.cents, got, err, tt.want)
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	var body struct {
+		A Money `json:"a"`
+		B Money `json:"b"`
+		C Money `json:"c"`
+	}
+	if err := json.Unmarshal([]byte(`{"a": 12.5, "b": "7", "c": null}`), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.A.Cents() != 1250 || body.B.Cents() != 700 || !body.C.IsZero() {
+		t.Fatalf("decoded %+v", body)
+	}
+	out, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"a":12.50,"b":7.00,"c":0.00}` {
+		t.Fatalf("encoded %s", out)
+	}
+	if err := json.Unmarshal([]byte(`{"a": 1e3}`), &body); err == nil {
+		t.Fatal("exponent accepted")
+	}
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	a, b := MoneyFromCents(1050), MoneyFromCents(300)
+	if got := a.Add(b); got != MoneyFromCents(1350) {
+		t.Errorf("Add = %v", got)
+	}
+	if got := b.Sub(a); got != MoneyFromCents(-750) || got.Sign() != -1 {
+		t.Errorf("Sub = %v", got)
+	}
+	if !b.Less(a) || a.Less(b) {
+		t.Error("Less is wrong")
+	}
+	if MoneyFromFloat(0.1+0.2) != MoneyFromCents(30) {
+		t.Error("MoneyFromFloat does not round to cents")
+	}
+}
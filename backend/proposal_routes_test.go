@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Otabek228101/mehmon/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestProposalWorkflow(t *testing.T) {
+	s := newTestServer(t)
+	hotel := s.createHotel("Palazzo", "BARI")
+
+	var proposal models.Proposal
+	s.expect(http.StatusCreated, http.MethodPost, "/api/proposals", proposalPayload(hotel.ID, "Ann"), &proposal)
+	if proposal.ProposalNumber == "" || proposal.Status != models.ProposalStatusDraft || proposal.Hotel == nil {
+		t.Fatalf("created proposal = %+v, want a numbered draft with its hotel", proposal)
+	}
+	path := "/api/proposals/" + id(proposal.ID)
+
+	update := proposalPayload(hotel.ID, "Ann Lee")
+	update["rooms"] = []fiber.Map{{"count": 1}, {"count": 2}}
+	var updated models.Proposal
+	s.expect(http.StatusOK, http.MethodPut, path, update, &updated)
+	if updated.ClientName != "Ann Lee" || len(updated.Rooms) != 2 {
+		t.Fatalf("updated proposal = %+v, want Ann Lee with 2 rooms", updated)
+	}
+
+	status, contentType, pdf := s.raw(http.MethodGet, path+"/pdf", nil)
+	if status != http.StatusOK || contentType != "application/pdf" || !strings.HasPrefix(string(pdf), "%PDF") {
+		t.Fatalf("pdf: status %d, content type %q", status, contentType)
+	}
+
+	var body fiber.Map
+	s.expect(http.StatusConflict, http.MethodPost, path+"/convert", nil, &body)
+	s.expect(http.StatusConflict, http.MethodPost, path+"/status", fiber.Map{"status": "accepted"}, &body)
+	if !strings.Contains(body["error"].(string), "from draft to accepted") {
+		t.Fatalf("transition error = %v", body["error"])
+	}
+	s.expect(http.StatusBadRequest, http.MethodPost, path+"/status", fiber.Map{"status": "lost"}, nil)
+	s.expect(http.StatusOK, http.MethodPost, path+"/status", fiber.Map{"status": "sent", "note": "emailed"}, nil)
+	var accepted models.Proposal
+	s.expect(http.StatusOK, http.MethodPost, path+"/status", fiber.Map{"status": "accepted"}, &accepted)
+	if accepted.Status != models.ProposalStatusAccepted || len(accepted.StatusHistory) != 3 {
+		t.Fatalf("accepted proposal = %s with %d changes, want accepted after creation, sent and accepted", accepted.Status, len(accepted.StatusHistory))
+	}
+	if accepted.StatusHistory[1].Note != "emailed" {
+		t.Fatalf("sent note = %q, want emailed", accepted.StatusHistory[1].Note)
+	}
+
+	var list []models.Proposal
+	s.expect(http.StatusOK, http.MethodGet, "/api/proposals?status=accepted", nil, &list)
+	if len(list) != 1 {
+		t.Fatalf("accepted proposals = %d, want 1", len(list))
+	}
+	s.expect(http.StatusOK, http.MethodGet, "/api/proposals?status=draft", nil, &list)
+	if len(list) != 0 {
+		t.Fatalf("draft proposals = %d, want 0", len(list))
+	}
+	s.expect(http.StatusBadRequest, http.MethodGet, "/api/proposals?status=lost", nil, nil)
+
+	var receipt models.Receipt
+	s.expect(http.StatusCreated, http.MethodPost, path+"/convert", nil, &receipt)
+	if receipt.ProposalID == nil || *receipt.ProposalID != proposal.ID || len(receipt.Activities) != 1 {
+		t.Fatalf("converted receipt = %+v, want one stay from proposal %d", receipt, proposal.ID)
+	}
+	if a := receipt.Activities[0]; a.HotelID == nil || *a.HotelID != hotel.ID || a.Amount.Cents() != 45000 {
+		t.Fatalf("converted activity = %+v, want hotel %d for 450", a, hotel.ID)
+	}
+	s.expect(http.StatusConflict, http.MethodPost, path+"/convert", nil, nil)
+
+	s.expect(http.StatusOK, http.MethodDelete, "/api/receipts/"+id(receipt.ID), nil, nil)
+	var got models.Proposal
+	s.expect(http.StatusOK, http.MethodGet, path, nil, &got)
+	if got.ReceiptID != nil {
+		t.Fatalf("proposal receipt = %d, want unlinked after the receipt was deleted", *got.ReceiptID)
+	}
+	s.expect(http.StatusOK, http.MethodPost, "/api/receipts/"+id(receipt.ID)+"/restore", nil, nil)
+	s.expect(http.StatusOK, http.MethodGet, path, nil, &got)
+	if got.ReceiptID == nil || *got.ReceiptID != receipt.ID {
+		t.Fatalf("proposal receipt = %v, want %d after restore", got.ReceiptID, receipt.ID)
+	}
+
+	s.expect(http.StatusOK, http.MethodDelete, path, nil, nil)
+	s.expect(http.StatusNotFound, http.MethodGet, path, nil, nil)
+	var trash struct {
+		Proposals []models.Proposal `json:"proposals"`
+	}
+	s.expect(http.StatusOK, http.MethodGet, "/api/trash", nil, &trash)
+	if len(trash.Proposals) != 1 || trash.Proposals[0].ID != proposal.ID {
+		t.Fatalf("trash = %+v, want the deleted proposal", trash.Proposals)
+	}
+	var restored models.Proposal
+	s.expect(http.StatusOK, http.MethodPost, path+"/restore", nil, &restored)
+	if restored.ID != proposal.ID || restored.Hotel == nil {
+		t.Fatalf("restored proposal = %+v", restored)
+	}
+	s.expect(http.StatusNotFound, http.MethodPost, path+"/restore", nil, nil)
+}
+
+func TestProposalErrors(t *testing.T) {
+	s := newTestServer(t)
+	hotel := s.createHotel("Palazzo", "BARI")
+
+	var body fiber.Map
+	s.expect(http.StatusNotFound, http.MethodPost, "/api/proposals", proposalPayload(999, "Ann"), &body)
+	if body["error"] != "Hotel not found" {
+		t.Fatalf("missing hotel error = %v", body["error"])
+	}
+
+	cases := []struct {
+		name   string
+		change func(fiber.Map)
+		field  string
+	}{
+		{"check-out before check-in", func(p fiber.Map) { p["checkOut"] = "2026-05-30" }, "checkOut"},
+		{"bad check-in", func(p fiber.Map) { p["checkIn"] = "01/06/2026" }, "checkIn"},
+		{"missing dates", func(p fiber.Map) { delete(p, "checkIn"); delete(p, "checkOut") }, "checkIn"},
+		{"no guests", func(p fiber.Map) { p["guests"] = 0 }, "guests"},
+		{"no rooms", func(p fiber.Map) { p["rooms"] = []fiber.Map{} }, "rooms"},
+		{"empty room", func(p fiber.Map) { p["rooms"] = []fiber.Map{{"count": 0}} }, "rooms[0].count"},
+		{"negative price", func(p fiber.Map) { p["price"] = -1 }, "price"},
+		{"no hotel", func(p fiber.Map) { delete(p, "hotelId") }, "hotelId"},
+		{"unknown client", func(p fiber.Map) { p["clientId"] = 999 }, "clientId"},
+	}
+	for _, tc := range cases {
+		payload := proposalPayload(hotel.ID, "Ann")
+		tc.change(payload)
+		var body fiber.Map
+		s.expect(http.StatusBadRequest, http.MethodPost, "/api/proposals", payload, &body)
+		if !hasField(body, tc.field) {
+			t.Errorf("%s: field errors %v, want %s", tc.name, errorFields(body), tc.field)
+		}
+	}
+
+	var list []models.Proposal
+	s.expect(http.StatusOK, http.MethodGet, "/api/proposals", nil, &list)
+	if len(list) != 0 {
+		t.Fatalf("proposals = %d, want none after rejected requests", len(list))
+	}
+
+	sameDay := proposalPayload(hotel.ID, "Ann")
+	sameDay["checkOut"] = sameDay["checkIn"]
+	s.expect(http.StatusCreated, http.MethodPost, "/api/proposals", sameDay, nil)
+
+	for _, req := range []struct{ method, path string }{
+		{http.MethodGet, "/api/proposals/999"},
+		{http.MethodGet, "/api/proposals/999/pdf"},
+		{http.MethodPost, "/api/proposals/999/convert"},
+		{http.MethodDelete, "/api/proposals/999"},
+		{http.MethodPost, "/api/proposals/999/restore"},
+	} {
+		s.expect(http.StatusNotFound, req.method, req.path, nil, nil)
+	}
+	s.expect(http.StatusNotFound, http.MethodPut, "/api/proposals/999", proposalPayload(hotel.ID, "Ann"), nil)
+	s.expect(http.StatusNotFound, http.MethodPost, "/api/proposals/999/status", fiber.Map{"status": "sent"}, nil)
+
+	var proposal models.Proposal
+	s.expect(http.StatusCreated, http.MethodPost, "/api/proposals", proposalPayload(hotel.ID, "Ann"), &proposal)
+	path := "/api/proposals/" + id(proposal.ID)
+	s.expect(http.StatusNotFound, http.MethodPut, path, proposalPayload(999, "Ann"), nil)
+	bad := proposalPayload(hotel.ID, "Ann")
+	bad["checkOut"] = "2026-05-01"
+	s.expect(http.StatusBadRequest, http.MethodPut, path, bad, nil)
+	s.expect(http.StatusBadRequest, http.MethodPost, path+"/status", fiber.Map{}, nil)
+}
+
+func TestProposalPDFSkipsImagesItCannotEmbed(t *testing.T) {
+	s := newTestServer(t)
+	hotel := s.createHotel("Palazzo", "BARI")
+	var proposal models.Proposal
+	s.expect(http.StatusCreated, http.MethodPost, "/api/proposals", proposalPayload(hotel.ID, "Ann"), &proposal)
+
+	var photo bytes.Buffer
+	if err := png.Encode(&photo, image.NewGray(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	images := "/api/hotels/" + id(hotel.ID) + "/images"
+	// The webp image comes first in display order but cannot be embedded.
+	if status := s.upload(images, "file", map[string][]byte{"lobby.webp": []byte("RIFFwebp")}, nil); status != http.StatusOK {
+		t.Fatalf("upload webp: status %d", status)
+	}
+	if status := s.upload(images, "file", map[string][]byte{"front.png": photo.Bytes()}, nil); status != http.StatusOK {
+		t.Fatalf("upload png: status %d", status)
+	}
+
+	embedded := func(query string) int {
+		status, _, pdf := s.raw(http.MethodGet, "/api/proposals/"+id(proposal.ID)+"/pdf"+query, nil)
+		if status != http.StatusOK {
+			t.Fatalf("pdf%s: status %d", query, status)
+		}
+		return bytes.Count(pdf, []byte("/Subtype /Image"))
+	}
+	if without, with := embedded("?images=0"), embedded("?images=1"); with != without+1 {
+		t.Fatalf("pdf embeds %d images with ?images=1 and %d without, want the png added", with, without)
+	}
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Otabek228101/mehmon/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+type receiptPage struct {
+	Data       []models.Receipt `json:"data"`
+	Total      int64            `json:"total"`
+	NextCursor string           `json:"nextCursor"`
+}
+
+func TestReceiptLifecycle(t *testing.T) {
+	s := newTestServer(t)
+	hotel := s.createHotel("Palazzo", "BARI")
+
+	receipt := s.createReceipt(receiptPayload("Ann", "2026-05-01", hotelStay("palazzo", "2026-05-10", "2026-05-12", 150)))
+	if receipt.ReceiptNumber != "M00001" {
+		t.Fatalf("receipt number = %q, want M00001", receipt.ReceiptNumber)
+	}
+	if len(receipt.Activities) != 1 || receipt.Activities[0].HotelID == nil || *receipt.Activities[0].HotelID != hotel.ID {
+		t.Fatalf("activities = %+v, want one stay linked to hotel %d", receipt.Activities, hotel.ID)
+	}
+	if receipt.Activities[0].PropertyName != "Palazzo" {
+		t.Fatalf("property name = %q, want the catalog name", receipt.Activities[0].PropertyName)
+	}
+	path := "/api/receipts/" + id(receipt.ID)
+
+	var got struct {
+		models.Receipt
+		Total   models.Money `json:"total"`
+		Balance models.Money `json:"balance"`
+	}
+	s.expect(http.StatusOK, http.MethodGet, path, nil, &got)
+	if got.Total.Cents() != 15000 || got.Balance.Cents() != 15000 {
+		t.Fatalf("total %v balance %v, want 150 and 150", got.Total, got.Balance)
+	}
+
+	update := receiptPayload("Ann Lee", "2026-05-02", hotelStay("Other Inn", "2026-05-10", "2026-05-13", 200))
+	update["clientEmail"] = "ann.lee@example.com"
+	update["amountPaid"] = 50
+	var updated models.Receipt
+	s.expect(http.StatusOK, http.MethodPut, path, update, &updated)
+	if updated.ClientName != "Ann Lee" || updated.AmountPaid.Cents() != 5000 {
+		t.Fatalf("updated client %q paid %v, want Ann Lee and 50", updated.ClientName, updated.AmountPaid)
+	}
+	if len(updated.Activities) != 1 || updated.Activities[0].HotelID != nil {
+		t.Fatalf("activities = %+v, want one unlinked stay", updated.Activities)
+	}
+
+	status, contentType, pdf := s.raw(http.MethodGet, path+"/pdf", nil)
+	if status != http.StatusOK || contentType != "application/pdf" || !strings.HasPrefix(string(pdf), "%PDF") {
+		t.Fatalf("pdf: status %d, content type %q", status, contentType)
+	}
+
+	var share struct {
+		URL    string `json:"url"`
+		PDFURL string `json:"pdfUrl"`
+	}
+	s.expect(http.StatusOK, http.MethodGet, path+"/share", nil, &share)
+	public := s.anonymous()
+	link, err := url.Parse(share.URL)
+	if err != nil {
+		t.Fatalf("share url %q: %v", share.URL, err)
+	}
+	var shared models.Receipt
+	public.expect(http.StatusOK, http.MethodGet, link.RequestURI(), nil, &shared)
+	if shared.ID != receipt.ID {
+		t.Fatalf("shared receipt = %d, want %d", shared.ID, receipt.ID)
+	}
+	pdfLink, _ := url.Parse(share.PDFURL)
+	if status, _, _ := public.raw(http.MethodGet, pdfLink.RequestURI(), nil); status != http.StatusOK {
+		t.Fatalf("public pdf: status %d", status)
+	}
+	public.expect(http.StatusNotFound, http.MethodGet, "/api/public/receipts/"+id(receipt.ID)+"?sig=forged", nil, nil)
+	public.expect(http.StatusUnauthorized, http.MethodGet, path, nil, nil)
+
+	s.expect(http.StatusOK, http.MethodDelete, path, nil, nil)
+	s.expect(http.StatusNotFound, http.MethodGet, path, nil, nil)
+	s.expect(http.StatusNotFound, http.MethodDelete, path, nil, nil)
+	var trash struct {
+		Receipts []models.Receipt `json:"receipts"`
+	}
+	s.expect(http.StatusOK, http.MethodGet, "/api/trash", nil, &trash)
+	if len(trash.Receipts) != 1 || trash.Receipts[0].ID != receipt.ID {
+		t.Fatalf("trash = %+v, want the deleted receipt", trash.Receipts)
+	}
+
+	var restored models.Receipt
+	s.expect(http.StatusOK, http.MethodPost, path+"/restore", nil, &restored)
+	if restored.ID != receipt.ID {
+		t.Fatalf("restored receipt = %d, want %d", restored.ID, receipt.ID)
+	}
+	s.expect(http.StatusNotFound, http.MethodPost, path+"/restore", nil, nil)
+	s.expect(http.StatusOK, http.MethodGet, path, nil, nil)
+}
+
+func TestReceiptValidation(t *testing.T) {
+	s := newTestServer(t)
+
+	var body fiber.Map
+	s.expect(http.StatusBadRequest, http.MethodPost, "/api/receipts", fiber.Map{}, &body)
+	for _, field := range []string{"clientName", "receiptDate"} {
+		if !hasField(body, field) {
+			t.Fatalf("missing field error for %s in %v", field, errorFields(body))
+		}
+	}
+	for _, field := range []string{"clientEmail", "clientPhone"} {
+		if hasField(body, field) {
+			t.Fatalf("optional %s reported as an error in %v", field, errorFields(body))
+		}
+	}
+
+	cases := []struct {
+		name    string
+		payload fiber.Map
+		field   string
+	}{
+		{"bad receipt date", receiptPayload("Ann", "2026-13-45"), "receiptDate"},
+		{"check-out before check-in", receiptPayload("Ann", "2026-05-01", hotelStay("A", "2026-05-12", "2026-05-10", 100)), "activities[0].checkOut"},
+		{"bad check-in", receiptPayload("Ann", "2026-05-01", hotelStay("A", "next week", "2026-05-10", 100)), "activities[0].checkIn"},
+		{"missing hotel", receiptPayload("Ann", "2026-05-01", fiber.Map{"type": "hotel", "hotelId": 999, "checkIn": "2026-05-10", "checkOut": "2026-05-12", "amount": 100}), "activities[0].hotelId"},
+		{"missing car rental", receiptPayload("Ann", "2026-05-01", carRental(999)), "activities[0].carRentalId"},
+		{"negative amount", receiptPayload("Ann", "2026-05-01", hotelStay("A", "2026-05-10", "2026-05-12", -5)), "activities[0].amount"},
+		{"unknown type", receiptPayload("Ann", "2026-05-01", fiber.Map{"type": "spaceflight"}), "activities[0].type"},
+		{"unknown currency", func() fiber.Map { p := receiptPayload("Ann", "2026-05-01"); p["currency"] = "XYZ"; return p }(), "currency"},
+		{"bad email", func() fiber.Map { p := receiptPayload("Ann", "2026-05-01"); p["clientEmail"] = "nope"; return p }(), "clientEmail"},
+		{"bad phone", func() fiber.Map { p := receiptPayload("Ann", "2026-05-01"); p["clientPhone"] = "12"; return p }(), "clientPhone"},
+		{"other without description", receiptPayload("Ann", "2026-05-01", fiber.Map{"type": "other", "amount": 10}), "activities[0].description"},
+	}
+	for _, tc := range cases {
+		var body fiber.Map
+		s.expect(http.StatusBadRequest, http.MethodPost, "/api/receipts", tc.payload, &body)
+		if !hasField(body, tc.field) {
+			t.Errorf("%s: field errors %v, want %s", tc.name, errorFields(body), tc.field)
+		}
+	}
+
+	s.expect(http.StatusNotFound, http.MethodGet, "/api/receipts/999", nil, nil)
+	s.expect(http.StatusNotFound, http.MethodGet, "/api/receipts/abc", nil, nil)
+	s.expect(http.StatusNotFound, http.MethodPut, "/api/receipts/999", receiptPayload("Ann", "2026-05-01"), nil)
+	s.expect(http.StatusNotFound, http.MethodGet, "/api/receipts/999/pdf", nil, nil)
+	s.expect(http.StatusNotFound, http.MethodGet, "/api/receipts/999/share", nil, nil)
+	s.expect(http.StatusNotFound, http.MethodPost, "/api/receipts/999/restore", nil, nil)
+
+	var page receiptPage
+	s.expect(http.StatusOK, http.MethodGet, "/api/receipts", nil, &page)
+	if page.Total != 0 {
+		t.Fatalf("receipts = %d, want none after rejected requests", page.Total)
+	}
+
+	walkIn := receiptPayload("Ann", "2026-05-01", fiber.Map{"type": "other", "description": "Museum tickets", "amount": 10})
+	delete(walkIn, "clientEmail")
+	delete(walkIn, "clientPhone")
+	if receipt := s.createReceipt(walkIn); receipt.ClientEmail != "" || len(receipt.Activities) != 1 {
+		t.Fatalf("receipt without contact details = %+v", receipt)
+	}
+}
+
+func TestReceiptListAndSearch(t *testing.T) {
+	s := newTestServer(t)
+	s.createReceipt(receiptPayload("Ann", "2026-03-01", hotelStay("A", "2026-03-10", "2026-03-12", 100)))
+	bob := receiptPayload("Bob", "2026-04-15", fiber.Map{"type": "transfer", "transferType": "airport_pickup", "pickupLocation": "Airport", "dropoffLocation": "Hotel", "amount": 40})
+	bob["amountPaid"] = 40
+	s.createReceipt(bob)
+	s.createReceipt(receiptPayload("Carla", "2026-05-20", hotelStay("B", "2026-05-21", "2026-05-22", 80)))
+
+	list := func(query string) receiptPage {
+		t.Helper()
+		var page receiptPage
+		s.expect(http.StatusOK, http.MethodGet, "/api/receipts"+query, nil, &page)
+		return page
+	}
+	if page := list(""); page.Total != 3 || len(page.Data) != 3 {
+		t.Fatalf("all receipts: total %d, got %d", page.Total, len(page.Data))
+	}
+	if page := list("?from=2026-04-01&to=2026-04-30"); page.Total != 1 || page.Data[0].ClientName != "Bob" {
+		t.Fatalf("April receipts = %+v, want Bob", page.Data)
+	}
+	if page := list("?type=hotel"); page.Total != 2 {
+		t.Fatalf("hotel receipts = %d, want 2", page.Total)
+	}
+	if page := list("?minAmount=10"); page.Total != 1 {
+		t.Fatalf("paid receipts = %d, want 1", page.Total)
+	}
+
+	first := list("?limit=2&sort=number&order=asc")
+	if len(first.Data) != 2 || first.NextCursor == "" {
+		t.Fatalf("first page: %d receipts, cursor %q", len(first.Data), first.NextCursor)
+	}
+	second := list("?limit=2&sort=number&order=asc&cursor=" + url.QueryEscape(first.NextCursor))
+	if len(second.Data) != 1 || second.Data[0].ClientName != "Carla" {
+		t.Fatalf("second page = %+v, want Carla", second.Data)
+	}
+
+	for _, query := range []string{"?from=yesterday", "?to=2026-02-30", "?minAmount=lots", "?sort=colour", "?limit=-1"} {
+		s.expect(http.StatusBadRequest, http.MethodGet, "/api/receipts"+query, nil, nil)
+	}
+
+	var found receiptPage
+	s.expect(http.StatusOK, http.MethodGet, "/api/receipts/search?q=CARLA", nil, &found)
+	if found.Total != 1 || found.Data[0].ClientName != "Carla" {
+		t.Fatalf("search CARLA = %+v, want Carla", found.Data)
+	}
+	s.expect(http.StatusOK, http.MethodGet, "/api/receipts/search?q=example.com&type=hotel", nil, &found)
+	if found.Total != 2 {
+		t.Fatalf("search with type filter = %d, want 2", found.Total)
+	}
+	for _, query := range []string{"%25", "_"} {
+		s.expect(http.StatusOK, http.MethodGet, "/api/receipts/search?q="+query, nil, &found)
+		if found.Total != 0 {
+			t.Fatalf("search %s = %d receipts, want the wildcard matched literally", query, found.Total)
+		}
+	}
+	s.expect(http.StatusBadRequest, http.MethodGet, "/api/receipts/search", nil, nil)
+}
+
+func TestReceiptPayments(t *testing.T) {
+	s := newTestServer(t)
+	receipt := s.createReceipt(receiptPayload("Ann", "2026-05-01", hotelStay("A", "2026-05-10", "2026-05-12", 150)))
+	path := "/api/receipts/" + id(receipt.ID) + "/payments"
+
+	var payment models.Payment
+	s.expect(http.StatusCreated, http.MethodPost, path, fiber.Map{"amount": 100, "method": "Card", "date": "2026-05-02"}, &payment)
+	if payment.Method != models.PaymentCard {
+		t.Fatalf("method = %q, want card", payment.Method)
+	}
+	s.expect(http.StatusCreated, http.MethodPost, path, fiber.Map{"amount": 20}, nil)
+
+	var payments []models.Payment
+	s.expect(http.StatusOK, http.MethodGet, path, nil, &payments)
+	var balance struct {
+		Paid    models.Money `json:"paid"`
+		Balance models.Money `json:"balance"`
+	}
+	s.expect(http.StatusOK, http.MethodGet, "/api/receipts/"+id(receipt.ID), nil, &balance)
+	if len(payments) != 2 || balance.Paid.Cents() != 12000 || balance.Balance.Cents() != 3000 {
+		t.Fatalf("payments %d paid %v balance %v, want 2, 120 and 30", len(payments), balance.Paid, balance.Balance)
+	}
+
+	s.expect(http.StatusOK, http.MethodDelete, path+"/"+id(payment.ID), nil, nil)
+	var got models.Receipt
+	s.expect(http.StatusOK, http.MethodGet, "/api/receipts/"+id(receipt.ID), nil, &got)
+	if got.AmountPaid.Cents() != 2000 {
+		t.Fatalf("amount paid = %v, want 20 after deleting a payment", got.AmountPaid)
+	}
+
+	var body fiber.Map
+	s.expect(http.StatusBadRequest, http.MethodPost, path, fiber.Map{"amount": 0, "method": "barter", "date": "soon"}, &body)
+	for _, field := range []string{"amount", "method", "date"} {
+		if !hasField(body, field) {
+			t.Fatalf("field errors %v, want %s", errorFields(body), field)
+		}
+	}
+	s.expect(http.StatusNotFound, http.MethodDelete, path+"/"+id(payment.ID), nil, nil)
+	s.expect(http.StatusNotFound, http.MethodPost, "/api/receipts/999/payments", fiber.Map{"amount": 10}, nil)
+	s.expect(http.StatusNotFound, http.MethodGet, "/api/receipts/999/payments", nil, nil)
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Otabek228101/mehmon/config"
+	"github.com/Otabek228101/mehmon/models"
+	"github.com/Otabek228101/mehmon/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestClientRoutes(t *testing.T) {
+	s := newTestServer(t)
+
+	var client models.Client
+	s.expect(http.StatusCreated, http.MethodPost, "/api/clients", fiber.Map{
+		"name": " Ann Lee ", "email": "Ann@Example.com", "phone": "+998 90 123 45 67",
+	}, &client)
+	if client.Name != "Ann Lee" || client.Email != "ann@example.com" {
+		t.Fatalf("created client = %+v, want trimmed and normalized contact details", client)
+	}
+	var conflict struct {
+		Client models.Client `json:"client"`
+	}
+	s.expect(http.StatusConflict, http.MethodPost, "/api/clients", fiber.Map{
+		"name": "Ann", "email": "ann@example.com", "phone": "+998907654321",
+	}, &conflict)
+	if conflict.Client.ID != client.ID {
+		t.Fatalf("conflicting client = %d, want %d", conflict.Client.ID, client.ID)
+	}
+	var body fiber.Map
+	s.expect(http.StatusBadRequest, http.MethodPost, "/api/clients", fiber.Map{"email": "bad"}, &body)
+	for _, field := range []string{"name", "email"} {
+		if !hasField(body, field) {
+			t.Fatalf("field errors %v, want %s", errorFields(body), field)
+		}
+	}
+
+	var other models.Client
+	s.expect(http.StatusCreated, http.MethodPost, "/api/clients", fiber.Map{"name": "Bob", "email": "bob@example.com"}, &other)
+	var page struct {
+		Data  []models.Client `json:"data"`
+		Total int64           `json:"total"`
+	}
+	s.expect(http.StatusOK, http.MethodGet, "/api/clients", nil, &page)
+	if page.Total != 2 || page.Data[0].Name != "Ann Lee" {
+		t.Fatalf("clients = %+v, want Ann Lee and Bob by name", page.Data)
+	}
+	s.expect(http.StatusOK, http.MethodGet, "/api/clients?q=bob", nil, &page)
+	if page.Total != 1 || page.Data[0].ID != other.ID {
+		t.Fatalf("clients matching bob = %+v", page.Data)
+	}
+
+	path := "/api/clients/" + id(client.ID)
+	var updated models.Client
+	s.expect(http.StatusOK, http.MethodPut, path, fiber.Map{"name": "Ann Lee-Smith", "email": "ann@example.com"}, &updated)
+	if updated.Name != "Ann Lee-Smith" {
+		t.Fatalf("updated client = %+v", updated)
+	}
+	s.expect(http.StatusConflict, http.MethodPut, path, fiber.Map{"name": "Ann", "email": "bob@example.com"}, nil)
+
+	receipt := receiptPayload("Ann", "2026-05-01", hotelStay("Palazzo", "2026-05-10", "2026-05-12", 100))
+	receipt["clientId"] = client.ID
+	s.createReceipt(receipt)
+	var history struct {
+		Client   models.Client    `json:"client"`
+		Receipts []models.Receipt `json:"receipts"`
+		Currency string           `json:"currency"`
+	}
+	s.expect(http.StatusOK, http.MethodGet, path+"/history", nil, &history)
+	if history.Client.ID != client.ID || len(history.Receipts) != 1 || history.Currency == "" {
+		t.Fatalf("history = %+v, want one receipt", history)
+	}
+	s.expect(http.StatusBadRequest, http.MethodGet, path+"/history?currency=XYZ", nil, nil)
+
+	s.expect(http.StatusOK, http.MethodDelete, path, nil, nil)
+	for _, req := range []struct{ method, path string }{
+		{http.MethodGet, path},
+		{http.MethodGet, path + "/history"},
+		{http.MethodDelete, path},
+	} {
+		s.expect(http.StatusNotFound, req.method, req.path, nil, nil)
+	}
+	s.expect(http.StatusNotFound, http.MethodPut, path, fiber.Map{"name": "Ghost"}, nil)
+}
+
+func TestReportRoutes(t *testing.T) {
+	s := newTestServer(t)
+	hotel := s.createHotel("Palazzo", "BARI")
+	paid := receiptPayload("Ann", "2026-05-01", fiber.Map{
+		"type": "hotel", "hotelId": hotel.ID, "checkIn": "2026-05-10", "checkOut": "2026-05-12", "amount": 200,
+	})
+	paid["amountPaid"] = 200
+	s.createReceipt(paid)
+	s.createReceipt(receiptPayload("Bob", "2026-06-01", hotelStay("Duomo", "2026-06-10", "2026-06-11", 80)))
+
+	var totals struct {
+		Receipts int64 `json:"receipts"`
+	}
+	s.expect(http.StatusOK, http.MethodGet, "/api/reports/totals", nil, &totals)
+	if totals.Receipts != 2 {
+		t.Fatalf("totals cover %d receipts, want 2", totals.Receipts)
+	}
+	s.expect(http.StatusOK, http.MethodGet, "/api/reports/totals?from=2026-06-01", nil, &totals)
+	if totals.Receipts != 1 {
+		t.Fatalf("totals from June cover %d receipts, want 1", totals.Receipts)
+	}
+	var outstanding struct {
+		Receipts []struct {
+			ClientName string `json:"clientName"`
+		} `json:"receipts"`
+	}
+	s.expect(http.StatusOK, http.MethodGet, "/api/reports/outstanding", nil, &outstanding)
+	if len(outstanding.Receipts) != 1 || outstanding.Receipts[0].ClientName != "Bob" {
+		t.Fatalf("outstanding = %+v, want Bob's unpaid receipt", outstanding.Receipts)
+	}
+
+	for _, dimension := range []string{"monthly", "hotels", "cities", "activity-types", "agents"} {
+		path := "/api/reports/" + dimension
+		var report struct {
+			GroupBy string               `json:"groupBy"`
+			Rows    []services.ReportRow `json:"rows"`
+		}
+		s.expect(http.StatusOK, http.MethodGet, path, nil, &report)
+		if report.GroupBy == "" || len(report.Rows) == 0 {
+			t.Errorf("%s: report = %+v, want grouped rows", dimension, report)
+		}
+		status, contentType, data := s.raw(http.MethodGet, path+"?format=csv", nil)
+		if status != http.StatusOK || !strings.HasPrefix(contentType, "text/csv") || len(strings.Split(strings.TrimSpace(string(data)), "\n")) < 2 {
+			t.Errorf("%s csv: status %d, content type %q: %s", dimension, status, contentType, data)
+		}
+		s.expect(http.StatusBadRequest, http.MethodGet, path+"?format=xml", nil, nil)
+		s.expect(http.StatusBadRequest, http.MethodGet, path+"?currency=XYZ", nil, nil)
+		s.expect(http.StatusBadRequest, http.MethodGet, path+"?from=yesterday", nil, nil)
+	}
+
+	var monthly struct {
+		Rows []services.ReportRow `json:"rows"`
+	}
+	s.expect(http.StatusOK, http.MethodGet, "/api/reports/monthly?to=2026-05-31", nil, &monthly)
+	if len(monthly.Rows) != 1 || monthly.Rows[0].Paid.Cents() != 20000 {
+		t.Fatalf("May = %+v, want Ann's paid receipt only", monthly.Rows)
+	}
+}
+
+func TestSearchRoutes(t *testing.T) {
+	s := newTestServer(t)
+	hotel := s.createHotel("Palazzo", "BARI")
+	stay := hotelStay("Grand Canal", "2026-05-10", "2026-05-12", 100)
+	stay["provider"] = "Booking.com"
+	stay["reference"] = "XK-42"
+	receipt := s.createReceipt(receiptPayload("Ann", "2026-05-01", stay))
+	s.expect(http.StatusCreated, http.MethodPost, "/api/proposals", proposalPayload(hotel.ID, "Annabel"), nil)
+
+	var result struct {
+		Query string             `json:"query"`
+		Hits  []models.SearchHit `json:"hits"`
+	}
+	s.expect(http.StatusOK, http.MethodGet, "/api/search?q=ann", nil, &result)
+	if result.Query != "ann" || len(result.Hits) < 2 {
+		t.Fatalf("search ann = %+v, want the receipt and the proposal", result.Hits)
+	}
+	s.expect(http.StatusOK, http.MethodGet, "/api/search?q=canal&types=activity", nil, &result)
+	if len(result.Hits) != 1 || result.Hits[0].ReceiptID == nil || *result.Hits[0].ReceiptID != receipt.ID {
+		t.Fatalf("search canal = %+v, want the stay on Ann's receipt", result.Hits)
+	}
+	s.expect(http.StatusOK, http.MethodGet, "/api/search?q=booking+xk-42&types=activity", nil, &result)
+	if len(result.Hits) != 1 || result.Hits[0].ID != receipt.Activities[0].ID {
+		t.Fatalf("search provider and reference = %+v, want the stay", result.Hits)
+	}
+	// "_" is matched literally, not as a LIKE wildcard that would match the "xk" above.
+	s.expect(http.StatusOK, http.MethodGet, "/api/search?q=x_&types=activity", nil, &result)
+	if len(result.Hits) != 0 {
+		t.Fatalf("search x_ = %+v, want no hits", result.Hits)
+	}
+	s.expect(http.StatusOK, http.MethodGet, "/api/search?q=nothing-matches", nil, &result)
+	if len(result.Hits) != 0 {
+		t.Fatalf("search = %+v, want no hits", result.Hits)
+	}
+	s.expect(http.StatusBadRequest, http.MethodGet, "/api/search", nil, nil)
+	s.expect(http.StatusBadRequest, http.MethodGet, "/api/search?q=ann&types=hotel", nil, nil)
+}
+
+func TestRateRoutes(t *testing.T) {
+	s := newTestServer(t)
+
+	var rates struct {
+		Base  string             `json:"base"`
+		Rates map[string]float64 `json:"rates"`
+	}
+	s.expect(http.StatusOK, http.MethodGet, "/api/rates", nil, &rates)
+	if rates.Base == "" {
+		t.Fatalf("rates = %+v, want a base currency", rates)
+	}
+	s.expect(http.StatusBadRequest, http.MethodPost, "/api/rates/reload", nil, nil)
+	s.as(models.RoleAgent).expect(http.StatusForbidden, http.MethodPost, "/api/rates/reload", nil, nil)
+
+	file := filepath.Join(t.TempDir(), "rates.json")
+	if err := os.WriteFile(file, []byte(`{"base":"`+rates.Base+`","rates":{"EUR":0.9}}`), 0o600); err != nil {
+		t.Fatalf("write rates file: %v", err)
+	}
+	config.Current.ExchangeRatesFile = file
+	var reloaded struct {
+		Loaded int                `json:"loaded"`
+		Rates  map[string]float64 `json:"rates"`
+	}
+	s.expect(http.StatusOK, http.MethodPost, "/api/rates/reload", nil, &reloaded)
+	if reloaded.Loaded == 0 || reloaded.Rates["EUR"] != 0.9 {
+		t.Fatalf("reloaded = %+v, want EUR at 0.9", reloaded)
+	}
+
+	if err := os.WriteFile(file, []byte(`{"rates":{"EUR":-1}}`), 0o600); err != nil {
+		t.Fatalf("write rates file: %v", err)
+	}
+	s.expect(http.StatusInternalServerError, http.MethodPost, "/api/rates/reload", nil, nil)
+}
@@ -0,0 +1,619 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Otabek228101/mehmon/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type gormStore struct {
+	db *gorm.DB
+}
+
+// NewGormStore returns a Store backed by db.
+func NewGormStore(db *gorm.DB) Store {
+	return &gormStore{db: db}
+}
+
+func (s *gormStore) Hotels() HotelRepository               { return gormHotels{s.db} }
+func (s *gormStore) CarRentals() CarRentalRepository       { return gormCarRentals{s.db} }
+func (s *gormStore) Receipts() ReceiptRepository           { return gormReceipts{s.db} }
+func (s *gormStore) Proposals() ProposalRepository         { return gormProposals{s.db} }
+func (s *gormStore) Clients() ClientRepository             { return gormClients{s.db} }
+func (s *gormStore) Users() UserRepository                 { return gormUsers{s.db} }
+func (s *gormStore) Numbers() NumberRepository             { return gormNumbers{s.db} }
+func (s *gormStore) ExchangeRates() ExchangeRateRepository { return gormExchangeRates{s.db} }
+func (s *gormStore) Audit() AuditRepository                { return gormAudit{s.db} }
+func (s *gormStore) Search() SearchRepository              { return gormSearch{s.db} }
+
+func (s *gormStore) Transaction(fn func(Store) error) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&gormStore{db: tx})
+	})
+}
+
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// deleted reports ErrNotFound when a delete or restore touched no row.
+func deleted(res *gorm.DB) error {
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// paged orders and limits query by page; with a cursor it seeks past the cursor row
+// instead of skipping rows.
+func paged(query *gorm.DB, table string, page Page) *gorm.DB {
+	column := table + "." + page.Sort
+	id := table + ".id"
+	direction, cmp := "asc", ">"
+	if page.Desc {
+		direction, cmp = "desc", "<"
+	}
+
+	if page.After != nil {
+		query = query.Where(
+			fmt.Sprintf("%s %s ? OR (%s = ? AND %s %s ?)", column, cmp, column, id, cmp),
+			page.After.Value, page.After.Value, page.After.ID,
+		)
+	} else {
+		query = query.Offset(page.Offset)
+	}
+	return query.Order(column + " " + direction).Order(id + " " + direction).Limit(page.Limit)
+}
+
+// likeLiteral escapes the LIKE wildcards in s so that it only matches itself. Patterns built
+// from it must be compared with LIKE ? ESCAPE '\'.
+func likeLiteral(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+type gormHotels struct{ db *gorm.DB }
+
+func (r gormHotels) List(city string) ([]models.Hotel, error) {
+	var hotels []models.Hotel
+	query := r.db
+	if city != "" {
+		query = query.Where(`LOWER(city) LIKE ? ESCAPE '\'`, "%"+likeLiteral(strings.ToLower(city))+"%")
+	}
+	err := query.Preload("Images").Find(&hotels).Error
+	return hotels, err
+}
+
+func (r gormHotels) Get(id uint) (models.Hotel, error) {
+	var hotel models.Hotel
+	err := r.db.Preload("Images").First(&hotel, id).Error
+	return hotel, notFound(err)
+}
+
+func (r gormHotels) FindByName(name string) ([]models.Hotel, error) {
+	var hotels []models.Hotel
+	err := r.db.Where("LOWER(name) = LOWER(?)", name).Order("id").Find(&hotels).Error
+	return hotels, err
+}
+
+func (r gormHotels) Create(hotel *models.Hotel) error {
+	return r.db.Omit(clause.Associations).Create(hotel).Error
+}
+
+func (r gormHotels) Update(hotel *models.Hotel) error {
+	return r.db.Omit(clause.Associations).Save(hotel).Error
+}
+
+func (r gormHotels) Delete(id uint) error {
+	return deleted(r.db.Delete(&models.Hotel{}, id))
+}
+
+func (r gormHotels) Images(hotelID uint, mimes []string, limit int) ([]models.HotelImage, error) {
+	var images []models.HotelImage
+	query := r.db.Where("hotel_id = ?", hotelID).Order("sort_order asc, id asc")
+	if len(mimes) > 0 {
+		query = query.Where("mime IN ?", mimes)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	err := query.Find(&images).Error
+	return images, err
+}
+
+func (r gormHotels) LastImageSort(hotelID uint) (int, error) {
+	var last int
+	err := r.db.Model(&models.HotelImage{}).Where("hotel_id = ?", hotelID).Select("COALESCE(MAX(sort_order), 0)").Scan(&last).Error
+	return last, err
+}
+
+func (r gormHotels) AddImage(image *models.HotelImage) error {
+	return r.db.Create(image).Error
+}
+
+type gormCarRentals struct{ db *gorm.DB }
+
+func (r gormCarRentals) List() ([]models.CarRental, error) {
+	var rentals []models.CarRental
+	err := r.db.Find(&rentals).Error
+	return rentals, err
+}
+
+func (r gormCarRentals) Get(id uint) (models.CarRental, error) {
+	var rental models.CarRental
+	err := r.db.First(&rental, id).Error
+	return rental, notFound(err)
+}
+
+func (r gormCarRentals) Create(rental *models.CarRental) error {
+	return r.db.Create(rental).Error
+}
+
+func (r gormCarRentals) Delete(id uint) error {
+	return deleted(r.db.Delete(&models.CarRental{}, id))
+}
+
+type gormReceipts struct{ db *gorm.DB }
+
+func (r gormReceipts) filtered(filter ReceiptFilter) *gorm.DB {
+	query := r.db.Model(&models.Receipt{})
+	if filter.From != nil {
+		query = query.Where("receipts.receipt_date >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("receipts.receipt_date <= ?", *filter.To)
+	}
+	if filter.Before != nil {
+		query = query.Where("receipts.receipt_date < ?", *filter.Before)
+	}
+	if filter.MinAmount != nil {
+		query = query.Where("receipts.amount_paid >= ?", *filter.MinAmount)
+	}
+	if filter.MaxAmount != nil {
+		query = query.Where("receipts.amount_paid <= ?", *filter.MaxAmount)
+	}
+	if filter.ActivityType != "" {
+		query = query.Where("EXISTS (SELECT 1 FROM activities WHERE activities.receipt_id = receipts.id AND activities.type = ?)", filter.ActivityType)
+	}
+	if filter.HotelID != 0 {
+		query = query.Where("EXISTS (SELECT 1 FROM activities WHERE activities.receipt_id = receipts.id AND activities.hotel_id = ?)", filter.HotelID)
+	}
+	if filter.Query != "" {
+		pattern := "%" + likeLiteral(strings.ToLower(filter.Query)) + "%"
+		query = query.Where(
+			`LOWER(receipts.receipt_number) LIKE ? ESCAPE '\' OR LOWER(receipts.client_name) LIKE ? ESCAPE '\' OR `+
+				`LOWER(receipts.client_email) LIKE ? ESCAPE '\' OR LOWER(receipts.client_phone) LIKE ? ESCAPE '\'`,
+			pattern, pattern, pattern, pattern,
+		)
+	}
+	return query
+}
+
+func (r gormReceipts) List(filter ReceiptFilter, page Page) ([]models.Receipt, int64, error) {
+	query := r.filtered(filter)
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	receipts := []models.Receipt{}
+	err := paged(query, "receipts", page).Preload("Activities").Find(&receipts).Error
+	return receipts, total, err
+}
+
+func (r gormReceipts) Find(filter ReceiptFilter) ([]models.Receipt, error) {
+	var receipts []models.Receipt
+	err := r.filtered(filter).Preload("Activities").Order("receipts.receipt_date, receipts.id").Find(&receipts).Error
+	return receipts, err
+}
+
+func (r gormReceipts) ListDeleted() ([]models.Receipt, error) {
+	receipts := []models.Receipt{}
+	err := r.db.Unscoped().Where("deleted_at IS NOT NULL").Order("deleted_at desc").
+		Preload("Activities").Find(&receipts).Error
+	return receipts, err
+}
+
+func (r gormReceipts) ForClient(clientID uint) ([]models.Receipt, error) {
+	receipts := []models.Receipt{}
+	err := r.db.Where("client_id = ?", clientID).Preload("Activities").
+		Order("receipt_date desc, id desc").Find(&receipts).Error
+	return receipts, err
+}
+
+func (r gormReceipts) Get(id uint) (models.Receipt, error) {
+	var receipt models.Receipt
+	err := r.db.Preload("Activities").Preload("Payments", func(db *gorm.DB) *gorm.DB {
+		return db.Order("paid_at, id")
+	}).First(&receipt, id).Error
+	return receipt, notFound(err)
+}
+
+func (r gormReceipts) GetDeleted(id uint) (models.Receipt, error) {
+	var receipt models.Receipt
+	err := r.db.Unscoped().Where("deleted_at IS NOT NULL").Preload("Activities").First(&receipt, id).Error
+	return receipt, notFound(err)
+}
+
+func (r gormReceipts) Create(receipt *models.Receipt) error {
+	if err := r.db.Omit(clause.Associations).Create(receipt).Error; err != nil {
+		return err
+	}
+	return r.createActivities(receipt)
+}
+
+func (r gormReceipts) Update(receipt *models.Receipt) error {
+	if err := r.db.Omit(clause.Associations).Save(receipt).Error; err != nil {
+		return err
+	}
+	if err := r.db.Where("receipt_id = ?", receipt.ID).Delete(&models.Activity{}).Error; err != nil {
+		return err
+	}
+	return r.createActivities(receipt)
+}
+
+func (r gormReceipts) createActivities(receipt *models.Receipt) error {
+	for i := range receipt.Activities {
+		activity := &receipt.Activities[i]
+		activity.ID = 0
+		activity.ReceiptID = receipt.ID
+		if err := r.db.Omit(clause.Associations).Create(activity).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r gormReceipts) AddPayment(payment *models.Payment) error {
+	if err := r.db.Create(payment).Error; err != nil {
+		return err
+	}
+	return syncAmountPaid(r.db, payment.ReceiptID)
+}
+
+func (r gormReceipts) Payments(receiptID uint) ([]models.Payment, error) {
+	var payments []models.Payment
+	err := r.db.Where("receipt_id = ?", receiptID).Order("paid_at, id").Find(&payments).Error
+	return payments, err
+}
+
+func (r gormReceipts) GetPayment(receiptID, paymentID uint) (models.Payment, error) {
+	var payment models.Payment
+	err := r.db.Where("receipt_id = ?", receiptID).First(&payment, paymentID).Error
+	return payment, notFound(err)
+}
+
+func (r gormReceipts) DeletePayment(payment models.Payment) error {
+	if err := deleted(r.db.Delete(&models.Payment{}, payment.ID)); err != nil {
+		return err
+	}
+	return syncAmountPaid(r.db, payment.ReceiptID)
+}
+
+// SyncAmountPaid recomputes the receipt's cached amount_paid from its payments.
+func syncAmountPaid(db *gorm.DB, receiptID uint) error {
+	return db.Exec(
+		"UPDATE receipts SET amount_paid = (SELECT COALESCE(SUM(amount), 0) FROM payments WHERE receipt_id = ?) WHERE id = ?",
+		receiptID, receiptID,
+	).Error
+}
+
+func (r gormReceipts) Delete(id uint) error {
+	return deleted(r.db.Delete(&models.Receipt{}, id))
+}
+
+func (r gormReceipts) Restore(id uint) error {
+	return deleted(r.db.Unscoped().Model(&models.Receipt{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).Update("deleted_at", nil))
+}
+
+type gormProposals struct{ db *gorm.DB }
+
+func (r gormProposals) List(status string) ([]models.Proposal, error) {
+	var proposals []models.Proposal
+	query := r.db
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Preload("Hotel").Preload("Rooms").Find(&proposals).Error
+	return proposals, err
+}
+
+func (r gormProposals) ListDeleted() ([]models.Proposal, error) {
+	proposals := []models.Proposal{}
+	err := r.db.Unscoped().Where("deleted_at IS NOT NULL").Order("deleted_at desc").
+		Preload("Hotel").Preload("Rooms").Find(&proposals).Error
+	return proposals, err
+}
+
+func (r gormProposals) ForClient(clientID uint) ([]models.Proposal, error) {
+	proposals := []models.Proposal{}
+	err := r.db.Where("client_id = ?", clientID).Preload("Hotel").
+		Order("created_at desc, id desc").Find(&proposals).Error
+	return proposals, err
+}
+
+func (r gormProposals) Get(id uint) (models.Proposal, error) {
+	var proposal models.Proposal
+	err := r.db.Preload("Hotel").Preload("Rooms").Preload("StatusHistory", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at asc, id asc")
+	}).First(&proposal, id).Error
+	return proposal, notFound(err)
+}
+
+func (r gormProposals) GetDeleted(id uint) (models.Proposal, error) {
+	var proposal models.Proposal
+	err := r.db.Unscoped().Where("deleted_at IS NOT NULL").Preload("Rooms").First(&proposal, id).Error
+	return proposal, notFound(err)
+}
+
+func (r gormProposals) Create(proposal *models.Proposal) error {
+	if err := r.db.Omit(clause.Associations).Create(proposal).Error; err != nil {
+		return err
+	}
+	return r.createRooms(proposal)
+}
+
+func (r gormProposals) Update(proposal *models.Proposal) error {
+	if err := r.db.Omit(clause.Associations).Save(proposal).Error; err != nil {
+		return err
+	}
+	if err := r.db.Where("proposal_id = ?", proposal.ID).Delete(&models.ProposalRoom{}).Error; err != nil {
+		return err
+	}
+	return r.createRooms(proposal)
+}
+
+func (r gormProposals) createRooms(proposal *models.Proposal) error {
+	for i := range proposal.Rooms {
+		room := &proposal.Rooms[i]
+		room.ID = 0
+		room.ProposalID = proposal.ID
+		if err := r.db.Create(room).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r gormProposals) UpdateStatus(id uint, from, to string) error {
+	res := r.db.Model(&models.Proposal{}).Where("id = ? AND status = ?", id, from).Update("status", to)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+func (r gormProposals) AddStatusChange(change *models.ProposalStatusChange) error {
+	return r.db.Create(change).Error
+}
+
+func (r gormProposals) LinkReceipt(id, receiptID uint) error {
+	res := r.db.Model(&models.Proposal{}).Where("id = ? AND receipt_id IS NULL", id).Update("receipt_id", receiptID)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+func (r gormProposals) UnlinkReceipt(receiptID uint) error {
+	return r.db.Model(&models.Proposal{}).Where("receipt_id = ?", receiptID).Update("receipt_id", nil).Error
+}
+
+func (r gormProposals) Delete(id uint) error {
+	return deleted(r.db.Delete(&models.Proposal{}, id))
+}
+
+func (r gormProposals) Restore(id uint) error {
+	return deleted(r.db.Unscoped().Model(&models.Proposal{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).Update("deleted_at", nil))
+}
+
+type gormClients struct{ db *gorm.DB }
+
+func (r gormClients) List(text, phone string, page Page) ([]models.Client, int64, error) {
+	query := r.db.Model(&models.Client{})
+	if text != "" {
+		pattern := "%" + likeLiteral(strings.ToLower(text)) + "%"
+		if phone != "" {
+			query = query.Where(`LOWER(name) LIKE ? ESCAPE '\' OR email LIKE ? ESCAPE '\' OR phone LIKE ? ESCAPE '\'`,
+				pattern, pattern, "%"+likeLiteral(phone)+"%")
+		} else {
+			query = query.Where(`LOWER(name) LIKE ? ESCAPE '\' OR email LIKE ? ESCAPE '\'`, pattern, pattern)
+		}
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	clients := []models.Client{}
+	err := paged(query, "clients", page).Find(&clients).Error
+	return clients, total, err
+}
+
+func (r gormClients) Get(id uint) (models.Client, error) {
+	var client models.Client
+	err := r.db.First(&client, id).Error
+	return client, notFound(err)
+}
+
+func (r gormClients) FindByEmail(email string, excludeID uint) (models.Client, error) {
+	var client models.Client
+	err := r.db.Where("email = ? AND id <> ?", email, excludeID).Order("id").First(&client).Error
+	return client, notFound(err)
+}
+
+func (r gormClients) FindByPhoneAndName(phone, name string, excludeID uint) (models.Client, error) {
+	var client models.Client
+	err := r.db.Where("phone = ? AND LOWER(name) = LOWER(?) AND id <> ?", phone, name, excludeID).Order("id").First(&client).Error
+	return client, notFound(err)
+}
+
+func (r gormClients) Create(client *models.Client) error {
+	return r.db.Create(client).Error
+}
+
+func (r gormClients) Update(client *models.Client) error {
+	return r.db.Save(client).Error
+}
+
+func (r gormClients) Delete(id uint) error {
+	if err := r.db.Unscoped().Model(&models.Receipt{}).Where("client_id = ?", id).Update("client_id", nil).Error; err != nil {
+		return err
+	}
+	if err := r.db.Unscoped().Model(&models.Proposal{}).Where("client_id = ?", id).Update("client_id", nil).Error; err != nil {
+		return err
+	}
+	return deleted(r.db.Delete(&models.Client{}, id))
+}
+
+type gormUsers struct{ db *gorm.DB }
+
+func (r gormUsers) List() ([]models.User, error) {
+	var users []models.User
+	err := r.db.Order("id asc").Find(&users).Error
+	return users, err
+}
+
+func (r gormUsers) Get(id uint) (models.User, error) {
+	var user models.User
+	err := r.db.First(&user, id).Error
+	return user, notFound(err)
+}
+
+func (r gormUsers) FindByEmail(email string, excludeID uint) (models.User, error) {
+	var user models.User
+	err := r.db.Where("email = ? AND id <> ?", email, excludeID).First(&user).Error
+	return user, notFound(err)
+}
+
+func (r gormUsers) Create(user *models.User) error {
+	return r.db.Create(user).Error
+}
+
+func (r gormUsers) Update(user *models.User) error {
+	return r.db.Save(user).Error
+}
+
+func (r gormUsers) Delete(id uint) error {
+	return deleted(r.db.Delete(&models.User{}, id))
+}
+
+// numberedColumns maps each number sequence to the column holding the numbers it issues.
+var numberedColumns = map[string]struct{ table, column string }{
+	"receipt":  {"receipts", "receipt_number"},
+	"proposal": {"proposals", "proposal_number"},
+}
+
+type gormNumbers struct{ db *gorm.DB }
+
+func (r gormNumbers) Next(name, period, stem string) (int, error) {
+	var exists int64
+	if err := r.db.Model(&models.NumberSequence{}).Where("name = ? AND period = ?", name, period).Count(&exists).Error; err != nil {
+		return 0, err
+	}
+	if exists == 0 {
+		start, err := HighestIssued(r.db, name, stem)
+		if err != nil {
+			return 0, err
+		}
+		if err := r.db.Exec(
+			"INSERT INTO number_sequences (name, period, value) VALUES (?, ?, ?) ON CONFLICT (name, period) DO NOTHING",
+			name, period, start,
+		).Error; err != nil {
+			return 0, err
+		}
+	}
+
+	var value int
+	if err := r.db.Raw(
+		"UPDATE number_sequences SET value = value + 1 WHERE name = ? AND period = ? RETURNING value",
+		name, period,
+	).Scan(&value).Error; err != nil {
+		return 0, err
+	}
+	if value == 0 {
+		return 0, fmt.Errorf("number sequence %s/%s was not allocated", name, period)
+	}
+	return value, nil
+}
+
+// HighestIssued finds the largest counter sequence name has already used with stem, so a
+// new counter continues after numbers issued before counters existed or typed by hand.
+func HighestIssued(db *gorm.DB, name, stem string) (int, error) {
+	target, ok := numberedColumns[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown number sequence %q", name)
+	}
+	var numbers []string
+	if err := db.Table(target.table).Where(target.column+` LIKE ? ESCAPE '\'`, likeLiteral(stem)+"%").Pluck(target.column, &numbers).Error; err != nil {
+		return 0, err
+	}
+	highest := 0
+	for _, number := range numbers {
+		n, err := strconv.Atoi(strings.TrimPrefix(number, stem))
+		if err == nil && n > highest {
+			highest = n
+		}
+	}
+	return highest, nil
+}
+
+type gormExchangeRates struct{ db *gorm.DB }
+
+func (r gormExchangeRates) List() ([]models.ExchangeRate, error) {
+	var rates []models.ExchangeRate
+	err := r.db.Find(&rates).Error
+	return rates, err
+}
+
+func (r gormExchangeRates) Replace(rates []models.ExchangeRate) error {
+	if err := r.db.Where("1 = 1").Delete(&models.ExchangeRate{}).Error; err != nil {
+		return err
+	}
+	if len(rates) == 0 {
+		return nil
+	}
+	return r.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&rates).Error
+}
+
+type gormAudit struct{ db *gorm.DB }
+
+func (r gormAudit) Create(entry *models.AuditLog) error {
+	return r.db.Create(entry).Error
+}
+
+func (r gormAudit) List(filter AuditFilter, limit int) ([]models.AuditLog, error) {
+	query := r.db.Order("created_at desc, id desc")
+	if filter.Entity != "" {
+		query = query.Where("entity = ?", filter.Entity)
+	}
+	if filter.EntityID != nil {
+		query = query.Where("entity_id = ?", *filter.EntityID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.ActorID != nil {
+		query = query.Where("actor_id = ?", *filter.ActorID)
+	}
+	var logs []models.AuditLog
+	err := query.Limit(limit).Find(&logs).Error
+	return logs, err
+}
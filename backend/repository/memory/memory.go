@@ -0,0 +1,1099 @@
+// Package memory is an in-memory repository.Store for tests. It keeps every table in maps,
+// hands out ids from one counter and undoes a failed Transaction by restoring a snapshot.
+// It is not safe for concurrent transactions.
+package memory
+
+import (
+	"cmp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Otabek228101/mehmon/models"
+	"github.com/Otabek228101/mehmon/repository"
+	"gorm.io/gorm"
+)
+
+type tables struct {
+	nextID        uint
+	hotels        map[uint]models.Hotel
+	carRentals    map[uint]models.CarRental
+	receipts      map[uint]models.Receipt
+	proposals     map[uint]models.Proposal
+	clients       map[uint]models.Client
+	users         map[uint]models.User
+	numbers       map[string]int
+	statusChanges []models.ProposalStatusChange
+	rates         []models.ExchangeRate
+	audit         []models.AuditLog
+}
+
+func (t *tables) clone() *tables {
+	c := *t
+	c.hotels = cloneMap(t.hotels)
+	c.carRentals = cloneMap(t.carRentals)
+	c.receipts = cloneMap(t.receipts)
+	c.proposals = cloneMap(t.proposals)
+	c.clients = cloneMap(t.clients)
+	c.users = cloneMap(t.users)
+	c.numbers = make(map[string]int, len(t.numbers))
+	for k, v := range t.numbers {
+		c.numbers[k] = v
+	}
+	c.statusChanges = append([]models.ProposalStatusChange(nil), t.statusChanges...)
+	c.rates = append([]models.ExchangeRate(nil), t.rates...)
+	c.audit = append([]models.AuditLog(nil), t.audit...)
+	return &c
+}
+
+func cloneMap[T any](m map[uint]T) map[uint]T {
+	c := make(map[uint]T, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// compare orders two values of one sort column.
+func compare(a, b any) int {
+	switch a := a.(type) {
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	case float64:
+		return cmp.Compare(a, b.(float64))
+	case string:
+		return strings.Compare(a, b.(string))
+	}
+	return 0
+}
+
+// paged sorts rows as page asks and cuts the page out of them. value reads the sort column
+// of a row the way the gorm store compares it.
+func paged[T any](rows []T, page repository.Page, value func(T, string) any, id func(T) uint) []T {
+	order := func(row T, v any, rowID uint) int {
+		c := compare(value(row, page.Sort), v)
+		if c == 0 {
+			c = cmp.Compare(id(row), rowID)
+		}
+		if page.Desc {
+			c = -c
+		}
+		return c
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return order(rows[i], value(rows[j], page.Sort), id(rows[j])) < 0
+	})
+
+	start := page.Offset
+	if page.After != nil {
+		start = len(rows)
+		for i, row := range rows {
+			if order(row, page.After.Value, page.After.ID) > 0 {
+				start = i
+				break
+			}
+		}
+	}
+	start = min(start, len(rows))
+	end := len(rows)
+	if page.Limit > 0 {
+		end = min(start+page.Limit, end)
+	}
+	return rows[start:end]
+}
+
+func sortedValues[T any](m map[uint]T, keep func(T) bool) []T {
+	ids := make([]uint, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	values := []T{}
+	for _, id := range ids {
+		if keep(m[id]) {
+			values = append(values, m[id])
+		}
+	}
+	return values
+}
+
+type Store struct {
+	mu sync.Mutex
+	t  *tables
+
+	// FailAudit, when set, is returned by every audit write. Tests use it to check that
+	// a service rolls its changes back.
+	FailAudit error
+}
+
+func NewStore() *Store {
+	return &Store{t: &tables{
+		hotels:     map[uint]models.Hotel{},
+		carRentals: map[uint]models.CarRental{},
+		receipts:   map[uint]models.Receipt{},
+		proposals:  map[uint]models.Proposal{},
+		clients:    map[uint]models.Client{},
+		users:      map[uint]models.User{},
+		numbers:    map[string]int{},
+	}}
+}
+
+func (s *Store) id() uint {
+	s.t.nextID++
+	return s.t.nextID
+}
+
+// PutReceipt stores a receipt as is, giving it an id when it has none.
+func (s *Store) PutReceipt(receipt models.Receipt) models.Receipt {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if receipt.ID == 0 {
+		receipt.ID = s.id()
+	}
+	s.t.receipts[receipt.ID] = receipt
+	return receipt
+}
+
+// PutProposal stores a proposal as is, giving it an id when it has none.
+func (s *Store) PutProposal(proposal models.Proposal) models.Proposal {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if proposal.ID == 0 {
+		proposal.ID = s.id()
+	}
+	proposal.Hotel = nil
+	proposal.StatusHistory = nil
+	s.t.proposals[proposal.ID] = proposal
+	return proposal
+}
+
+// PutHotel stores a hotel as is, giving it an id when it has none.
+func (s *Store) PutHotel(hotel models.Hotel) models.Hotel {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if hotel.ID == 0 {
+		hotel.ID = s.id()
+	}
+	s.t.hotels[hotel.ID] = hotel
+	return hotel
+}
+
+// PutClient stores a client as is, giving it an id when it has none.
+func (s *Store) PutClient(client models.Client) models.Client {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if client.ID == 0 {
+		client.ID = s.id()
+	}
+	s.t.clients[client.ID] = client
+	return client
+}
+
+// PutUser stores a user as is, giving it an id when it has none.
+func (s *Store) PutUser(user models.User) models.User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if user.ID == 0 {
+		user.ID = s.id()
+	}
+	s.t.users[user.ID] = user
+	return user
+}
+
+func (s *Store) PutExchangeRate(rate models.ExchangeRate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.t.rates = append(s.t.rates, rate)
+}
+
+// AuditLog returns the audit entries written so far, oldest first.
+func (s *Store) AuditLog() []models.AuditLog {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]models.AuditLog(nil), s.t.audit...)
+}
+
+func (s *Store) Hotels() repository.HotelRepository               { return hotels{s} }
+func (s *Store) CarRentals() repository.CarRentalRepository       { return carRentals{s} }
+func (s *Store) Receipts() repository.ReceiptRepository           { return receipts{s} }
+func (s *Store) Proposals() repository.ProposalRepository         { return proposals{s} }
+func (s *Store) Clients() repository.ClientRepository             { return clients{s} }
+func (s *Store) Users() repository.UserRepository                 { return users{s} }
+func (s *Store) Numbers() repository.NumberRepository             { return numbers{s} }
+func (s *Store) ExchangeRates() repository.ExchangeRateRepository { return exchangeRates{s} }
+func (s *Store) Audit() repository.AuditRepository                { return audit{s} }
+func (s *Store) Search() repository.SearchRepository              { return search{s} }
+
+func (s *Store) Transaction(fn func(repository.Store) error) error {
+	s.mu.Lock()
+	snapshot := s.t.clone()
+	s.mu.Unlock()
+
+	err := fn(s)
+	if err != nil {
+		s.mu.Lock()
+		s.t = snapshot
+		s.mu.Unlock()
+	}
+	return err
+}
+
+func deletedAt() gorm.DeletedAt {
+	return gorm.DeletedAt{Time: time.Now(), Valid: true}
+}
+
+type hotels struct{ s *Store }
+
+func (r hotels) List(city string) ([]models.Hotel, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	city = strings.ToLower(city)
+	return sortedValues(r.s.t.hotels, func(h models.Hotel) bool {
+		return strings.Contains(strings.ToLower(h.City), city)
+	}), nil
+}
+
+func (r hotels) Get(id uint) (models.Hotel, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	hotel, ok := r.s.t.hotels[id]
+	if !ok {
+		return hotel, repository.ErrNotFound
+	}
+	return hotel, nil
+}
+
+func (r hotels) FindByName(name string) ([]models.Hotel, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	found := sortedValues(r.s.t.hotels, func(h models.Hotel) bool { return strings.EqualFold(h.Name, name) })
+	for i := range found {
+		found[i].Images = nil
+	}
+	return found, nil
+}
+
+func (r hotels) Create(hotel *models.Hotel) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	hotel.ID = r.s.id()
+	r.s.t.hotels[hotel.ID] = *hotel
+	return nil
+}
+
+func (r hotels) Update(hotel *models.Hotel) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	stored, ok := r.s.t.hotels[hotel.ID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	updated := *hotel
+	updated.Images = stored.Images
+	r.s.t.hotels[hotel.ID] = updated
+	return nil
+}
+
+func (r hotels) Delete(id uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.t.hotels[id]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(r.s.t.hotels, id)
+	return nil
+}
+
+func (r hotels) Images(hotelID uint, mimes []string, limit int) ([]models.HotelImage, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var images []models.HotelImage
+	for _, image := range r.s.t.hotels[hotelID].Images {
+		if len(mimes) == 0 || slices.Contains(mimes, image.Mime) {
+			images = append(images, image)
+		}
+	}
+	sort.SliceStable(images, func(i, j int) bool {
+		if images[i].SortOrder != images[j].SortOrder {
+			return images[i].SortOrder < images[j].SortOrder
+		}
+		return images[i].ID < images[j].ID
+	})
+	if limit > 0 && len(images) > limit {
+		images = images[:limit]
+	}
+	return images, nil
+}
+
+func (r hotels) LastImageSort(hotelID uint) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	last := 0
+	for _, image := range r.s.t.hotels[hotelID].Images {
+		last = max(last, image.SortOrder)
+	}
+	return last, nil
+}
+
+func (r hotels) AddImage(image *models.HotelImage) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	hotel, ok := r.s.t.hotels[image.HotelID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	image.ID = r.s.id()
+	hotel.Images = append(append([]models.HotelImage(nil), hotel.Images...), *image)
+	r.s.t.hotels[hotel.ID] = hotel
+	return nil
+}
+
+type carRentals struct{ s *Store }
+
+func (r carRentals) List() ([]models.CarRental, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return sortedValues(r.s.t.carRentals, func(models.CarRental) bool { return true }), nil
+}
+
+func (r carRentals) Get(id uint) (models.CarRental, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	rental, ok := r.s.t.carRentals[id]
+	if !ok {
+		return rental, repository.ErrNotFound
+	}
+	return rental, nil
+}
+
+func (r carRentals) Create(rental *models.CarRental) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	rental.ID = r.s.id()
+	r.s.t.carRentals[rental.ID] = *rental
+	return nil
+}
+
+func (r carRentals) Delete(id uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.t.carRentals[id]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(r.s.t.carRentals, id)
+	return nil
+}
+
+type receipts struct{ s *Store }
+
+func hasActivity(receipt models.Receipt, match func(models.Activity) bool) bool {
+	for _, activity := range receipt.Activities {
+		if match(activity) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesReceipt(receipt models.Receipt, f repository.ReceiptFilter) bool {
+	date, paid := receipt.ReceiptDate, receipt.AmountPaid
+	switch {
+	case receipt.DeletedAt.Valid,
+		f.From != nil && date.Before(*f.From),
+		f.To != nil && date.After(*f.To),
+		f.Before != nil && !date.Before(*f.Before),
+		f.MinAmount != nil && paid.Less(*f.MinAmount),
+		f.MaxAmount != nil && f.MaxAmount.Less(paid):
+		return false
+	}
+	if f.ActivityType != "" && !hasActivity(receipt, func(a models.Activity) bool { return a.Type == f.ActivityType }) {
+		return false
+	}
+	if f.HotelID != 0 && !hasActivity(receipt, func(a models.Activity) bool { return a.HotelID != nil && *a.HotelID == f.HotelID }) {
+		return false
+	}
+	if f.Query != "" {
+		q := strings.ToLower(f.Query)
+		for _, field := range []string{receipt.ReceiptNumber, receipt.ClientName, receipt.ClientEmail, receipt.ClientPhone} {
+			if strings.Contains(strings.ToLower(field), q) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func receiptColumn(receipt models.Receipt, column string) any {
+	switch column {
+	case "amount_paid":
+		return receipt.AmountPaid.Float64()
+	case "receipt_number":
+		return receipt.ReceiptNumber
+	}
+	return receipt.ReceiptDate
+}
+
+func withoutPayments(list []models.Receipt) []models.Receipt {
+	for i := range list {
+		list[i].Payments = nil
+	}
+	return list
+}
+
+func (r receipts) List(filter repository.ReceiptFilter, page repository.Page) ([]models.Receipt, int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	list := sortedValues(r.s.t.receipts, func(rc models.Receipt) bool { return matchesReceipt(rc, filter) })
+	total := int64(len(list))
+	list = paged(list, page, receiptColumn, func(rc models.Receipt) uint { return rc.ID })
+	return withoutPayments(list), total, nil
+}
+
+func (r receipts) Find(filter repository.ReceiptFilter) ([]models.Receipt, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	list := sortedValues(r.s.t.receipts, func(rc models.Receipt) bool { return matchesReceipt(rc, filter) })
+	sort.SliceStable(list, func(i, j int) bool { return list[i].ReceiptDate.Before(list[j].ReceiptDate) })
+	return withoutPayments(list), nil
+}
+
+func (r receipts) ListDeleted() ([]models.Receipt, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	list := sortedValues(r.s.t.receipts, func(rc models.Receipt) bool { return rc.DeletedAt.Valid })
+	sort.SliceStable(list, func(i, j int) bool { return list[j].DeletedAt.Time.Before(list[i].DeletedAt.Time) })
+	return withoutPayments(list), nil
+}
+
+func (r receipts) ForClient(clientID uint) ([]models.Receipt, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	list := sortedValues(r.s.t.receipts, func(rc models.Receipt) bool {
+		return !rc.DeletedAt.Valid && rc.ClientID != nil && *rc.ClientID == clientID
+	})
+	sort.SliceStable(list, func(i, j int) bool {
+		if !list[i].ReceiptDate.Equal(list[j].ReceiptDate) {
+			return list[j].ReceiptDate.Before(list[i].ReceiptDate)
+		}
+		return list[i].ID > list[j].ID
+	})
+	return withoutPayments(list), nil
+}
+
+func (r receipts) find(id uint, trashed bool) (models.Receipt, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	receipt, ok := r.s.t.receipts[id]
+	if !ok || receipt.DeletedAt.Valid != trashed {
+		return models.Receipt{}, repository.ErrNotFound
+	}
+	return receipt, nil
+}
+
+func (r receipts) Get(id uint) (models.Receipt, error)        { return r.find(id, false) }
+func (r receipts) GetDeleted(id uint) (models.Receipt, error) { return r.find(id, true) }
+
+// withActivityIDs copies the activities of receipt, giving each one an id. The caller
+// holds the lock.
+func (r receipts) withActivityIDs(receipt *models.Receipt) {
+	activities := make([]models.Activity, len(receipt.Activities))
+	for i, activity := range receipt.Activities {
+		activity.ID = r.s.id()
+		activity.ReceiptID = receipt.ID
+		activities[i] = activity
+	}
+	receipt.Activities = activities
+}
+
+func (r receipts) Create(receipt *models.Receipt) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	receipt.ID = r.s.id()
+	receipt.CreatedAt = time.Now()
+	receipt.UpdatedAt = receipt.CreatedAt
+	receipt.Payments = nil
+	r.withActivityIDs(receipt)
+	r.s.t.receipts[receipt.ID] = *receipt
+	return nil
+}
+
+func (r receipts) Update(receipt *models.Receipt) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	stored, ok := r.s.t.receipts[receipt.ID]
+	if !ok || stored.DeletedAt.Valid {
+		return repository.ErrNotFound
+	}
+	receipt.UpdatedAt = time.Now()
+	receipt.Payments = stored.Payments
+	r.withActivityIDs(receipt)
+	r.s.t.receipts[receipt.ID] = *receipt
+	return nil
+}
+
+func (r receipts) AddPayment(payment *models.Payment) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	receipt, ok := r.s.t.receipts[payment.ReceiptID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	payment.ID = r.s.id()
+	payment.CreatedAt = time.Now()
+	r.setPayments(receipt, append(append([]models.Payment(nil), receipt.Payments...), *payment))
+	return nil
+}
+
+// setPayments stores payments on the receipt and recomputes its amount paid. The caller
+// holds the lock.
+func (r receipts) setPayments(receipt models.Receipt, payments []models.Payment) {
+	sort.SliceStable(payments, func(i, j int) bool {
+		if !payments[i].PaidAt.Equal(payments[j].PaidAt) {
+			return payments[i].PaidAt.Before(payments[j].PaidAt)
+		}
+		return payments[i].ID < payments[j].ID
+	})
+	receipt.Payments = payments
+	receipt.AmountPaid = models.Money{}
+	for _, p := range payments {
+		receipt.AmountPaid = receipt.AmountPaid.Add(p.Amount)
+	}
+	r.s.t.receipts[receipt.ID] = receipt
+}
+
+func (r receipts) Payments(receiptID uint) ([]models.Payment, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return append([]models.Payment(nil), r.s.t.receipts[receiptID].Payments...), nil
+}
+
+func (r receipts) GetPayment(receiptID, paymentID uint) (models.Payment, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, payment := range r.s.t.receipts[receiptID].Payments {
+		if payment.ID == paymentID {
+			return payment, nil
+		}
+	}
+	return models.Payment{}, repository.ErrNotFound
+}
+
+func (r receipts) DeletePayment(payment models.Payment) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	receipt, ok := r.s.t.receipts[payment.ReceiptID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	kept := []models.Payment{}
+	for _, p := range receipt.Payments {
+		if p.ID != payment.ID {
+			kept = append(kept, p)
+		}
+	}
+	if len(kept) == len(receipt.Payments) {
+		return repository.ErrNotFound
+	}
+	r.setPayments(receipt, kept)
+	return nil
+}
+
+func (r receipts) setDeleted(id uint, at gorm.DeletedAt) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	receipt, ok := r.s.t.receipts[id]
+	if !ok || receipt.DeletedAt.Valid == at.Valid {
+		return repository.ErrNotFound
+	}
+	receipt.DeletedAt = at
+	r.s.t.receipts[id] = receipt
+	return nil
+}
+
+func (r receipts) Delete(id uint) error  { return r.setDeleted(id, deletedAt()) }
+func (r receipts) Restore(id uint) error { return r.setDeleted(id, gorm.DeletedAt{}) }
+
+type proposals struct{ s *Store }
+
+// withRelations fills the hotel and status history the gorm store preloads. The caller
+// holds the lock.
+func (r proposals) withRelations(proposal models.Proposal) models.Proposal {
+	if hotel, ok := r.s.t.hotels[proposal.HotelID]; ok {
+		proposal.Hotel = &hotel
+	}
+	proposal.StatusHistory = nil
+	for _, change := range r.s.t.statusChanges {
+		if change.ProposalID == proposal.ID {
+			proposal.StatusHistory = append(proposal.StatusHistory, change)
+		}
+	}
+	return proposal
+}
+
+func (r proposals) List(status string) ([]models.Proposal, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	list := sortedValues(r.s.t.proposals, func(p models.Proposal) bool {
+		return !p.DeletedAt.Valid && (status == "" || p.Status == status)
+	})
+	for i := range list {
+		list[i] = r.withRelations(list[i])
+		list[i].StatusHistory = nil
+	}
+	return list, nil
+}
+
+func (r proposals) ListDeleted() ([]models.Proposal, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	list := sortedValues(r.s.t.proposals, func(p models.Proposal) bool { return p.DeletedAt.Valid })
+	sort.SliceStable(list, func(i, j int) bool { return list[j].DeletedAt.Time.Before(list[i].DeletedAt.Time) })
+	for i := range list {
+		list[i] = r.withRelations(list[i])
+		list[i].StatusHistory = nil
+	}
+	return list, nil
+}
+
+func (r proposals) ForClient(clientID uint) ([]models.Proposal, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	list := sortedValues(r.s.t.proposals, func(p models.Proposal) bool {
+		return !p.DeletedAt.Valid && p.ClientID != nil && *p.ClientID == clientID
+	})
+	sort.SliceStable(list, func(i, j int) bool {
+		if !list[i].CreatedAt.Equal(list[j].CreatedAt) {
+			return list[j].CreatedAt.Before(list[i].CreatedAt)
+		}
+		return list[i].ID > list[j].ID
+	})
+	for i := range list {
+		list[i] = r.withRelations(list[i])
+		list[i].Rooms, list[i].StatusHistory = nil, nil
+	}
+	return list, nil
+}
+
+func (r proposals) find(id uint, trashed bool) (models.Proposal, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	proposal, ok := r.s.t.proposals[id]
+	if !ok || proposal.DeletedAt.Valid != trashed {
+		return models.Proposal{}, repository.ErrNotFound
+	}
+	return r.withRelations(proposal), nil
+}
+
+func (r proposals) Get(id uint) (models.Proposal, error)        { return r.find(id, false) }
+func (r proposals) GetDeleted(id uint) (models.Proposal, error) { return r.find(id, true) }
+
+// update applies fn to a live proposal; fn reports whether it changed anything.
+func (r proposals) update(id uint, fn func(*models.Proposal) bool) bool {
+	proposal, ok := r.s.t.proposals[id]
+	if !ok || proposal.DeletedAt.Valid || !fn(&proposal) {
+		return false
+	}
+	r.s.t.proposals[id] = proposal
+	return true
+}
+
+// store keeps proposal with fresh room ids and without the relations Get fills in. The
+// caller holds the lock.
+func (r proposals) store(proposal *models.Proposal) {
+	rooms := make([]models.ProposalRoom, len(proposal.Rooms))
+	for i, room := range proposal.Rooms {
+		room.ID = r.s.id()
+		room.ProposalID = proposal.ID
+		rooms[i] = room
+	}
+	proposal.Rooms = rooms
+	stored := *proposal
+	stored.Hotel, stored.StatusHistory = nil, nil
+	r.s.t.proposals[proposal.ID] = stored
+}
+
+func (r proposals) Create(proposal *models.Proposal) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	proposal.ID = r.s.id()
+	proposal.CreatedAt = time.Now()
+	proposal.UpdatedAt = proposal.CreatedAt
+	r.store(proposal)
+	return nil
+}
+
+func (r proposals) Update(proposal *models.Proposal) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	stored, ok := r.s.t.proposals[proposal.ID]
+	if !ok || stored.DeletedAt.Valid {
+		return repository.ErrNotFound
+	}
+	proposal.UpdatedAt = time.Now()
+	r.store(proposal)
+	return nil
+}
+
+func (r proposals) UpdateStatus(id uint, from, to string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	ok := r.update(id, func(p *models.Proposal) bool {
+		if p.Status != from {
+			return false
+		}
+		p.Status = to
+		return true
+	})
+	if !ok {
+		return repository.ErrConflict
+	}
+	return nil
+}
+
+func (r proposals) AddStatusChange(change *models.ProposalStatusChange) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	change.ID = r.s.id()
+	change.CreatedAt = time.Now()
+	r.s.t.statusChanges = append(r.s.t.statusChanges, *change)
+	return nil
+}
+
+func (r proposals) LinkReceipt(id, receiptID uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	linked := r.update(id, func(p *models.Proposal) bool {
+		if p.ReceiptID != nil {
+			return false
+		}
+		p.ReceiptID = &receiptID
+		return true
+	})
+	if !linked {
+		return repository.ErrConflict
+	}
+	return nil
+}
+
+func (r proposals) UnlinkReceipt(receiptID uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for id := range r.s.t.proposals {
+		r.update(id, func(p *models.Proposal) bool {
+			if p.ReceiptID == nil || *p.ReceiptID != receiptID {
+				return false
+			}
+			p.ReceiptID = nil
+			return true
+		})
+	}
+	return nil
+}
+
+func (r proposals) setDeleted(id uint, at gorm.DeletedAt) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	proposal, ok := r.s.t.proposals[id]
+	if !ok || proposal.DeletedAt.Valid == at.Valid {
+		return repository.ErrNotFound
+	}
+	proposal.DeletedAt = at
+	r.s.t.proposals[id] = proposal
+	return nil
+}
+
+func (r proposals) Delete(id uint) error  { return r.setDeleted(id, deletedAt()) }
+func (r proposals) Restore(id uint) error { return r.setDeleted(id, gorm.DeletedAt{}) }
+
+type clients struct{ s *Store }
+
+func clientColumn(client models.Client, column string) any {
+	if column == "created_at" {
+		return client.CreatedAt
+	}
+	return client.Name
+}
+
+func (r clients) List(text, phone string, page repository.Page) ([]models.Client, int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	text = strings.ToLower(text)
+	list := sortedValues(r.s.t.clients, func(c models.Client) bool {
+		return strings.Contains(strings.ToLower(c.Name), text) || strings.Contains(c.Email, text) ||
+			(phone != "" && strings.Contains(c.Phone, phone))
+	})
+	total := int64(len(list))
+	return paged(list, page, clientColumn, func(c models.Client) uint { return c.ID }), total, nil
+}
+
+func (r clients) Get(id uint) (models.Client, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	client, ok := r.s.t.clients[id]
+	if !ok {
+		return client, repository.ErrNotFound
+	}
+	return client, nil
+}
+
+func (r clients) find(excludeID uint, match func(models.Client) bool) (models.Client, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	found := sortedValues(r.s.t.clients, func(c models.Client) bool { return c.ID != excludeID && match(c) })
+	if len(found) == 0 {
+		return models.Client{}, repository.ErrNotFound
+	}
+	return found[0], nil
+}
+
+func (r clients) FindByEmail(email string, excludeID uint) (models.Client, error) {
+	return r.find(excludeID, func(c models.Client) bool { return c.Email == email })
+}
+
+func (r clients) FindByPhoneAndName(phone, name string, excludeID uint) (models.Client, error) {
+	return r.find(excludeID, func(c models.Client) bool { return c.Phone == phone && strings.EqualFold(c.Name, name) })
+}
+
+func (r clients) Create(client *models.Client) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	client.ID = r.s.id()
+	r.s.t.clients[client.ID] = *client
+	return nil
+}
+
+func (r clients) Update(client *models.Client) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.t.clients[client.ID]; !ok {
+		return repository.ErrNotFound
+	}
+	r.s.t.clients[client.ID] = *client
+	return nil
+}
+
+func (r clients) Delete(id uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.t.clients[id]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(r.s.t.clients, id)
+	for _, receipt := range r.s.t.receipts {
+		if receipt.ClientID != nil && *receipt.ClientID == id {
+			receipt.ClientID = nil
+			r.s.t.receipts[receipt.ID] = receipt
+		}
+	}
+	for _, proposal := range r.s.t.proposals {
+		if proposal.ClientID != nil && *proposal.ClientID == id {
+			proposal.ClientID = nil
+			r.s.t.proposals[proposal.ID] = proposal
+		}
+	}
+	return nil
+}
+
+type users struct{ s *Store }
+
+func (r users) List() ([]models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return sortedValues(r.s.t.users, func(models.User) bool { return true }), nil
+}
+
+func (r users) Get(id uint) (models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	user, ok := r.s.t.users[id]
+	if !ok {
+		return user, repository.ErrNotFound
+	}
+	return user, nil
+}
+
+func (r users) FindByEmail(email string, excludeID uint) (models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	found := sortedValues(r.s.t.users, func(u models.User) bool { return u.ID != excludeID && u.Email == email })
+	if len(found) == 0 {
+		return models.User{}, repository.ErrNotFound
+	}
+	return found[0], nil
+}
+
+func (r users) Create(user *models.User) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	user.ID = r.s.id()
+	user.CreatedAt = time.Now()
+	r.s.t.users[user.ID] = *user
+	return nil
+}
+
+func (r users) Update(user *models.User) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.t.users[user.ID]; !ok {
+		return repository.ErrNotFound
+	}
+	r.s.t.users[user.ID] = *user
+	return nil
+}
+
+func (r users) Delete(id uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.t.users[id]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(r.s.t.users, id)
+	return nil
+}
+
+type numbers struct{ s *Store }
+
+func (r numbers) Next(name, period, stem string) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	key := name + "/" + period
+	value, ok := r.s.t.numbers[key]
+	if !ok {
+		var issued []string
+		switch name {
+		case "receipt":
+			for _, receipt := range r.s.t.receipts {
+				issued = append(issued, receipt.ReceiptNumber)
+			}
+		case "proposal":
+			for _, proposal := range r.s.t.proposals {
+				issued = append(issued, proposal.ProposalNumber)
+			}
+		}
+		for _, number := range issued {
+			if n, err := strconv.Atoi(strings.TrimPrefix(number, stem)); err == nil && strings.HasPrefix(number, stem) && n > value {
+				value = n
+			}
+		}
+	}
+	value++
+	r.s.t.numbers[key] = value
+	return value, nil
+}
+
+type exchangeRates struct{ s *Store }
+
+func (r exchangeRates) List() ([]models.ExchangeRate, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return append([]models.ExchangeRate(nil), r.s.t.rates...), nil
+}
+
+func (r exchangeRates) Replace(rates []models.ExchangeRate) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.t.rates = append([]models.ExchangeRate(nil), rates...)
+	return nil
+}
+
+type audit struct{ s *Store }
+
+func (r audit) Create(entry *models.AuditLog) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if r.s.FailAudit != nil {
+		return r.s.FailAudit
+	}
+	entry.ID = r.s.id()
+	entry.CreatedAt = time.Now()
+	r.s.t.audit = append(r.s.t.audit, *entry)
+	return nil
+}
+
+func (r audit) List(filter repository.AuditFilter, limit int) ([]models.AuditLog, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	logs := []models.AuditLog{}
+	for i := len(r.s.t.audit) - 1; i >= 0 && len(logs) < limit; i-- {
+		entry := r.s.t.audit[i]
+		switch {
+		case filter.Entity != "" && entry.Entity != filter.Entity,
+			filter.EntityID != nil && entry.EntityID != *filter.EntityID,
+			filter.Action != "" && entry.Action != filter.Action,
+			filter.ActorID != nil && (entry.ActorID == nil || *entry.ActorID != *filter.ActorID):
+			continue
+		}
+		logs = append(logs, entry)
+	}
+	return logs, nil
+}
+
+// search matches every term as a substring of the same fields the gorm store indexes. All
+// hits rank equally.
+type search struct{ s *Store }
+
+func containsAll(terms []string, fields ...string) bool {
+	document := strings.ToLower(strings.Join(fields, " "))
+	for _, term := range terms {
+		if !strings.Contains(document, term) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r search) Search(terms, types []string, limit int) ([]models.SearchHit, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	hits := []models.SearchHit{}
+	if len(terms) == 0 {
+		return hits, nil
+	}
+	wanted := map[string]bool{}
+	for _, t := range types {
+		wanted[t] = true
+	}
+	include := func(t string) bool { return len(wanted) == 0 || wanted[t] }
+
+	live := sortedValues(r.s.t.receipts, func(rc models.Receipt) bool { return !rc.DeletedAt.Valid })
+	for _, receipt := range live {
+		if include(models.SearchHitReceipt) && containsAll(terms, receipt.ReceiptNumber, receipt.ClientName, receipt.ClientEmail, receipt.ClientPhone) {
+			hits = append(hits, models.SearchHit{Type: models.SearchHitReceipt, ID: receipt.ID, Number: receipt.ReceiptNumber, Title: receipt.ClientName, Rank: 1})
+		}
+		if !include(models.SearchHitActivity) {
+			continue
+		}
+		for _, a := range receipt.Activities {
+			if containsAll(terms, a.Type, a.PropertyName, a.PropertyAddress, a.PickupLocation, a.DropoffLocation, a.TransferType, a.Description, a.Provider, a.Reference) {
+				title := a.PropertyName
+				if title == "" {
+					title = a.Type
+				}
+				receiptID := receipt.ID
+				hits = append(hits, models.SearchHit{Type: models.SearchHitActivity, ID: a.ID, ReceiptID: &receiptID, Number: receipt.ReceiptNumber, Title: title, Rank: 1})
+			}
+		}
+	}
+	if include(models.SearchHitProposal) {
+		for _, p := range sortedValues(r.s.t.proposals, func(p models.Proposal) bool { return !p.DeletedAt.Valid }) {
+			if containsAll(terms, p.ProposalNumber, p.ClientName, p.Status) {
+				hits = append(hits, models.SearchHit{Type: models.SearchHitProposal, ID: p.ID, Number: p.ProposalNumber, Title: p.ClientName, Rank: 1})
+			}
+		}
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Type != hits[j].Type {
+			return hits[i].Type < hits[j].Type
+		}
+		return hits[i].ID > hits[j].ID
+	})
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
@@ -0,0 +1,206 @@
+// Package repository describes the storage that services depend on. The gorm store in this
+// package backs the running server; package memory holds in-memory fakes for tests.
+//
+// The maintenance commands (export, import, renumber) and the client linking done at
+// startup still take a *gorm.DB: they copy or rewrite whole tables and never run behind
+// an HTTP handler.
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Otabek228101/mehmon/models"
+)
+
+var (
+	ErrNotFound = errors.New("record not found")
+	// ErrConflict means a conditional write found the record changed since it was read.
+	ErrConflict = errors.New("record changed concurrently")
+)
+
+// Page selects Limit rows of a sorted list, skipping Offset rows or, when After is set,
+// every row up to and including the one it marks. Sort names a column of the listed table;
+// ties are broken by id in the same direction.
+type Page struct {
+	Sort   string
+	Desc   bool
+	Offset int
+	Limit  int
+	After  *Cursor
+}
+
+// Cursor marks the last row of the previous page by its sort value and id.
+type Cursor struct {
+	Value any
+	ID    uint
+}
+
+// ReceiptFilter selects receipts; zero fields match everything. From and To are inclusive
+// and Before is exclusive, so a date-only bound can cover the whole day.
+type ReceiptFilter struct {
+	From, To, Before     *time.Time
+	MinAmount, MaxAmount *models.Money
+	ActivityType         string
+	// HotelID keeps receipts with an activity at the catalog hotel.
+	HotelID uint
+	// Query matches part of the number or the client's name, email or phone, ignoring case.
+	Query string
+}
+
+// AuditFilter selects audit entries; zero fields match everything.
+type AuditFilter struct {
+	Entity   string
+	EntityID *uint
+	Action   string
+	ActorID  *uint
+}
+
+// HotelRepository stores catalog hotels. Get and List include the hotel images.
+type HotelRepository interface {
+	List(city string) ([]models.Hotel, error)
+	Get(id uint) (models.Hotel, error)
+	// FindByName returns the hotels named name, ignoring case, without their images.
+	FindByName(name string) ([]models.Hotel, error)
+	Create(hotel *models.Hotel) error
+	Update(hotel *models.Hotel) error
+	Delete(id uint) error
+	// Images returns the hotel's images in display order, only those of the given mime
+	// types when mimes is not empty, and at most limit of them when limit is positive.
+	Images(hotelID uint, mimes []string, limit int) ([]models.HotelImage, error)
+	// LastImageSort returns the highest sort order of the hotel's images, 0 without any.
+	LastImageSort(hotelID uint) (int, error)
+	AddImage(image *models.HotelImage) error
+}
+
+type CarRentalRepository interface {
+	List() ([]models.CarRental, error)
+	Get(id uint) (models.CarRental, error)
+	Create(rental *models.CarRental) error
+	Delete(id uint) error
+}
+
+// ReceiptRepository stores receipts. Get includes activities and payments; GetDeleted only
+// finds receipts in the trash.
+type ReceiptRepository interface {
+	// List returns one page of the receipts matching filter with their activities, and
+	// how many match in all.
+	List(filter ReceiptFilter, page Page) ([]models.Receipt, int64, error)
+	// Find returns every receipt matching filter with its activities, oldest first.
+	Find(filter ReceiptFilter) ([]models.Receipt, error)
+	// ListDeleted returns the trashed receipts with their activities, latest deleted first.
+	ListDeleted() ([]models.Receipt, error)
+	// ForClient returns the client's receipts with their activities, newest first.
+	ForClient(clientID uint) ([]models.Receipt, error)
+	Get(id uint) (models.Receipt, error)
+	GetDeleted(id uint) (models.Receipt, error)
+	// Create inserts the receipt and its activities.
+	Create(receipt *models.Receipt) error
+	// Update saves the receipt and replaces its activities; payments are left alone.
+	Update(receipt *models.Receipt) error
+	// AddPayment inserts the payment and brings its receipt's amount paid up to date.
+	AddPayment(payment *models.Payment) error
+	// Payments returns the receipt's payments, earliest first.
+	Payments(receiptID uint) ([]models.Payment, error)
+	GetPayment(receiptID, paymentID uint) (models.Payment, error)
+	// DeletePayment removes the payment and brings its receipt's amount paid up to date.
+	DeletePayment(payment models.Payment) error
+	Delete(id uint) error
+	Restore(id uint) error
+}
+
+// ProposalRepository stores proposals. Get includes the hotel, rooms and status history.
+type ProposalRepository interface {
+	List(status string) ([]models.Proposal, error)
+	// ListDeleted returns the trashed proposals with their hotel and rooms, latest deleted first.
+	ListDeleted() ([]models.Proposal, error)
+	// ForClient returns the client's proposals with their hotel, newest first.
+	ForClient(clientID uint) ([]models.Proposal, error)
+	Get(id uint) (models.Proposal, error)
+	GetDeleted(id uint) (models.Proposal, error)
+	// Create inserts the proposal and its rooms.
+	Create(proposal *models.Proposal) error
+	// Update saves the proposal and replaces its rooms.
+	Update(proposal *models.Proposal) error
+	// UpdateStatus moves the proposal from one status to another and returns ErrConflict
+	// when it is no longer in from.
+	UpdateStatus(id uint, from, to string) error
+	AddStatusChange(change *models.ProposalStatusChange) error
+	// LinkReceipt points the proposal at receiptID. It returns ErrConflict when the
+	// proposal already has a receipt or is gone.
+	LinkReceipt(id, receiptID uint) error
+	// UnlinkReceipt clears receiptID from every proposal that points at it.
+	UnlinkReceipt(receiptID uint) error
+	Delete(id uint) error
+	Restore(id uint) error
+}
+
+// ClientRepository stores clients. The Find methods return ErrNotFound when nothing
+// matches and skip the client with excludeID.
+type ClientRepository interface {
+	// List returns one page of the clients whose name or email contains text, or whose
+	// phone contains phone when it is set, and how many match in all.
+	List(text, phone string, page Page) ([]models.Client, int64, error)
+	Get(id uint) (models.Client, error)
+	FindByEmail(email string, excludeID uint) (models.Client, error)
+	// FindByPhoneAndName matches the phone exactly and the name ignoring case.
+	FindByPhoneAndName(phone, name string, excludeID uint) (models.Client, error)
+	Create(client *models.Client) error
+	Update(client *models.Client) error
+	// Delete removes the client and unlinks its receipts and proposals, trashed ones included.
+	Delete(id uint) error
+}
+
+type UserRepository interface {
+	List() ([]models.User, error)
+	Get(id uint) (models.User, error)
+	// FindByEmail returns the user other than excludeID with email, or ErrNotFound.
+	FindByEmail(email string, excludeID uint) (models.User, error)
+	Create(user *models.User) error
+	Update(user *models.User) error
+	Delete(id uint) error
+}
+
+// NumberRepository hands out document numbers.
+type NumberRepository interface {
+	// Next increments and returns the counter of sequence name in period. A new counter
+	// starts after the highest number already issued with stem. The counter stays locked
+	// until the transaction ends, so a rolled back document gives its number back.
+	Next(name, period, stem string) (int, error)
+}
+
+type ExchangeRateRepository interface {
+	List() ([]models.ExchangeRate, error)
+	// Replace drops every stored rate and stores rates instead.
+	Replace(rates []models.ExchangeRate) error
+}
+
+type AuditRepository interface {
+	Create(entry *models.AuditLog) error
+	// List returns up to limit entries matching filter, newest first.
+	List(filter AuditFilter, limit int) ([]models.AuditLog, error)
+}
+
+// SearchRepository finds receipts, activities and proposals by free text.
+type SearchRepository interface {
+	// Search returns up to limit hits of the given types (all when empty) that contain
+	// every term, best match first.
+	Search(terms, types []string, limit int) ([]models.SearchHit, error)
+}
+
+// Store hands out repositories that share one connection or transaction.
+type Store interface {
+	Hotels() HotelRepository
+	CarRentals() CarRentalRepository
+	Receipts() ReceiptRepository
+	Proposals() ProposalRepository
+	Clients() ClientRepository
+	Users() UserRepository
+	Numbers() NumberRepository
+	ExchangeRates() ExchangeRateRepository
+	Audit() AuditRepository
+	Search() SearchRepository
+	// Transaction runs fn against a store whose writes commit together when fn returns nil
+	// and are discarded otherwise.
+	Transaction(fn func(Store) error) error
+}
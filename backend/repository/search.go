@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"strings"
+
+	"github.com/Otabek228101/mehmon/database"
+	"github.com/Otabek228101/mehmon/models"
+	"gorm.io/gorm"
+)
+
+type gormSearch struct{ db *gorm.DB }
+
+// prefixQuery turns "una bar" into "una:* & bar:*" so partially typed words still match.
+func prefixQuery(terms []string) string {
+	parts := make([]string, 0, len(terms))
+	for _, term := range terms {
+		term = strings.Trim(term, ".-_")
+		if term != "" {
+			parts = append(parts, term+":*")
+		}
+	}
+	return strings.Join(parts, " & ")
+}
+
+func (r gormSearch) Search(terms, types []string, limit int) ([]models.SearchHit, error) {
+	hits := []models.SearchHit{}
+	if len(terms) == 0 {
+		return hits, nil
+	}
+
+	wanted := map[string]bool{}
+	for _, t := range types {
+		wanted[t] = true
+	}
+	include := func(t string) bool { return len(wanted) == 0 || wanted[t] }
+
+	var (
+		parts []string
+		args  []any
+	)
+	if r.db.Dialector.Name() == "postgres" {
+		query := prefixQuery(terms)
+		if query == "" {
+			return hits, nil
+		}
+		match := func(document string) string {
+			return database.SearchVector(document) + " @@ to_tsquery('simple', ?)"
+		}
+		rank := func(document string) string {
+			return "ts_rank(" + database.SearchVector(document) + ", to_tsquery('simple', ?))"
+		}
+		headline := func(document string) string {
+			return "ts_headline('simple', " + document + ", to_tsquery('simple', ?), 'MaxFragments=1, MaxWords=12, MinWords=4')"
+		}
+		if include(models.SearchHitReceipt) {
+			parts = append(parts, `SELECT 'receipt' AS type, receipts.id AS id, NULL AS receipt_id, receipts.receipt_number AS number,
+				receipts.client_name AS title, `+headline(database.ReceiptSearchDocument)+` AS snippet, `+rank(database.ReceiptSearchDocument)+` AS rank
+				FROM receipts WHERE receipts.deleted_at IS NULL AND `+match(database.ReceiptSearchDocument))
+			args = append(args, query, query, query)
+		}
+		if include(models.SearchHitActivity) {
+			parts = append(parts, `SELECT 'activity' AS type, activities.id AS id, activities.receipt_id AS receipt_id, receipts.receipt_number AS number,
+				COALESCE(NULLIF(activities.property_name, ''), activities.type) AS title, `+headline(database.ActivitySearchDocument)+` AS snippet, `+rank(database.ActivitySearchDocument)+` AS rank
+				FROM activities JOIN receipts ON receipts.id = activities.receipt_id
+				WHERE receipts.deleted_at IS NULL AND `+match(database.ActivitySearchDocument))
+			args = append(args, query, query, query)
+		}
+		if include(models.SearchHitProposal) {
+			parts = append(parts, `SELECT 'proposal' AS type, proposals.id AS id, NULL AS receipt_id, proposals.proposal_number AS number,
+				proposals.client_name AS title, `+headline(database.ProposalSearchDocument)+` AS snippet, `+rank(database.ProposalSearchDocument)+` AS rank
+				FROM proposals WHERE proposals.deleted_at IS NULL AND `+match(database.ProposalSearchDocument))
+			args = append(args, query, query, query)
+		}
+	} else {
+		// Databases without tsvector (SQLite in tests) get an unranked substring match over the same text.
+		like := func(document string) (string, []any) {
+			conds := make([]string, len(terms))
+			likeArgs := make([]any, len(terms))
+			for i, term := range terms {
+				conds[i] = "LOWER(" + document + `) LIKE ? ESCAPE '\'`
+				likeArgs[i] = "%" + likeLiteral(term) + "%"
+			}
+			return strings.Join(conds, " AND "), likeArgs
+		}
+		if include(models.SearchHitReceipt) {
+			cond, condArgs := like(database.ReceiptSearchDocument)
+			parts = append(parts, `SELECT 'receipt' AS type, receipts.id AS id, NULL AS receipt_id, receipts.receipt_number AS number,
+				receipts.client_name AS title, `+database.ReceiptSearchDocument+` AS snippet, 1.0 AS rank
+				FROM receipts WHERE receipts.deleted_at IS NULL AND `+cond)
+			args = append(args, condArgs...)
+		}
+		if include(models.SearchHitActivity) {
+			cond, condArgs := like(database.ActivitySearchDocument)
+			parts = append(parts, `SELECT 'activity' AS type, activities.id AS id, activities.receipt_id AS receipt_id, receipts.receipt_number AS number,
+				COALESCE(NULLIF(activities.property_name, ''), activities.type) AS title, `+database.ActivitySearchDocument+` AS snippet, 1.0 AS rank
+				FROM activities JOIN receipts ON receipts.id = activities.receipt_id
+				WHERE receipts.deleted_at IS NULL AND `+cond)
+			args = append(args, condArgs...)
+		}
+		if include(models.SearchHitProposal) {
+			cond, condArgs := like(database.ProposalSearchDocument)
+			parts = append(parts, `SELECT 'proposal' AS type, proposals.id AS id, NULL AS receipt_id, proposals.proposal_number AS number,
+				proposals.client_name AS title, `+database.ProposalSearchDocument+` AS snippet, 1.0 AS rank
+				FROM proposals WHERE proposals.deleted_at IS NULL AND `+cond)
+			args = append(args, condArgs...)
+		}
+	}
+	if len(parts) == 0 {
+		return hits, nil
+	}
+
+	sql := "SELECT * FROM (" + strings.Join(parts, " UNION ALL ") + ") AS hits ORDER BY rank DESC, type, id DESC LIMIT ?"
+	args = append(args, limit)
+	if err := r.db.Raw(sql, args...).Scan(&hits).Error; err != nil {
+		return nil, err
+	}
+	return hits, nil
+}
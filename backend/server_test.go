@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Otabek228101/mehmon/config"
+	"github.com/Otabek228101/mehmon/database"
+	"github.com/Otabek228101/mehmon/models"
+	"github.com/Otabek228101/mehmon/services"
+	"github.com/glebarez/sqlite"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const testPassword = "correct-horse"
+
+// testServer is the full API from setupRoutes on a private in-memory SQLite database,
+// signed in as an admin.
+type testServer struct {
+	t     *testing.T
+	app   *fiber.App
+	token string
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	cfg := config.Default()
+	cfg.JWTSecret = "integration-test-secret"
+	cfg.UploadDir = t.TempDir()
+	config.Current = cfg
+
+	// Foreign keys are enforced so deletes behave as they do on Postgres.
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared&_pragma=foreign_keys(1)", strings.ReplaceAll(t.Name(), "/", "_"))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("test database handle: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	database.DB = db
+	if _, err := database.MigrateUp(); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	app := fiber.New(fiber.Config{BodyLimit: cfg.BodyLimit()})
+	setupRoutes(app)
+	s := &testServer{t: t, app: app}
+
+	admin := s.createUser("admin@example.com", models.RoleAdmin)
+	var login struct {
+		Token string      `json:"token"`
+		User  models.User `json:"user"`
+	}
+	status := s.do(http.MethodPost, "/api/auth/login", fiber.Map{"email": admin.Email, "password": testPassword}, &login)
+	if status != http.StatusOK || login.Token == "" {
+		t.Fatalf("admin login: status %d", status)
+	}
+	s.token = login.Token
+	return s
+}
+
+func (s *testServer) createUser(email, role string) models.User {
+	s.t.Helper()
+	hash, err := services.HashPassword(testPassword)
+	if err != nil {
+		s.t.Fatalf("hash password: %v", err)
+	}
+	user := models.User{Email: email, Name: role, Role: role, PasswordHash: hash}
+	if err := database.DB.Create(&user).Error; err != nil {
+		s.t.Fatalf("create %s: %v", email, err)
+	}
+	return user
+}
+
+// as returns a client of the same server signed in as a new user with role.
+func (s *testServer) as(role string) *testServer {
+	s.t.Helper()
+	user := s.createUser(role+"@example.com", role)
+	token, _, err := services.IssueToken(user.ID)
+	if err != nil {
+		s.t.Fatalf("issue token: %v", err)
+	}
+	return &testServer{t: s.t, app: s.app, token: token}
+}
+
+// anonymous returns a client of the same server that sends no credentials.
+func (s *testServer) anonymous() *testServer {
+	return &testServer{t: s.t, app: s.app}
+}
+
+func (s *testServer) send(req *http.Request) *http.Response {
+	s.t.Helper()
+	if s.token != "" {
+		req.Header.Set(fiber.HeaderAuthorization, "Bearer "+s.token)
+	}
+	resp, err := s.app.Test(req, -1)
+	if err != nil {
+		s.t.Fatalf("%s %s: %v", req.Method, req.URL, err)
+	}
+	return resp
+}
+
+// raw sends a JSON body and returns the status, content type and undecoded response.
+func (s *testServer) raw(method, path string, body any) (int, string, []byte) {
+	s.t.Helper()
+	var payload io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			s.t.Fatalf("marshal body: %v", err)
+		}
+		payload = bytes.NewReader(b)
+	}
+	req := httptest.NewRequest(method, path, payload)
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp := s.send(req)
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		s.t.Fatalf("read %s %s: %v", method, path, err)
+	}
+	return resp.StatusCode, resp.Header.Get(fiber.HeaderContentType), data
+}
+
+// do sends a JSON body and decodes the JSON response into out when out is not nil.
+func (s *testServer) do(method, path string, body, out any) int {
+	s.t.Helper()
+	status, _, data := s.raw(method, path, body)
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			s.t.Fatalf("decode %s %s (%d): %v: %s", method, path, status, err, data)
+		}
+	}
+	return status
+}
+
+// expect is do for calls whose status is part of the test.
+func (s *testServer) expect(want int, method, path string, body, out any) {
+	s.t.Helper()
+	status, _, data := s.raw(method, path, body)
+	if status != want {
+		s.t.Fatalf("%s %s: status %d, want %d: %s", method, path, status, want, data)
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			s.t.Fatalf("decode %s %s: %v: %s", method, path, err, data)
+		}
+	}
+}
+
+// upload posts files as a multipart form under field.
+func (s *testServer) upload(path, field string, files map[string][]byte, out any) int {
+	s.t.Helper()
+	var buf bytes.Buffer
+	form := multipart.NewWriter(&buf)
+	for name, content := range files {
+		w, err := form.CreateFormFile(field, name)
+		if err != nil {
+			s.t.Fatalf("form file: %v", err)
+		}
+		w.Write(content)
+	}
+	form.Close()
+	req := httptest.NewRequest(http.MethodPost, path, &buf)
+	req.Header.Set(fiber.HeaderContentType, form.FormDataContentType())
+	resp := s.send(req)
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			s.t.Fatalf("decode upload: %v", err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// errorFields returns the field names of a validation failure response.
+func errorFields(body fiber.Map) []string {
+	raw, _ := body["fields"].([]any)
+	var fields []string
+	for _, f := range raw {
+		if m, ok := f.(map[string]any); ok {
+			fields = append(fields, fmt.Sprint(m["field"]))
+		}
+	}
+	return fields
+}
+
+func hasField(body fiber.Map, field string) bool {
+	for _, f := range errorFields(body) {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *testServer) createHotel(name, city string) models.Hotel {
+	s.t.Helper()
+	var hotel models.Hotel
+	s.expect(http.StatusCreated, http.MethodPost, "/api/hotels", fiber.Map{
+		"name": name, "city": city, "address": "Via Roma 1", "type": "hotel", "stars": 4,
+	}, &hotel)
+	return hotel
+}
+
+func (s *testServer) createReceipt(payload fiber.Map) models.Receipt {
+	s.t.Helper()
+	var receipt models.Receipt
+	s.expect(http.StatusCreated, http.MethodPost, "/api/receipts", payload, &receipt)
+	return receipt
+}
+
+func receiptPayload(name, date string, activities ...fiber.Map) fiber.Map {
+	return fiber.Map{
+		"clientName":  name,
+		"clientEmail": strings.ToLower(name) + "@example.com",
+		"clientPhone": "+998901234567",
+		"receiptDate": date,
+		"activities":  activities,
+	}
+}
+
+func hotelStay(name, checkIn, checkOut string, amount float64) fiber.Map {
+	return fiber.Map{
+		"type":         "hotel",
+		"propertyName": name,
+		"checkIn":      checkIn,
+		"checkOut":     checkOut,
+		"amount":       amount,
+	}
+}
+
+func carRental(rentalID uint) fiber.Map {
+	return fiber.Map{
+		"type":            "car_rental",
+		"carRentalId":     rentalID,
+		"pickupLocation":  "Airport",
+		"dropoffLocation": "Airport",
+		"description":     "Compact, 3 days",
+		"amount":          90,
+	}
+}
+
+func proposalPayload(hotelID uint, client string) fiber.Map {
+	return fiber.Map{
+		"clientName": client,
+		"guests":     2,
+		"checkIn":    "2026-06-01",
+		"checkOut":   "2026-06-04",
+		"price":      450,
+		"hotelId":    hotelID,
+		"rooms":      []fiber.Map{{"count": 1}},
+	}
+}
+
+func id(v uint) string {
+	return strconv.FormatUint(uint64(v), 10)
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/Otabek228101/mehmon/models"
+	"github.com/Otabek228101/mehmon/repository"
+)
+
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+)
+
+var auditIgnoredFields = map[string]bool{"createdAt": true, "updatedAt": true}
+
+// auditComparable drops row identity and timestamps from nested records, so children that
+// were deleted and re-inserted with the same content do not show up as changed.
+func auditComparable(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, val := range v {
+			if key == "id" || auditIgnoredFields[key] {
+				continue
+			}
+			out[key] = auditComparable(val)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, val := range v {
+			out[i] = auditComparable(val)
+		}
+		return out
+	}
+	return v
+}
+
+func auditJSON(v any) (models.JSONText, map[string]any, error) {
+	if v == nil {
+		return "", nil, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", nil, err
+	}
+	fields := map[string]any{}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		fields = nil
+	}
+	return models.JSONText(b), fields, nil
+}
+
+// AuditDiff lists the top-level fields that differ between two snapshots as {"field": {"before": x, "after": y}}.
+func AuditDiff(before, after map[string]any) map[string]any {
+	diff := map[string]any{}
+	for key, old := range before {
+		if auditIgnoredFields[key] {
+			continue
+		}
+		if cur, ok := after[key]; !ok || !reflect.DeepEqual(auditComparable(old), auditComparable(cur)) {
+			diff[key] = map[string]any{"before": old, "after": after[key]}
+		}
+	}
+	for key, cur := range after {
+		if _, ok := before[key]; !ok && !auditIgnoredFields[key] {
+			diff[key] = map[string]any{"before": nil, "after": cur}
+		}
+	}
+	return diff
+}
+
+// NewAuditEntry builds the audit entry for a change without storing it.
+func NewAuditEntry(actor *models.User, entity string, entityID uint, action string, before, after any) (models.AuditLog, error) {
+	beforeJSON, beforeFields, err := auditJSON(before)
+	if err != nil {
+		return models.AuditLog{}, err
+	}
+	afterJSON, afterFields, err := auditJSON(after)
+	if err != nil {
+		return models.AuditLog{}, err
+	}
+	diff, err := json.Marshal(AuditDiff(beforeFields, afterFields))
+	if err != nil {
+		return models.AuditLog{}, err
+	}
+
+	entry := models.AuditLog{
+		Entity:   entity,
+		EntityID: entityID,
+		Action:   action,
+		Before:   beforeJSON,
+		After:    afterJSON,
+		Diff:     models.JSONText(diff),
+	}
+	if actor != nil {
+		entry.ActorID = &actor.ID
+		entry.ActorEmail = actor.Email
+	}
+	return entry, nil
+}
+
+// storeAudit writes an audit entry in store, so inside a transaction it commits or rolls
+// back with the change it describes.
+func storeAudit(store repository.Store, actor *models.User, entity string, entityID uint, action string, before, after any) error {
+	entry, err := NewAuditEntry(actor, entity, entityID, action, before, after)
+	if err != nil {
+		return err
+	}
+	return store.Audit().Create(&entry)
+}
+
+// AuditService reads the audit log.
+type AuditService struct {
+	store repository.Store
+}
+
+func NewAuditService(store repository.Store) *AuditService {
+	return &AuditService{store: store}
+}
+
+// List returns up to limit entries matching filter, newest first.
+func (s *AuditService) List(filter repository.AuditFilter, limit int) ([]models.AuditLog, error) {
+	return s.store.Audit().List(filter, limit)
+}
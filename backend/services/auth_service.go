@@ -0,0 +1,141 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Otabek228101/mehmon/config"
+	"github.com/Otabek228101/mehmon/models"
+	"github.com/Otabek228101/mehmon/repository"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const TokenTTL = 24 * time.Hour
+
+// ErrNoSecret means tokens and receipt links cannot be signed because JWT_SECRET is empty.
+var ErrNoSecret = errors.New("JWT_SECRET is not set")
+
+// secret signs tokens and public receipt links. Config.Validate rejects short and placeholder
+// values at startup, so an empty secret means the configuration was never loaded.
+func secret() ([]byte, error) {
+	s := config.Current.JWTSecret
+	if s == "" {
+		return nil, ErrNoSecret
+	}
+	return []byte(s), nil
+}
+
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+func IssueToken(userID uint) (string, time.Time, error) {
+	key, err := secret()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	expiresAt := time.Now().Add(TokenTTL)
+	claims := jwt.RegisteredClaims{
+		Subject:   strconv.FormatUint(uint64(userID), 10),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(key)
+	return token, expiresAt, err
+}
+
+// ParseToken validates a token issued by IssueToken and returns the user ID it was issued for.
+func ParseToken(token string) (uint, error) {
+	key, err := secret()
+	if err != nil {
+		return 0, err
+	}
+	var claims jwt.RegisteredClaims
+	_, err = jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (interface{}, error) {
+		return key, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return 0, err
+	}
+	id, err := strconv.ParseUint(claims.Subject, 10, 64)
+	if err != nil || id == 0 {
+		return 0, errors.New("invalid token subject")
+	}
+	return uint(id), nil
+}
+
+// ErrInvalidCredentials rejects a login with an unknown email or a wrong password.
+var ErrInvalidCredentials = errors.New("invalid email or password")
+
+// AuthService signs users in and finds the user a token was issued for.
+type AuthService struct {
+	store repository.Store
+}
+
+func NewAuthService(store repository.Store) *AuthService {
+	return &AuthService{store: store}
+}
+
+// Login checks the password of the user with email and issues them a token.
+func (s *AuthService) Login(email, password string) (models.User, string, time.Time, error) {
+	user, err := s.store.Users().FindByEmail(strings.ToLower(strings.TrimSpace(email)), 0)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && !CheckPassword(user.PasswordHash, password)) {
+		return models.User{}, "", time.Time{}, ErrInvalidCredentials
+	}
+	if err != nil {
+		return models.User{}, "", time.Time{}, err
+	}
+	token, expiresAt, err := IssueToken(user.ID)
+	return user, token, expiresAt, err
+}
+
+// Authenticate returns the user token was issued for.
+func (s *AuthService) Authenticate(token string) (models.User, error) {
+	id, err := ParseToken(token)
+	if err != nil {
+		return models.User{}, err
+	}
+	return s.store.Users().Get(id)
+}
+
+func SignReceiptLink(receiptID uint) (string, error) {
+	key, err := secret()
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("receipt:" + strconv.FormatUint(uint64(receiptID), 10)))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// VerifyReceiptLink reports whether signature is the one SignReceiptLink gives receiptID.
+// The error is only set when links cannot be signed at all.
+func VerifyReceiptLink(receiptID uint, signature string) (bool, error) {
+	sig, err := SignReceiptLink(receiptID)
+	if err != nil {
+		return false, err
+	}
+	expected, err := hex.DecodeString(sig)
+	if err != nil {
+		return false, err
+	}
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return false, nil
+	}
+	return hmac.Equal(expected, got), nil
+}
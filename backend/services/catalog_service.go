@@ -0,0 +1,149 @@
+package services
+
+import (
+	"github.com/Otabek228101/mehmon/models"
+	"github.com/Otabek228101/mehmon/repository"
+	"github.com/Otabek228101/mehmon/validation"
+)
+
+// HotelService manages catalog hotels. Missing hotels come back as repository.ErrNotFound
+// and invalid requests as validation.Errors.
+type HotelService struct {
+	store repository.Store
+}
+
+func NewHotelService(store repository.Store) *HotelService {
+	return &HotelService{store: store}
+}
+
+func applyHotelRequest(hotel *models.Hotel, req models.CreateHotelRequest) {
+	hotel.Name = req.Name
+	hotel.City = req.City
+	hotel.GroupName = req.GroupName
+	hotel.Type = req.Type
+	hotel.Stars = req.Stars
+	hotel.Address = req.Address
+	hotel.LocationLink = req.LocationLink
+	hotel.WebsiteLink = req.WebsiteLink
+	hotel.Breakfast = req.Breakfast
+}
+
+func (s *HotelService) List(city string) ([]models.Hotel, error) {
+	return s.store.Hotels().List(city)
+}
+
+func (s *HotelService) Get(id uint) (models.Hotel, error) {
+	return s.store.Hotels().Get(id)
+}
+
+func (s *HotelService) Create(actor *models.User, req models.CreateHotelRequest) (models.Hotel, error) {
+	var hotel models.Hotel
+	if err := req.Validate(); err != nil {
+		return hotel, err
+	}
+	applyHotelRequest(&hotel, req)
+	err := s.store.Transaction(func(tx repository.Store) error {
+		if err := tx.Hotels().Create(&hotel); err != nil {
+			return err
+		}
+		return storeAudit(tx, actor, "hotel", hotel.ID, AuditCreate, nil, hotel)
+	})
+	return hotel, err
+}
+
+func (s *HotelService) Update(actor *models.User, id uint, req models.CreateHotelRequest) (models.Hotel, error) {
+	hotel, err := s.store.Hotels().Get(id)
+	if err != nil {
+		return hotel, err
+	}
+	if err := req.Validate(); err != nil {
+		return hotel, err
+	}
+	before := hotel
+	applyHotelRequest(&hotel, req)
+	err = s.store.Transaction(func(tx repository.Store) error {
+		if err := tx.Hotels().Update(&hotel); err != nil {
+			return err
+		}
+		return storeAudit(tx, actor, "hotel", hotel.ID, AuditUpdate, before, hotel)
+	})
+	return hotel, err
+}
+
+func (s *HotelService) Delete(actor *models.User, id uint) error {
+	hotel, err := s.store.Hotels().Get(id)
+	if err != nil {
+		return err
+	}
+	return s.store.Transaction(func(tx repository.Store) error {
+		if err := tx.Hotels().Delete(hotel.ID); err != nil {
+			return err
+		}
+		return storeAudit(tx, actor, "hotel", hotel.ID, AuditDelete, hotel, nil)
+	})
+}
+
+// Images returns the hotel's images in display order, at most limit of them when limit
+// is positive.
+func (s *HotelService) Images(id uint, limit int) ([]models.HotelImage, error) {
+	if _, err := s.store.Hotels().Get(id); err != nil {
+		return nil, err
+	}
+	return s.store.Hotels().Images(id, nil, limit)
+}
+
+// LastImageSort returns the highest sort order of the hotel's images, 0 without any.
+func (s *HotelService) LastImageSort(id uint) (int, error) {
+	return s.store.Hotels().LastImageSort(id)
+}
+
+// AddImage records an image already saved under the upload directory.
+func (s *HotelService) AddImage(actor *models.User, image *models.HotelImage) error {
+	return s.store.Transaction(func(tx repository.Store) error {
+		if err := tx.Hotels().AddImage(image); err != nil {
+			return err
+		}
+		return storeAudit(tx, actor, "hotel_image", image.ID, AuditCreate, nil, *image)
+	})
+}
+
+type CarRentalService struct {
+	store repository.Store
+}
+
+func NewCarRentalService(store repository.Store) *CarRentalService {
+	return &CarRentalService{store: store}
+}
+
+func (s *CarRentalService) List() ([]models.CarRental, error) {
+	return s.store.CarRentals().List()
+}
+
+func (s *CarRentalService) Create(actor *models.User, rental models.CarRental) (models.CarRental, error) {
+	v := validation.New()
+	v.Required("name", rental.Name)
+	if err := v.Err(); err != nil {
+		return rental, err
+	}
+	rental.ID = 0
+	err := s.store.Transaction(func(tx repository.Store) error {
+		if err := tx.CarRentals().Create(&rental); err != nil {
+			return err
+		}
+		return storeAudit(tx, actor, "car_rental", rental.ID, AuditCreate, nil, rental)
+	})
+	return rental, err
+}
+
+func (s *CarRentalService) Delete(actor *models.User, id uint) error {
+	rental, err := s.store.CarRentals().Get(id)
+	if err != nil {
+		return err
+	}
+	return s.store.Transaction(func(tx repository.Store) error {
+		if err := tx.CarRentals().Delete(rental.ID); err != nil {
+			return err
+		}
+		return storeAudit(tx, actor, "car_rental", rental.ID, AuditDelete, rental, nil)
+	})
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Otabek228101/mehmon/models"
+	"github.com/Otabek228101/mehmon/repository"
+	"github.com/Otabek228101/mehmon/repository/memory"
+	"github.com/Otabek228101/mehmon/validation"
+)
+
+var testActor = &models.User{ID: 7, Email: "agent@example.com"}
+
+func hotelRequest(name string) models.CreateHotelRequest {
+	return models.CreateHotelRequest{Name: name, City: "BARI", Address: "Via Roma 1", Type: "hotel", Stars: 4}
+}
+
+func TestHotelServiceAuditsChanges(t *testing.T) {
+	store := memory.NewStore()
+	hotels := NewHotelService(store)
+
+	hotel, err := hotels.Create(testActor, hotelRequest("Palazzo"))
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := hotels.Update(testActor, hotel.ID, hotelRequest("Palazzo Nuovo")); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if err := hotels.Delete(testActor, hotel.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := hotels.Get(hotel.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("get after delete: err = %v, want ErrNotFound", err)
+	}
+
+	log := store.AuditLog()
+	want := []string{AuditCreate, AuditUpdate, AuditDelete}
+	if len(log) != len(want) {
+		t.Fatalf("audit entries = %d, want %d", len(log), len(want))
+	}
+	for i, entry := range log {
+		if entry.Action != want[i] || entry.Entity != "hotel" || entry.EntityID != hotel.ID {
+			t.Fatalf("entry %d = %s %s %d, want %s hotel %d", i, entry.Action, entry.Entity, entry.EntityID, want[i], hotel.ID)
+		}
+		if entry.ActorID == nil || *entry.ActorID != testActor.ID {
+			t.Fatalf("entry %d actor = %v, want %d", i, entry.ActorID, testActor.ID)
+		}
+	}
+	if log[1].Diff != `{"name":{"after":"Palazzo Nuovo","before":"Palazzo"}}` {
+		t.Fatalf("update diff = %s", log[1].Diff)
+	}
+}
+
+func TestHotelServiceRejectsInvalidRequest(t *testing.T) {
+	store := memory.NewStore()
+	hotels := NewHotelService(store)
+
+	req := hotelRequest("")
+	req.Stars = 9
+	_, err := hotels.Create(testActor, req)
+	var fields validation.Errors
+	if !errors.As(err, &fields) || len(fields) != 2 {
+		t.Fatalf("err = %v, want name and stars field errors", err)
+	}
+	if list, _ := hotels.List(""); len(list) != 0 {
+		t.Fatalf("hotels = %d, want none", len(list))
+	}
+	if _, err := hotels.Update(testActor, 42, hotelRequest("Palazzo")); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("update missing: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestCarRentalServiceRollsBackWhenAuditFails(t *testing.T) {
+	store := memory.NewStore()
+	rentals := NewCarRentalService(store)
+	store.FailAudit = errors.New("audit unavailable")
+
+	if _, err := rentals.Create(testActor, models.CarRental{Name: "Sixt"}); err == nil {
+		t.Fatal("create succeeded without its audit entry")
+	}
+	if list, _ := rentals.List(); len(list) != 0 {
+		t.Fatalf("car rentals = %d, want 0 after rollback", len(list))
+	}
+
+	store.FailAudit = nil
+	rental, err := rentals.Create(testActor, models.CarRental{Name: "Sixt"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := rentals.Delete(testActor, rental.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if err := rentals.Delete(testActor, rental.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("second delete: err = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,260 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+
+	"github.com/Otabek228101/mehmon/models"
+	"github.com/Otabek228101/mehmon/repository"
+	"github.com/Otabek228101/mehmon/validation"
+	"gorm.io/gorm"
+)
+
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// NormalizePhone keeps the digits of phone and a leading +.
+func NormalizePhone(phone string) string {
+	phone = strings.TrimSpace(phone)
+	var b strings.Builder
+	for i, r := range phone {
+		if unicode.IsDigit(r) || (r == '+' && i == 0) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// FindClient returns the client with the same email or, failing that, the same phone and
+// name, ignoring excludeID. A phone alone is not enough since it may be shared by several
+// people, such as an office line. It returns nil when there is no such client.
+func FindClient(store repository.Store, name, email, phone string, excludeID uint) (*models.Client, error) {
+	email, phone = NormalizeEmail(email), NormalizePhone(phone)
+	name = strings.TrimSpace(name)
+
+	if email != "" {
+		client, err := store.Clients().FindByEmail(email, excludeID)
+		if err == nil {
+			return &client, nil
+		}
+		if !errors.Is(err, repository.ErrNotFound) {
+			return nil, err
+		}
+	}
+	if phone == "" || name == "" {
+		return nil, nil
+	}
+	client, err := store.Clients().FindByPhoneAndName(phone, name, excludeID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
+
+// ResolveClient returns the client matching email, or phone and name, filling in contact
+// details it was missing, or creates one. created reports whether a new client was inserted.
+// Without an email or phone no client is resolved.
+func ResolveClient(store repository.Store, name, email, phone string) (client *models.Client, created bool, err error) {
+	email, phone = NormalizeEmail(email), NormalizePhone(phone)
+	if email == "" && phone == "" {
+		return nil, false, nil
+	}
+	client, err = FindClient(store, name, email, phone, 0)
+	if err != nil {
+		return nil, false, err
+	}
+	if client == nil {
+		client = &models.Client{Name: strings.TrimSpace(name), Email: email, Phone: phone}
+		return client, true, store.Clients().Create(client)
+	}
+
+	changed := false
+	if client.Email == "" && email != "" {
+		client.Email, changed = email, true
+	}
+	if client.Phone == "" && phone != "" {
+		client.Phone, changed = phone, true
+	}
+	if changed {
+		if err := store.Clients().Update(client); err != nil {
+			return nil, false, err
+		}
+	}
+	return client, false, nil
+}
+
+// pickedClient loads the client picked by id in a request, reporting a field error under
+// field when it does not exist.
+func pickedClient(store repository.Store, id uint, field string) (models.Client, error) {
+	client, err := store.Clients().Get(id)
+	if errors.Is(err, repository.ErrNotFound) {
+		v := validation.New()
+		v.Add(field, validation.CodeNotFound, "client does not exist")
+		return client, v.Err()
+	}
+	return client, err
+}
+
+// ClientConflictError rejects a client whose email, or name and phone, belong to Client.
+type ClientConflictError struct {
+	Client models.Client
+}
+
+func (e *ClientConflictError) Error() string {
+	return "a client with this email, or this name and phone, already exists"
+}
+
+// ClientService manages the client directory. Missing clients come back as
+// repository.ErrNotFound, bad requests as validation.Errors and duplicates as
+// *ClientConflictError.
+type ClientService struct {
+	store repository.Store
+}
+
+func NewClientService(store repository.Store) *ClientService {
+	return &ClientService{store: store}
+}
+
+// List returns one page of the clients whose name, email or phone contains q, and how many
+// match in all.
+func (s *ClientService) List(q string, page repository.Page) ([]models.Client, int64, error) {
+	q = strings.TrimSpace(q)
+	phone := ""
+	if q != "" {
+		phone = NormalizePhone(q)
+	}
+	return s.store.Clients().List(q, phone, page)
+}
+
+func (s *ClientService) Get(id uint) (models.Client, error) {
+	return s.store.Clients().Get(id)
+}
+
+// apply checks req and copies it onto client, normalizing the contact details.
+func (s *ClientService) apply(client *models.Client, req models.ClientRequest) error {
+	if err := req.Validate(); err != nil {
+		return err
+	}
+	existing, err := FindClient(s.store, req.Name, req.Email, req.Phone, client.ID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return &ClientConflictError{Client: *existing}
+	}
+	client.Name = strings.TrimSpace(req.Name)
+	client.Email = NormalizeEmail(req.Email)
+	client.Phone = NormalizePhone(req.Phone)
+	client.Notes = req.Notes
+	return nil
+}
+
+func (s *ClientService) Create(actor *models.User, req models.ClientRequest) (models.Client, error) {
+	var client models.Client
+	if err := s.apply(&client, req); err != nil {
+		return client, err
+	}
+	err := s.store.Transaction(func(tx repository.Store) error {
+		if err := tx.Clients().Create(&client); err != nil {
+			return err
+		}
+		return storeAudit(tx, actor, "client", client.ID, AuditCreate, nil, client)
+	})
+	return client, err
+}
+
+func (s *ClientService) Update(actor *models.User, id uint, req models.ClientRequest) (models.Client, error) {
+	client, err := s.store.Clients().Get(id)
+	if err != nil {
+		return client, err
+	}
+	before := client
+	if err := s.apply(&client, req); err != nil {
+		return client, err
+	}
+	err = s.store.Transaction(func(tx repository.Store) error {
+		if err := tx.Clients().Update(&client); err != nil {
+			return err
+		}
+		return storeAudit(tx, actor, "client", client.ID, AuditUpdate, before, client)
+	})
+	return client, err
+}
+
+// Delete removes the client; its receipts and proposals keep their copied contact details.
+func (s *ClientService) Delete(actor *models.User, id uint) error {
+	client, err := s.store.Clients().Get(id)
+	if err != nil {
+		return err
+	}
+	return s.store.Transaction(func(tx repository.Store) error {
+		if err := tx.Clients().Delete(client.ID); err != nil {
+			return err
+		}
+		return storeAudit(tx, actor, "client", client.ID, AuditDelete, client, nil)
+	})
+}
+
+// ClientHistory is a client's receipts and proposals, newest first, with what the receipts
+// have been paid in each currency.
+type ClientHistory struct {
+	Client    models.Client
+	Receipts  []models.Receipt
+	Proposals []models.Proposal
+	Paid      []CurrencySum
+}
+
+func (s *ClientService) History(id uint) (ClientHistory, error) {
+	var history ClientHistory
+	var err error
+	if history.Client, err = s.store.Clients().Get(id); err != nil {
+		return history, err
+	}
+	if history.Receipts, err = s.store.Receipts().ForClient(id); err != nil {
+		return history, err
+	}
+	if history.Proposals, err = s.store.Proposals().ForClient(id); err != nil {
+		return history, err
+	}
+	paid := map[string]models.Money{}
+	for _, receipt := range history.Receipts {
+		paid[receipt.Currency] = paid[receipt.Currency].Add(receipt.AmountPaid)
+	}
+	history.Paid = currencySums(paid)
+	return history, nil
+}
+
+// LinkClients attaches receipts that have no client to one matched or created from their
+// contact details, then links proposals to the client of the receipt they were converted into.
+func LinkClients(db *gorm.DB) (int, error) {
+	var receipts []models.Receipt
+	if err := db.Unscoped().Where("client_id IS NULL").Order("id").Find(&receipts).Error; err != nil {
+		return 0, err
+	}
+	linked := 0
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, receipt := range receipts {
+			client, _, err := ResolveClient(repository.NewGormStore(tx), receipt.ClientName, receipt.ClientEmail, receipt.ClientPhone)
+			if err != nil {
+				return err
+			}
+			if client == nil {
+				continue
+			}
+			if err := tx.Unscoped().Model(&models.Receipt{}).Where("id = ?", receipt.ID).Update("client_id", client.ID).Error; err != nil {
+				return err
+			}
+			linked++
+		}
+		return tx.Exec(
+			"UPDATE proposals SET client_id = (SELECT receipts.client_id FROM receipts WHERE receipts.id = proposals.receipt_id) " +
+				"WHERE client_id IS NULL AND receipt_id IS NOT NULL",
+		).Error
+	})
+	return linked, err
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Otabek228101/mehmon/models"
+	"github.com/Otabek228101/mehmon/repository"
+	"github.com/Otabek228101/mehmon/repository/memory"
+)
+
+func TestClientServiceRejectsDuplicates(t *testing.T) {
+	store := memory.NewStore()
+	clients := NewClientService(store)
+
+	ann, err := clients.Create(testActor, models.ClientRequest{Name: "Ann", Email: "Ann@Example.com", Phone: "+39 333 1234567"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	_, err = clients.Create(testActor, models.ClientRequest{Name: "Anna", Email: "ann@example.com"})
+	var conflict *ClientConflictError
+	if !errors.As(err, &conflict) || conflict.Client.ID != ann.ID {
+		t.Fatalf("duplicate email: err = %v, want a conflict with client %d", err, ann.ID)
+	}
+	if _, err := clients.Update(testActor, ann.ID, models.ClientRequest{Name: "Ann", Email: "ann@example.com"}); err != nil {
+		t.Fatalf("update keeping own email: %v", err)
+	}
+}
+
+func TestClientServiceDeleteUnlinksDocuments(t *testing.T) {
+	store := memory.NewStore()
+	clients := NewClientService(store)
+	client := store.PutClient(models.Client{Name: "Ann"})
+	receipt := store.PutReceipt(models.Receipt{ReceiptNumber: "R00001", ClientName: "Ann", ClientID: &client.ID})
+	proposal := store.PutProposal(models.Proposal{ProposalNumber: "P00001", ClientName: "Ann", ClientID: &client.ID})
+
+	if err := clients.Delete(testActor, client.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := clients.Get(client.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("get after delete: err = %v, want ErrNotFound", err)
+	}
+	if got, err := store.Receipts().Get(receipt.ID); err != nil || got.ClientID != nil || got.ClientName != "Ann" {
+		t.Fatalf("receipt = %+v, %v; want it kept without a client", got, err)
+	}
+	if got, err := store.Proposals().Get(proposal.ID); err != nil || got.ClientID != nil {
+		t.Fatalf("proposal = %+v, %v; want it kept without a client", got, err)
+	}
+}
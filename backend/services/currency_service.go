@@ -0,0 +1,143 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/Otabek228101/mehmon/config"
+	"github.com/Otabek228101/mehmon/models"
+	"github.com/Otabek228101/mehmon/repository"
+)
+
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+func BaseCurrency() string {
+	return config.Current.BaseCurrency
+}
+
+func NormalizeCurrency(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Rates holds how many units of each currency equal one unit of the base currency.
+type Rates map[string]float64
+
+func loadRates(store repository.Store) (Rates, error) {
+	stored, err := store.ExchangeRates().List()
+	if err != nil {
+		return nil, err
+	}
+	return NewRates(stored), nil
+}
+
+// NewRates builds the rate table from stored rates; the base currency is always 1.
+func NewRates(stored []models.ExchangeRate) Rates {
+	rates := Rates{BaseCurrency(): 1}
+	for _, r := range stored {
+		if r.Rate > 0 {
+			rates[r.Currency] = r.Rate
+		}
+	}
+	return rates
+}
+
+func (r Rates) Supports(code string) bool {
+	_, ok := r[code]
+	return ok
+}
+
+func (r Rates) Convert(amount models.Money, from, to string) (models.Money, error) {
+	if from == to {
+		return amount, nil
+	}
+	fromRate, ok := r[from]
+	if !ok {
+		return models.Money{}, fmt.Errorf("no exchange rate for %s", from)
+	}
+	toRate, ok := r[to]
+	if !ok {
+		return models.Money{}, fmt.Errorf("no exchange rate for %s", to)
+	}
+	return models.MoneyFromFloat(amount.Float64() / fromRate * toRate), nil
+}
+
+// ResolveCurrency normalizes code, falling back to fallback when empty, and checks a rate exists for it.
+func ResolveCurrency(rates Rates, code, fallback string) (string, error) {
+	code = NormalizeCurrency(code)
+	if code == "" {
+		code = fallback
+	}
+	if !currencyCode.MatchString(code) {
+		return "", fmt.Errorf("invalid currency code %q", code)
+	}
+	if !rates.Supports(code) {
+		return "", fmt.Errorf("unsupported currency %s", code)
+	}
+	return code, nil
+}
+
+type ratesFile struct {
+	Base  string             `json:"base"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+// CurrencyService reads the stored exchange rates and reloads them from a rates file.
+type CurrencyService struct {
+	store repository.Store
+}
+
+func NewCurrencyService(store repository.Store) *CurrencyService {
+	return &CurrencyService{store: store}
+}
+
+func (s *CurrencyService) Rates() (Rates, error) {
+	return loadRates(s.store)
+}
+
+// LoadFile replaces the stored rates with the ones in a JSON file of the form
+// {"base": "USD", "rates": {"EUR": 0.92, "UZS": 12650}}. Rates quoted against a different
+// base are rebased onto BaseCurrency.
+func (s *CurrencyService) LoadFile(path string) (int, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	var file ratesFile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return 0, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	base := BaseCurrency()
+	fileBase := NormalizeCurrency(file.Base)
+	if fileBase == "" {
+		fileBase = base
+	}
+	rates := map[string]float64{fileBase: 1}
+	for code, rate := range file.Rates {
+		code = NormalizeCurrency(code)
+		if !currencyCode.MatchString(code) || rate <= 0 {
+			return 0, fmt.Errorf("invalid rate %q: %v", code, rate)
+		}
+		rates[code] = rate
+	}
+	baseRate, ok := rates[base]
+	if !ok {
+		return 0, fmt.Errorf("%s does not include a rate for base currency %s", path, base)
+	}
+
+	stored := make([]models.ExchangeRate, 0, len(rates))
+	for code, rate := range rates {
+		if code == base {
+			continue
+		}
+		stored = append(stored, models.ExchangeRate{Currency: code, Rate: rate / baseRate, Source: path})
+	}
+
+	err = s.store.Transaction(func(tx repository.Store) error {
+		return tx.ExchangeRates().Replace(stored)
+	})
+	return len(stored), err
+}
@@ -0,0 +1,286 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Otabek228101/mehmon/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const exportVersion = 1
+
+// Export is a portable copy of the catalog and documents, including documents in the trash.
+// Users and the audit log are left out: they hold password hashes and history that belong
+// to the installation rather than the data.
+type Export struct {
+	Version       int                   `json:"version"`
+	ExportedAt    time.Time             `json:"exportedAt"`
+	Hotels        []models.Hotel        `json:"hotels"`
+	CarRentals    []models.CarRental    `json:"carRentals"`
+	Clients       []models.Client       `json:"clients"`
+	Receipts      []models.Receipt      `json:"receipts"`
+	Proposals     []models.Proposal     `json:"proposals"`
+	ExchangeRates []models.ExchangeRate `json:"exchangeRates"`
+}
+
+func ExportData(db *gorm.DB) (*Export, error) {
+	data := Export{Version: exportVersion, ExportedAt: time.Now().UTC()}
+	queries := []struct {
+		query *gorm.DB
+		dest  any
+	}{
+		{db.Preload("Images", func(q *gorm.DB) *gorm.DB { return q.Order("sort_order, id") }).Order("id"), &data.Hotels},
+		{db.Order("id"), &data.CarRentals},
+		{db.Order("id"), &data.Clients},
+		{db.Unscoped().Preload("Activities", func(q *gorm.DB) *gorm.DB { return q.Order("id") }).
+			Preload("Payments", func(q *gorm.DB) *gorm.DB { return q.Order("id") }).Order("id"), &data.Receipts},
+		{db.Unscoped().Preload("Rooms", func(q *gorm.DB) *gorm.DB { return q.Order("id") }).
+			Preload("StatusHistory", func(q *gorm.DB) *gorm.DB { return q.Order("id") }).Order("id"), &data.Proposals},
+		{db.Order("currency"), &data.ExchangeRates},
+	}
+	for _, q := range queries {
+		if err := q.query.Find(q.dest).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &data, nil
+}
+
+// ImportCounts reports how many top-level records an import wrote.
+type ImportCounts struct {
+	Hotels, CarRentals, Clients, Receipts, Proposals, ExchangeRates int
+}
+
+// NumberClash is a receipt or proposal number that an import would give to more than one
+// record.
+type NumberClash struct {
+	Entity string
+	Number string
+	IDs    []uint
+}
+
+// NumberClashError stops an import before anything is written when imported receipts or
+// proposals would share a number with each other or with a different record already here.
+type NumberClashError struct {
+	Clashes []NumberClash
+}
+
+func (e *NumberClashError) Error() string {
+	parts := make([]string, len(e.Clashes))
+	for i, clash := range e.Clashes {
+		ids := make([]string, len(clash.IDs))
+		for j, id := range clash.IDs {
+			ids[j] = fmt.Sprint(id)
+		}
+		parts[i] = fmt.Sprintf("%s %s would be used by ids %s", clash.Entity, clash.Number, strings.Join(ids, ", "))
+	}
+	return "numbers clash with other records: " + strings.Join(parts, "; ")
+}
+
+// numberClashes finds the numbers of imported that table would hold more than once after
+// the import overwrites its records by id. Trashed records count: they keep their number.
+func numberClashes(tx *gorm.DB, entity, table, column string, imported map[uint]string) ([]NumberClash, error) {
+	var rows []struct {
+		ID     uint
+		Number string
+	}
+	if err := tx.Table(table).Select("id, " + column + " AS number").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	final := map[uint]string{}
+	for _, row := range rows {
+		final[row.ID] = row.Number
+	}
+	wanted := map[string]bool{}
+	for id, number := range imported {
+		final[id] = number
+		wanted[number] = true
+	}
+	holders := map[string][]uint{}
+	for id, number := range final {
+		if wanted[number] {
+			holders[number] = append(holders[number], id)
+		}
+	}
+	var clashes []NumberClash
+	for number, ids := range holders {
+		if len(ids) > 1 {
+			slices.Sort(ids)
+			clashes = append(clashes, NumberClash{Entity: entity, Number: number, IDs: ids})
+		}
+	}
+	slices.SortFunc(clashes, func(a, b NumberClash) int { return strings.Compare(a.Number, b.Number) })
+	return clashes, nil
+}
+
+// checkNumbers returns a *NumberClashError listing every receipt and proposal number the
+// import would duplicate.
+func checkNumbers(tx *gorm.DB, data *Export) error {
+	receipts := map[uint]string{}
+	for _, receipt := range data.Receipts {
+		receipts[receipt.ID] = receipt.ReceiptNumber
+	}
+	proposals := map[uint]string{}
+	for _, proposal := range data.Proposals {
+		proposals[proposal.ID] = proposal.ProposalNumber
+	}
+	clashes, err := numberClashes(tx, "receipt", "receipts", "receipt_number", receipts)
+	if err != nil {
+		return err
+	}
+	proposalClashes, err := numberClashes(tx, "proposal", "proposals", "proposal_number", proposals)
+	if err != nil {
+		return err
+	}
+	clashes = append(clashes, proposalClashes...)
+	if len(clashes) > 0 {
+		return &NumberClashError{Clashes: clashes}
+	}
+	return nil
+}
+
+// ImportData writes an export into db inside tx, keeping record ids so links between
+// records survive. Records whose id already exists are overwritten, and their activities,
+// payments, images, rooms and status history are replaced by the imported ones, which get
+// fresh ids. Nothing is written when receipt or proposal numbers would clash.
+func ImportData(tx *gorm.DB, data *Export) (ImportCounts, error) {
+	var counts ImportCounts
+	if data.Version != exportVersion {
+		return counts, fmt.Errorf("unsupported export version %d", data.Version)
+	}
+	if err := checkNumbers(tx, data); err != nil {
+		return counts, err
+	}
+	upsert := func(value any) *gorm.DB {
+		return tx.Clauses(clause.OnConflict{UpdateAll: true}).Omit(clause.Associations).Create(value)
+	}
+
+	for _, hotel := range data.Hotels {
+		if err := upsert(&hotel).Error; err != nil {
+			return counts, fmt.Errorf("hotel %d: %w", hotel.ID, err)
+		}
+		err := replaceChildren(tx, &models.HotelImage{}, "hotel_id", hotel.ID, hotel.Images, func(image *models.HotelImage) {
+			image.ID, image.HotelID = 0, hotel.ID
+		})
+		if err != nil {
+			return counts, fmt.Errorf("hotel %d images: %w", hotel.ID, err)
+		}
+		counts.Hotels++
+	}
+	for _, rental := range data.CarRentals {
+		if err := upsert(&rental).Error; err != nil {
+			return counts, fmt.Errorf("car rental %d: %w", rental.ID, err)
+		}
+		counts.CarRentals++
+	}
+	for _, client := range data.Clients {
+		if err := upsert(&client).Error; err != nil {
+			return counts, fmt.Errorf("client %d: %w", client.ID, err)
+		}
+		counts.Clients++
+	}
+	for _, receipt := range data.Receipts {
+		receipt.Currency = currencyOrBase(receipt.Currency)
+		for i := range receipt.Activities {
+			receipt.Activities[i].Currency = currencyOrBase(receipt.Activities[i].Currency)
+		}
+		if err := upsert(&receipt).Error; err != nil {
+			return counts, fmt.Errorf("receipt %s: %w", receipt.ReceiptNumber, err)
+		}
+		err := replaceChildren(tx, &models.Activity{}, "receipt_id", receipt.ID, receipt.Activities, func(activity *models.Activity) {
+			activity.ID, activity.ReceiptID = 0, receipt.ID
+		})
+		if err != nil {
+			return counts, fmt.Errorf("receipt %s activities: %w", receipt.ReceiptNumber, err)
+		}
+		err = replaceChildren(tx, &models.Payment{}, "receipt_id", receipt.ID, receipt.Payments, func(payment *models.Payment) {
+			payment.ID, payment.ReceiptID = 0, receipt.ID
+		})
+		if err != nil {
+			return counts, fmt.Errorf("receipt %s payments: %w", receipt.ReceiptNumber, err)
+		}
+		counts.Receipts++
+	}
+	for _, proposal := range data.Proposals {
+		proposal.Hotel = nil
+		proposal.Currency = currencyOrBase(proposal.Currency)
+		if err := upsert(&proposal).Error; err != nil {
+			return counts, fmt.Errorf("proposal %s: %w", proposal.ProposalNumber, err)
+		}
+		err := replaceChildren(tx, &models.ProposalRoom{}, "proposal_id", proposal.ID, proposal.Rooms, func(room *models.ProposalRoom) {
+			room.ID, room.ProposalID = 0, proposal.ID
+		})
+		if err != nil {
+			return counts, fmt.Errorf("proposal %s rooms: %w", proposal.ProposalNumber, err)
+		}
+		err = replaceChildren(tx, &models.ProposalStatusChange{}, "proposal_id", proposal.ID, proposal.StatusHistory, func(change *models.ProposalStatusChange) {
+			change.ID, change.ProposalID = 0, proposal.ID
+		})
+		if err != nil {
+			return counts, fmt.Errorf("proposal %s status history: %w", proposal.ProposalNumber, err)
+		}
+		counts.Proposals++
+	}
+	for _, rate := range data.ExchangeRates {
+		if err := upsert(&rate).Error; err != nil {
+			return counts, fmt.Errorf("exchange rate %s: %w", rate.Currency, err)
+		}
+		counts.ExchangeRates++
+	}
+
+	if err := resetIDSequences(tx); err != nil {
+		return counts, err
+	}
+	if _, err := ResyncNumberSequences(tx); err != nil {
+		return counts, err
+	}
+	return counts, nil
+}
+
+// currencyOrBase fills in the base currency for documents exported without one.
+func currencyOrBase(code string) string {
+	if code == "" {
+		return BaseCurrency()
+	}
+	return code
+}
+
+// replaceChildren deletes the children of parentID and inserts rows in their place. adopt
+// clears each row's id and points it at parentID: an exported child id may belong to a
+// child of another parent here, and keeping it would fail the import or move that child.
+// Nothing refers to children by id, so fresh ids lose nothing.
+func replaceChildren[T any](tx *gorm.DB, model any, column string, parentID uint, rows []T, adopt func(*T)) error {
+	if err := tx.Where(column+" = ?", parentID).Delete(model).Error; err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	for i := range rows {
+		adopt(&rows[i])
+	}
+	return tx.Omit(clause.Associations).Create(&rows).Error
+}
+
+// resetIDSequences moves Postgres id sequences past the imported ids. SQLite tracks
+// explicit ids on its own.
+func resetIDSequences(tx *gorm.DB) error {
+	if tx.Dialector.Name() != "postgres" {
+		return nil
+	}
+	tables := []string{"hotels", "hotel_images", "car_rentals", "clients", "receipts", "activities",
+		"payments", "proposals", "proposal_rooms", "proposal_status_changes"}
+	for _, table := range tables {
+		err := tx.Exec(fmt.Sprintf(
+			"SELECT setval(pg_get_serial_sequence('%[1]s', 'id'), COALESCE((SELECT MAX(id) FROM %[1]s), 0) + 1, false)", table,
+		)).Error
+		if err != nil {
+			return fmt.Errorf("%s id sequence: %w", table, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Otabek228101/mehmon/config"
+	"github.com/Otabek228101/mehmon/models"
+	"github.com/Otabek228101/mehmon/repository"
+	"gorm.io/gorm"
+)
+
+type NumberFormat config.NumberFormat
+
+func (f NumberFormat) period(now time.Time) string {
+	if f.Yearly {
+		return strconv.Itoa(now.Year())
+	}
+	return ""
+}
+
+// stem is the part of a number before its counter, e.g. "M" or "M2026-".
+func (f NumberFormat) stem(period string) string {
+	if period == "" {
+		return f.Prefix
+	}
+	return f.Prefix + period + "-"
+}
+
+func (f NumberFormat) Format(period string, value int) string {
+	return fmt.Sprintf("%s%0*d", f.stem(period), f.Padding, value)
+}
+
+// nextNumber allocates the next number for a sequence in store, which should be a
+// transaction so that a rolled back create gives its number back.
+func nextNumber(store repository.Store, name string, format NumberFormat) (string, error) {
+	period := format.period(time.Now())
+	value, err := store.Numbers().Next(name, period, format.stem(period))
+	if err != nil {
+		return "", err
+	}
+	return format.Format(period, value), nil
+}
+
+type numberSequence struct {
+	name   string
+	format NumberFormat
+}
+
+func numberSequences() []numberSequence {
+	return []numberSequence{
+		{"receipt", NumberFormat(config.Current.ReceiptNumber)},
+		{"proposal", NumberFormat(config.Current.ProposalNumber)},
+	}
+}
+
+// SequenceChange is a counter moved by ResyncNumberSequences.
+type SequenceChange struct {
+	Name   string
+	Period string
+	From   int
+	To     int
+}
+
+// ResyncNumberSequences sets every counter, and the counter of the current period, to the
+// highest number issued with its stem, so the next document continues right after it.
+// Use it after importing documents or editing numbers by hand.
+func ResyncNumberSequences(tx *gorm.DB) ([]SequenceChange, error) {
+	var changes []SequenceChange
+	for _, seq := range numberSequences() {
+		var rows []models.NumberSequence
+		if err := tx.Where("name = ?", seq.name).Find(&rows).Error; err != nil {
+			return nil, err
+		}
+		current := map[string]int{}
+		for _, row := range rows {
+			current[row.Period] = row.Value
+		}
+		periods := []string{seq.format.period(time.Now())}
+		for _, row := range rows {
+			if row.Period != periods[0] {
+				periods = append(periods, row.Period)
+			}
+		}
+
+		for _, period := range periods {
+			highest, err := repository.HighestIssued(tx, seq.name, seq.format.stem(period))
+			if err != nil {
+				return nil, err
+			}
+			value, exists := current[period]
+			if exists && value == highest || !exists && highest == 0 {
+				continue
+			}
+			err = tx.Exec(
+				"INSERT INTO number_sequences (name, period, value) VALUES (?, ?, ?) ON CONFLICT (name, period) DO UPDATE SET value = excluded.value",
+				seq.name, period, highest,
+			).Error
+			if err != nil {
+				return nil, err
+			}
+			changes = append(changes, SequenceChange{Name: seq.name, Period: period, From: value, To: highest})
+		}
+	}
+	return changes, nil
+}
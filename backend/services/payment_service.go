@@ -0,0 +1,88 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Otabek228101/mehmon/models"
+	"github.com/Otabek228101/mehmon/repository"
+	"github.com/Otabek228101/mehmon/validation"
+)
+
+// Payments lists the receipt's payments, earliest first.
+func (s *ReceiptService) Payments(receiptID uint) ([]models.Payment, error) {
+	if _, err := s.store.Receipts().Get(receiptID); err != nil {
+		return nil, err
+	}
+	return s.store.Receipts().Payments(receiptID)
+}
+
+// AddPayment records a payment against the receipt, dated today unless the request says
+// otherwise.
+func (s *ReceiptService) AddPayment(actor *models.User, receiptID uint, req models.PaymentRequest) (models.Payment, error) {
+	receipt, err := s.store.Receipts().Get(receiptID)
+	if err != nil {
+		return models.Payment{}, err
+	}
+	if err := req.Validate(); err != nil {
+		return models.Payment{}, err
+	}
+	paidAt := time.Now()
+	if req.Date != "" {
+		paidAt, _ = validation.ParseTime(req.Date)
+	}
+
+	payment := models.Payment{
+		ReceiptID: receipt.ID,
+		PaidAt:    paidAt,
+		Method:    models.NormalizePaymentMethod(req.Method),
+		Amount:    req.Amount,
+		Reference: req.Reference,
+	}
+	err = s.store.Transaction(func(tx repository.Store) error {
+		return addPayment(tx, actor, &payment)
+	})
+	return payment, err
+}
+
+func (s *ReceiptService) DeletePayment(actor *models.User, receiptID, paymentID uint) error {
+	payment, err := s.store.Receipts().GetPayment(receiptID, paymentID)
+	if err != nil {
+		return err
+	}
+	return s.store.Transaction(func(tx repository.Store) error {
+		if err := tx.Receipts().DeletePayment(payment); err != nil {
+			return err
+		}
+		return storeAudit(tx, actor, "payment", payment.ID, AuditDelete, payment, nil)
+	})
+}
+
+type ReceiptBalance struct {
+	Total       models.Money `json:"total"`
+	Paid        models.Money `json:"paid"`
+	Balance     models.Money `json:"balance"`
+	Unconverted []string     `json:"unconverted,omitempty"`
+}
+
+// ComputeBalance totals the receipt's activities in the receipt's currency. Activities in
+// currencies without a rate are left out of the total and listed in Unconverted.
+func ComputeBalance(receipt models.Receipt, rates Rates) ReceiptBalance {
+	var result ReceiptBalance
+	seen := map[string]bool{}
+	for _, activity := range receipt.Activities {
+		amount, err := rates.Convert(activity.Amount, activity.Currency, receipt.Currency)
+		if err != nil {
+			if !seen[activity.Currency] {
+				seen[activity.Currency] = true
+				result.Unconverted = append(result.Unconverted, activity.Currency)
+			}
+			continue
+		}
+		result.Total = result.Total.Add(amount)
+	}
+	sort.Strings(result.Unconverted)
+	result.Paid = receipt.AmountPaid
+	result.Balance = result.Total.Sub(result.Paid)
+	return result
+}
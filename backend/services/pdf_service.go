@@ -0,0 +1,444 @@
+package services
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"image"
+	_ "image/png"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Otabek228101/mehmon/models"
+	"github.com/jung-kurt/gofpdf"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+//go:embed assets/logoBlack.png
+var logoPNG []byte
+
+const (
+	pdfMarginLeft  = 20.0
+	pdfMarginRight = 20.0
+	pdfPageBottom  = 270.0
+)
+
+type pdfDoc struct {
+	*gofpdf.Fpdf
+	tr func(string) string
+}
+
+func newPDFDoc() *pdfDoc {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(pdfMarginLeft, 20, pdfMarginRight)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.AddPage()
+	return &pdfDoc{Fpdf: pdf, tr: pdf.UnicodeTranslatorFromDescriptor("")}
+}
+
+func (d *pdfDoc) contentWidth() float64 {
+	w, _ := d.GetPageSize()
+	return w - pdfMarginLeft - pdfMarginRight
+}
+
+func (d *pdfDoc) ensureSpace(y, need float64) float64 {
+	if y+need > pdfPageBottom {
+		d.AddPage()
+		return 20
+	}
+	return y
+}
+
+func (d *pdfDoc) text(x, y float64, size float64, style, s string) {
+	d.SetFont("Helvetica", style, size)
+	d.Text(x, y, d.tr(s))
+}
+
+func (d *pdfDoc) logo(y float64) float64 {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(logoPNG))
+	if err != nil || cfg.Width == 0 {
+		return y
+	}
+	const logoWidth = 60.0
+	logoHeight := logoWidth * float64(cfg.Height) / float64(cfg.Width)
+	d.RegisterImageOptionsReader("logo", gofpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(logoPNG))
+	pageWidth, _ := d.GetPageSize()
+	d.ImageOptions("logo", (pageWidth-logoWidth)/2, y, logoWidth, logoHeight, false, gofpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+	return y + logoHeight + 5
+}
+
+func (d *pdfDoc) rule(y float64) {
+	pageWidth, _ := d.GetPageSize()
+	d.SetLineWidth(0.5)
+	d.Line(pdfMarginLeft, y, pageWidth-pdfMarginRight, y)
+}
+
+// table draws a two column Item/Details table starting at y and returns the y below it.
+func (d *pdfDoc) table(y float64, rows [][2]string) float64 {
+	const lineHeight = 5.0
+	labelWidth := 50.0
+	valueWidth := d.contentWidth() - labelWidth
+
+	d.SetFont("Helvetica", "B", 8)
+	d.SetFillColor(241, 241, 241)
+	d.SetDrawColor(0, 0, 0)
+	d.SetLineWidth(0.1)
+	d.SetXY(pdfMarginLeft, y)
+	d.CellFormat(labelWidth, lineHeight+1, "Item", "1", 0, "L", true, 0, "")
+	d.CellFormat(valueWidth, lineHeight+1, "Details", "1", 1, "L", true, 0, "")
+	y += lineHeight + 1
+
+	d.SetFont("Helvetica", "", 8)
+	for _, row := range rows {
+		label, value := d.tr(row[0]), d.tr(row[1])
+		lines := d.SplitLines([]byte(value), valueWidth-2)
+		height := lineHeight * float64(max(len(lines), 1))
+		if y+height > pdfPageBottom {
+			d.AddPage()
+			y = 20
+		}
+		d.SetXY(pdfMarginLeft, y)
+		d.CellFormat(labelWidth, height, label, "1", 0, "LM", false, 0, "")
+		d.SetXY(pdfMarginLeft+labelWidth, y)
+		d.MultiCell(valueWidth, lineHeight, value, "1", "L", false)
+		y += height
+	}
+	return y
+}
+
+func (d *pdfDoc) qr(y float64, content, title, caption string) (float64, error) {
+	png, err := qrcode.Encode(content, qrcode.High, 512)
+	if err != nil {
+		return y, err
+	}
+	const qrSize = 60.0
+	pageWidth, _ := d.GetPageSize()
+	y = d.ensureSpace(y, qrSize+20)
+
+	d.SetFont("Helvetica", "B", 12)
+	d.SetXY(pdfMarginLeft, y)
+	d.CellFormat(d.contentWidth(), 6, d.tr(title), "", 1, "C", false, 0, "")
+	y += 8
+
+	d.RegisterImageOptionsReader("qr", gofpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(png))
+	d.ImageOptions("qr", (pageWidth-qrSize)/2, y, qrSize, qrSize, false, gofpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+	y += qrSize + 2
+
+	d.SetFont("Helvetica", "", 8)
+	d.SetXY(pdfMarginLeft, y)
+	d.CellFormat(d.contentWidth(), 5, d.tr(caption), "", 1, "C", false, 0, "")
+	return y + 5, nil
+}
+
+func (d *pdfDoc) bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := d.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func formatPDFDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return strings.ToUpper(t.Format("January 02, 2006"))
+}
+
+func formatPDFAmount(amount models.Money, currency string) string {
+	if currency == "" || currency == "USD" {
+		return "$" + amount.String()
+	}
+	return amount.String() + " " + currency
+}
+
+// formatPDFTotals prints one amount per currency, in first-seen order.
+func formatPDFTotals(order []string, totals map[string]models.Money) string {
+	parts := make([]string, 0, len(order))
+	for _, currency := range order {
+		parts = append(parts, formatPDFAmount(totals[currency], currency))
+	}
+	if len(parts) == 0 {
+		return formatPDFAmount(models.Money{}, "")
+	}
+	return strings.Join(parts, " + ")
+}
+
+func activityTitle(activityType string) string {
+	if activityType == "" {
+		return "ACTIVITY"
+	}
+	return strings.ToUpper(strings.ReplaceAll(activityType, "_", " "))
+}
+
+func activityRows(activity models.Activity) [][2]string {
+	rows := [][2]string{}
+	add := func(label, value string) {
+		if strings.TrimSpace(value) != "" {
+			rows = append(rows, [2]string{label, value})
+		}
+	}
+	addDate := func(label string, t *time.Time) {
+		if t != nil {
+			rows = append(rows, [2]string{label, formatPDFDate(*t)})
+		}
+	}
+
+	details, _ := activity.Details()
+	switch d := details.(type) {
+	case models.Flight:
+		add("Airline", d.Airline)
+		add("Flight Number", d.FlightNumber)
+		add("From", d.From)
+		add("To", d.To)
+		addDate("Departure", d.Departure)
+		addDate("Arrival", d.Arrival)
+	case models.Excursion:
+		add("Excursion", d.Name)
+		add("Meeting Point", d.MeetingPoint)
+		add("Operator", d.Operator)
+		addDate("Date", d.Date)
+	case models.Insurance:
+		add("Insurer", d.Insurer)
+		add("Policy Number", d.PolicyNumber)
+		addDate("Coverage From", d.CoverageStart)
+		addDate("Coverage To", d.CoverageEnd)
+	default:
+		add("Property Name", activity.PropertyName)
+		add("Address", activity.PropertyAddress)
+		if activity.Type != models.ActivityCarRental {
+			addDate("Check-In", activity.CheckIn)
+			addDate("Check-Out", activity.CheckOut)
+		} else {
+			add("Pickup Location", activity.PickupLocation)
+			add("Dropoff Location", activity.DropoffLocation)
+		}
+		if activity.Type == models.ActivityTransfer {
+			add("Transfer Type", activity.TransferType)
+		}
+	}
+	add("Operator Comments", activity.Description)
+	rows = append(rows, [2]string{"Amount", formatPDFAmount(activity.Amount, activity.Currency)})
+	return rows
+}
+
+// RenderReceiptPDF lays the receipt out the same way the frontend jsPDF export does.
+func RenderReceiptPDF(receipt models.Receipt, qrURL string) ([]byte, error) {
+	d := newPDFDoc()
+	pageWidth, _ := d.GetPageSize()
+
+	y := d.logo(20)
+	d.rule(y)
+	y += 15
+
+	d.text(pdfMarginLeft, y, 10, "", "BOOKING NUMBER: "+receipt.ReceiptNumber)
+	y += 5
+	d.text(pdfMarginLeft, y, 10, "", "DATE: "+formatPDFDate(receipt.ReceiptDate))
+	y += 15
+
+	billToY := y
+	d.text(pdfMarginLeft, billToY, 10, "B", "Bill To:")
+	y = billToY + 8
+	d.text(pdfMarginLeft, y, 9, "", "Full Name: "+receipt.ClientName)
+	y += 5
+	d.text(pdfMarginLeft, y, 9, "", "Email: "+receipt.ClientEmail)
+	y += 5
+	d.text(pdfMarginLeft, y, 9, "", "Phone: "+receipt.ClientPhone)
+	y += 5
+	d.text(pdfMarginLeft, y, 9, "", "Date: "+formatPDFDate(receipt.ReceiptDate))
+
+	d.text(120, billToY, 10, "B", "Payment Method")
+	d.text(120, billToY+8, 9, "", "Uzum Bank")
+	d.text(120, billToY+13, 9, "", strings.ToUpper(receipt.ClientName))
+	d.text(120, billToY+18, 9, "", receipt.ClientPhone)
+	y += 10
+
+	var currencies []string
+	totals := map[string]models.Money{}
+	for i, activity := range receipt.Activities {
+		if y > 220 && i > 0 {
+			d.AddPage()
+			y = 20
+		}
+		d.text(pdfMarginLeft, y, 12, "B", activityTitle(activity.Type))
+		y += 4
+		y = d.table(y, activityRows(activity)) + 8
+		if _, seen := totals[activity.Currency]; !seen {
+			currencies = append(currencies, activity.Currency)
+		}
+		totals[activity.Currency] = totals[activity.Currency].Add(activity.Amount)
+	}
+
+	y = d.ensureSpace(y, 20)
+	d.SetFont("Helvetica", "B", 16)
+	d.Text(pdfMarginLeft, y, "Total Amount")
+	d.SetXY(pageWidth-pdfMarginRight-60, y-6)
+	d.CellFormat(60, 8, formatPDFTotals(currencies, totals), "", 0, "R", false, 0, "")
+	y += 8
+	d.SetFont("Helvetica", "B", 11)
+	d.Text(pdfMarginLeft, y, "Amount Paid")
+	d.SetXY(pageWidth-pdfMarginRight-60, y-5)
+	d.CellFormat(60, 6, formatPDFAmount(receipt.AmountPaid, receipt.Currency), "", 0, "R", false, 0, "")
+	y += 10
+
+	d.rule(y)
+	y += 10
+
+	if y > 180 {
+		d.AddPage()
+		y = 20
+	}
+	if _, err := d.qr(y, qrURL, "Scan QR Code for Digital Copy", "Scan with your phone camera to download"); err != nil {
+		d.text(pdfMarginLeft, y, 8, "", "QR Code unavailable")
+	}
+
+	return d.bytes()
+}
+
+func proposalNights(proposal models.Proposal) int {
+	nights := int(proposal.CheckOut.Sub(proposal.CheckIn).Hours() / 24)
+	if nights < 0 {
+		return 0
+	}
+	return nights
+}
+
+func yesNo(v bool) string {
+	if v {
+		return "Yes"
+	}
+	return "No"
+}
+
+// pdfImageMimes are the image types hotelImages can embed.
+var pdfImageMimes = []string{"image/jpeg", "image/png"}
+
+func (d *pdfDoc) hotelImages(y float64, images []models.HotelImage) float64 {
+	const gap = 4.0
+	perRow := 3
+	if len(images) < perRow {
+		perRow = len(images)
+	}
+	if perRow == 0 {
+		return y
+	}
+	width := (d.contentWidth() - gap*float64(perRow-1)) / float64(perRow)
+	height := width * 0.66
+
+	col := 0
+	for _, img := range images {
+		imageType := ""
+		switch img.Mime {
+		case "image/jpeg":
+			imageType = "JPG"
+		case "image/png":
+			imageType = "PNG"
+		default:
+			continue
+		}
+		b, err := os.ReadFile(img.Path)
+		if err != nil {
+			continue
+		}
+		if col == 0 {
+			y = d.ensureSpace(y, height)
+		}
+		name := fmt.Sprintf("hotel_image_%d", img.ID)
+		opts := gofpdf.ImageOptions{ImageType: imageType}
+		d.RegisterImageOptionsReader(name, opts, bytes.NewReader(b))
+		if d.Err() {
+			d.ClearError()
+			continue
+		}
+		d.ImageOptions(name, pdfMarginLeft+float64(col)*(width+gap), y, width, height, false, opts, 0, "")
+		col++
+		if col == perRow {
+			col = 0
+			y += height + gap
+		}
+	}
+	if col > 0 {
+		y += height + gap
+	}
+	return y
+}
+
+// RenderProposalPDF builds the client-facing offer for a proposal with its hotel preloaded.
+func RenderProposalPDF(proposal models.Proposal, images []models.HotelImage) ([]byte, error) {
+	d := newPDFDoc()
+
+	y := d.logo(20)
+	d.rule(y)
+	y += 12
+
+	d.text(pdfMarginLeft, y, 16, "B", "HOTEL OFFER")
+	y += 7
+	d.text(pdfMarginLeft, y, 10, "", "OFFER NUMBER: "+proposal.ProposalNumber)
+	y += 5
+	d.text(pdfMarginLeft, y, 10, "", "DATE: "+formatPDFDate(proposal.CreatedAt))
+	y += 5
+	d.text(pdfMarginLeft, y, 10, "", "PREPARED FOR: "+proposal.ClientName)
+	y += 10
+
+	if proposal.Hotel != nil {
+		hotel := proposal.Hotel
+		d.text(pdfMarginLeft, y, 14, "B", hotel.Name)
+		y += 6
+		if hotel.Stars > 0 {
+			d.text(pdfMarginLeft, y, 10, "", fmt.Sprintf("%d-star %s", hotel.Stars, hotel.Type))
+			y += 5
+		}
+		d.text(pdfMarginLeft, y, 9, "", strings.TrimSpace(hotel.Address+", "+hotel.City))
+		y += 5
+
+		d.SetFont("Helvetica", "U", 9)
+		d.SetTextColor(0, 0, 238)
+		if hotel.LocationLink != "" {
+			d.SetXY(pdfMarginLeft, y-3.5)
+			d.CellFormat(30, 5, "View on map", "", 0, "L", false, 0, hotel.LocationLink)
+		}
+		if hotel.WebsiteLink != "" {
+			d.SetXY(pdfMarginLeft+35, y-3.5)
+			d.CellFormat(30, 5, "Hotel website", "", 0, "L", false, 0, hotel.WebsiteLink)
+		}
+		d.SetTextColor(0, 0, 0)
+		y += 6
+
+		y = d.hotelImages(y, images)
+		y += 4
+	}
+
+	rows := [][2]string{
+		{"Check-In", formatPDFDate(proposal.CheckIn)},
+		{"Check-Out", formatPDFDate(proposal.CheckOut)},
+		{"Nights", fmt.Sprintf("%d", proposalNights(proposal))},
+		{"Guests", fmt.Sprintf("%d", proposal.Guests)},
+	}
+	totalRooms := 0
+	for i, room := range proposal.Rooms {
+		rows = append(rows, [2]string{fmt.Sprintf("Room %d", i+1), fmt.Sprintf("%d", room.Count)})
+		totalRooms += room.Count
+	}
+	rows = append(rows,
+		[2]string{"Total Rooms", fmt.Sprintf("%d", totalRooms)},
+		[2]string{"Breakfast", yesNo(proposal.Breakfast)},
+		[2]string{"Free Cancellation", yesNo(proposal.FreeCancel)},
+	)
+
+	y = d.ensureSpace(y, 12)
+	d.text(pdfMarginLeft, y, 12, "B", "STAY DETAILS")
+	y += 4
+	y = d.table(y, rows) + 10
+
+	y = d.ensureSpace(y, 15)
+	pageWidth, _ := d.GetPageSize()
+	d.SetFont("Helvetica", "B", 16)
+	d.Text(pdfMarginLeft, y, "Price")
+	d.SetXY(pageWidth-pdfMarginRight-60, y-6)
+	d.CellFormat(60, 8, formatPDFAmount(proposal.Price, proposal.Currency), "", 0, "R", false, 0, "")
+	y += 8
+	d.rule(y)
+
+	return d.bytes()
+}
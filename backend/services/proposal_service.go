@@ -1,25 +1,387 @@
 package services
 
 import (
+	"errors"
 	"fmt"
-	"strconv"
+	"strings"
+	"time"
 
-	"github.com/Otabek228101/mehmon/database"
+	"github.com/Otabek228101/mehmon/config"
 	"github.com/Otabek228101/mehmon/models"
+	"github.com/Otabek228101/mehmon/repository"
+	"github.com/Otabek228101/mehmon/validation"
 )
 
-func GenerateProposalNumber() string {
-	var lastProposal models.Proposal
-	result := database.DB.Order("created_at desc").First(&lastProposal)
+var (
+	// ErrHotelNotFound rejects a proposal for, or a receipt filter on, a hotel that does not exist.
+	ErrHotelNotFound = errors.New("hotel not found")
+	// ErrNotAccepted rejects converting a proposal the client has not accepted.
+	ErrNotAccepted = errors.New("only accepted proposals can be converted")
+)
+
+// ConvertedError rejects converting a proposal that already has a receipt.
+type ConvertedError struct {
+	ReceiptID uint
+}
+
+func (e *ConvertedError) Error() string {
+	return fmt.Sprintf("proposal already converted into receipt %d", e.ReceiptID)
+}
+
+func nextProposalNumber(store repository.Store) (string, error) {
+	return nextNumber(store, "proposal", NumberFormat(config.Current.ProposalNumber))
+}
+
+var proposalTransitions = map[string][]string{
+	models.ProposalStatusDraft: {models.ProposalStatusSent},
+	models.ProposalStatusSent: {
+		models.ProposalStatusAccepted,
+		models.ProposalStatusRejected,
+		models.ProposalStatusExpired,
+	},
+}
+
+func IsProposalStatus(status string) bool {
+	switch status {
+	case models.ProposalStatusDraft, models.ProposalStatusSent, models.ProposalStatusAccepted,
+		models.ProposalStatusRejected, models.ProposalStatusExpired:
+		return true
+	}
+	return false
+}
+
+func CanTransitionProposal(from, to string) bool {
+	for _, next := range proposalTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionError rejects a status change the proposal workflow does not allow.
+type TransitionError struct {
+	From, To string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("cannot change proposal status from %s to %s", e.From, e.To)
+}
+
+// ProposalService creates and edits proposals, converts them into receipts and runs the
+// status workflow and the trash. Missing proposals come back as repository.ErrNotFound and
+// bad requests as validation.Errors.
+type ProposalService struct {
+	store repository.Store
+}
+
+func NewProposalService(store repository.Store) *ProposalService {
+	return &ProposalService{store: store}
+}
+
+func (s *ProposalService) List(status string) ([]models.Proposal, error) {
+	return s.store.Proposals().List(status)
+}
+
+func (s *ProposalService) Get(id uint) (models.Proposal, error) {
+	return s.store.Proposals().Get(id)
+}
 
-	nextNumber := 1
-	if result.Error == nil && lastProposal.ProposalNumber != "" {
-		numStr := lastProposal.ProposalNumber[1:]
-		num, err := strconv.Atoi(numStr)
-		if err == nil {
-			nextNumber = num + 1
+// Trash lists the deleted proposals, latest first.
+func (s *ProposalService) Trash() ([]models.Proposal, error) {
+	return s.store.Proposals().ListDeleted()
+}
+
+// PDF renders the proposal with up to images photos of its hotel, skipping photos in
+// formats the PDF cannot embed before counting them.
+func (s *ProposalService) PDF(id uint, images int) (models.Proposal, []byte, error) {
+	proposal, err := s.store.Proposals().Get(id)
+	if err != nil {
+		return proposal, nil, err
+	}
+	var photos []models.HotelImage
+	if images > 0 {
+		if photos, err = s.store.Hotels().Images(proposal.HotelID, pdfImageMimes, images); err != nil {
+			return proposal, nil, err
 		}
 	}
+	pdf, err := RenderProposalPDF(proposal, photos)
+	return proposal, pdf, err
+}
+
+// Create issues a numbered draft proposal.
+func (s *ProposalService) Create(actor *models.User, req models.ProposalRequest) (models.Proposal, error) {
+	if err := s.prepare(&req, BaseCurrency()); err != nil {
+		return models.Proposal{}, err
+	}
+	checkIn, _ := validation.ParseTime(req.CheckIn)
+	checkOut, _ := validation.ParseTime(req.CheckOut)
+	proposal := models.Proposal{
+		Currency:   req.Currency,
+		ClientName: req.ClientName,
+		ClientID:   req.ClientID,
+		Guests:     req.Guests,
+		CheckIn:    checkIn,
+		CheckOut:   checkOut,
+		Price:      req.Price,
+		Breakfast:  req.Breakfast,
+		FreeCancel: req.FreeCancel,
+		HotelID:    req.HotelID,
+		Status:     models.ProposalStatusDraft,
+		Rooms:      newRooms(req.Rooms),
+	}
+
+	err := s.store.Transaction(func(tx repository.Store) error {
+		number, err := nextProposalNumber(tx)
+		if err != nil {
+			return err
+		}
+		proposal.ProposalNumber = number
+		if err := tx.Proposals().Create(&proposal); err != nil {
+			return err
+		}
+		change := models.ProposalStatusChange{ProposalID: proposal.ID, ToStatus: models.ProposalStatusDraft}
+		if err := tx.Proposals().AddStatusChange(&change); err != nil {
+			return err
+		}
+		return storeAudit(tx, actor, "proposal", proposal.ID, AuditCreate, nil, proposal)
+	})
+	if err != nil {
+		return proposal, err
+	}
+	return s.store.Proposals().Get(proposal.ID)
+}
+
+// Update replaces the proposal's details and rooms. Its status is left alone.
+func (s *ProposalService) Update(actor *models.User, id uint, req models.ProposalRequest) (models.Proposal, error) {
+	before, err := s.store.Proposals().Get(id)
+	if err != nil {
+		return before, err
+	}
+	if err := s.prepare(&req, before.Currency); err != nil {
+		return before, err
+	}
+	before.Hotel, before.StatusHistory = nil, nil
+	checkIn, _ := validation.ParseTime(req.CheckIn)
+	checkOut, _ := validation.ParseTime(req.CheckOut)
+
+	proposal := before
+	proposal.Currency = req.Currency
+	proposal.ClientName = req.ClientName
+	if req.ClientID != nil {
+		proposal.ClientID = req.ClientID
+	}
+	proposal.Guests = req.Guests
+	proposal.CheckIn = checkIn
+	proposal.CheckOut = checkOut
+	proposal.Price = req.Price
+	proposal.Breakfast = req.Breakfast
+	proposal.FreeCancel = req.FreeCancel
+	proposal.HotelID = req.HotelID
+	proposal.Rooms = newRooms(req.Rooms)
+
+	err = s.store.Transaction(func(tx repository.Store) error {
+		if err := tx.Proposals().Update(&proposal); err != nil {
+			return err
+		}
+		return storeAudit(tx, actor, "proposal", proposal.ID, AuditUpdate, before, proposal)
+	})
+	if err != nil {
+		return before, err
+	}
+	return s.store.Proposals().Get(proposal.ID)
+}
 
-	return fmt.Sprintf("P%05d", nextNumber)
+// prepare validates req, fills an empty client name from the picked client, checks the
+// hotel exists and defaults the currency to fallback. A missing hotel is ErrHotelNotFound.
+func (s *ProposalService) prepare(req *models.ProposalRequest, fallback string) error {
+	if err := req.Validate(); err != nil {
+		return err
+	}
+	if req.ClientID != nil {
+		client, err := pickedClient(s.store, *req.ClientID, "clientId")
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(req.ClientName) == "" {
+			req.ClientName = client.Name
+		}
+	}
+	if _, err := s.store.Hotels().Get(req.HotelID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrHotelNotFound
+		}
+		return err
+	}
+	rates, err := loadRates(s.store)
+	if err != nil {
+		return err
+	}
+	if req.Currency, err = ResolveCurrency(rates, req.Currency, fallback); err != nil {
+		v := validation.New()
+		v.Add("currency", validation.CodeCurrency, err.Error())
+		return v.Err()
+	}
+	return nil
+}
+
+func newRooms(requests []models.RoomRequest) []models.ProposalRoom {
+	rooms := make([]models.ProposalRoom, 0, len(requests))
+	for _, r := range requests {
+		rooms = append(rooms, models.ProposalRoom{Count: r.Count})
+	}
+	return rooms
+}
+
+// Convert issues a receipt for an accepted proposal, with one hotel activity for its stay,
+// and links the two. It fails with a *ConvertedError when the proposal already has a
+// receipt, ErrNotAccepted before the client accepts it and ErrHotelNotFound when its
+// hotel is gone.
+func (s *ProposalService) Convert(actor *models.User, id uint) (models.Receipt, error) {
+	proposal, err := s.store.Proposals().Get(id)
+	if err != nil {
+		return models.Receipt{}, err
+	}
+	if proposal.ReceiptID != nil {
+		return models.Receipt{}, &ConvertedError{ReceiptID: *proposal.ReceiptID}
+	}
+	if proposal.Status != models.ProposalStatusAccepted {
+		return models.Receipt{}, ErrNotAccepted
+	}
+	if proposal.Hotel == nil {
+		return models.Receipt{}, ErrHotelNotFound
+	}
+
+	checkIn, checkOut := proposal.CheckIn, proposal.CheckOut
+	receipt := models.Receipt{
+		ClientName:  proposal.ClientName,
+		ReceiptDate: time.Now(),
+		Currency:    proposal.Currency,
+		ProposalID:  &proposal.ID,
+		ClientID:    proposal.ClientID,
+		CreatedByID: actorID(actor),
+		Activities: []models.Activity{
+			{
+				Type:            models.ActivityHotel,
+				HotelID:         &proposal.HotelID,
+				PropertyName:    proposal.Hotel.Name,
+				PropertyAddress: proposal.Hotel.Address,
+				CheckIn:         &checkIn,
+				CheckOut:        &checkOut,
+				Amount:          proposal.Price,
+				Currency:        proposal.Currency,
+			},
+		},
+	}
+	if proposal.ClientID != nil {
+		client, err := s.store.Clients().Get(*proposal.ClientID)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return models.Receipt{}, err
+		}
+		receipt.ClientEmail, receipt.ClientPhone = client.Email, client.Phone
+	}
+
+	err = s.store.Transaction(func(tx repository.Store) error {
+		number, err := nextReceiptNumber(tx)
+		if err != nil {
+			return err
+		}
+		receipt.ReceiptNumber = number
+		if err := tx.Receipts().Create(&receipt); err != nil {
+			return err
+		}
+		if err := tx.Proposals().LinkReceipt(proposal.ID, receipt.ID); err != nil {
+			return err
+		}
+		if err := storeAudit(tx, actor, "receipt", receipt.ID, AuditCreate, nil, receipt); err != nil {
+			return err
+		}
+		return storeAudit(tx, actor, "proposal", proposal.ID, AuditUpdate,
+			map[string]any{"receiptId": nil}, map[string]any{"receiptId": receipt.ID})
+	})
+	if errors.Is(err, repository.ErrConflict) {
+		// Another convert linked the proposal first; this one's receipt was rolled back.
+		current, err := s.store.Proposals().Get(proposal.ID)
+		if err != nil {
+			return models.Receipt{}, err
+		}
+		if current.ReceiptID == nil {
+			return models.Receipt{}, repository.ErrNotFound
+		}
+		return models.Receipt{}, &ConvertedError{ReceiptID: *current.ReceiptID}
+	}
+	if err != nil {
+		return models.Receipt{}, err
+	}
+	return s.store.Receipts().Get(receipt.ID)
+}
+
+// ChangeStatus moves the proposal along the workflow and records the change in its history.
+// It returns a *TransitionError for moves the workflow forbids and repository.ErrConflict
+// when another request changed the status first.
+func (s *ProposalService) ChangeStatus(actor *models.User, id uint, req models.ProposalStatusRequest) (models.Proposal, error) {
+	proposal, err := s.store.Proposals().Get(id)
+	if err != nil {
+		return proposal, err
+	}
+	if err := req.Validate(); err != nil {
+		return proposal, err
+	}
+	if !CanTransitionProposal(proposal.Status, req.Status) {
+		return proposal, &TransitionError{From: proposal.Status, To: req.Status}
+	}
+
+	change := models.ProposalStatusChange{
+		ProposalID: proposal.ID,
+		FromStatus: proposal.Status,
+		ToStatus:   req.Status,
+		Note:       req.Note,
+	}
+	err = s.store.Transaction(func(tx repository.Store) error {
+		if err := tx.Proposals().UpdateStatus(proposal.ID, change.FromStatus, change.ToStatus); err != nil {
+			return err
+		}
+		if err := tx.Proposals().AddStatusChange(&change); err != nil {
+			return err
+		}
+		return storeAudit(tx, actor, "proposal", proposal.ID, AuditUpdate,
+			map[string]any{"status": change.FromStatus}, map[string]any{"status": change.ToStatus, "note": change.Note})
+	})
+	if err != nil {
+		return proposal, err
+	}
+	return s.store.Proposals().Get(proposal.ID)
+}
+
+func (s *ProposalService) Delete(actor *models.User, id uint) error {
+	proposal, err := s.store.Proposals().Get(id)
+	if err != nil {
+		return err
+	}
+	proposal.Hotel, proposal.StatusHistory = nil, nil
+	return s.store.Transaction(func(tx repository.Store) error {
+		if err := tx.Proposals().Delete(proposal.ID); err != nil {
+			return err
+		}
+		return storeAudit(tx, actor, "proposal", proposal.ID, AuditDelete, proposal, nil)
+	})
+}
+
+// Restore takes a proposal out of the trash; proposals that are not in the trash come back
+// as repository.ErrNotFound.
+func (s *ProposalService) Restore(actor *models.User, id uint) (models.Proposal, error) {
+	proposal, err := s.store.Proposals().GetDeleted(id)
+	if err != nil {
+		return proposal, err
+	}
+	err = s.store.Transaction(func(tx repository.Store) error {
+		if err := tx.Proposals().Restore(proposal.ID); err != nil {
+			return err
+		}
+		return storeAudit(tx, actor, "proposal", proposal.ID, AuditRestore, nil, nil)
+	})
+	if err != nil {
+		return proposal, err
+	}
+	return s.store.Proposals().Get(proposal.ID)
 }
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Otabek228101/mehmon/models"
+	"github.com/Otabek228101/mehmon/repository"
+	"github.com/Otabek228101/mehmon/repository/memory"
+)
+
+func TestProposalServiceChangeStatus(t *testing.T) {
+	store := memory.NewStore()
+	proposals := NewProposalService(store)
+	proposal := store.PutProposal(models.Proposal{ProposalNumber: "P00001", Status: models.ProposalStatusDraft})
+
+	got, err := proposals.ChangeStatus(testActor, proposal.ID, models.ProposalStatusRequest{Status: models.ProposalStatusSent, Note: "emailed"})
+	if err != nil {
+		t.Fatalf("draft -> sent: %v", err)
+	}
+	if got.Status != models.ProposalStatusSent {
+		t.Fatalf("status = %s, want sent", got.Status)
+	}
+	if len(got.StatusHistory) != 1 || got.StatusHistory[0].Note != "emailed" {
+		t.Fatalf("history = %+v, want one change noted emailed", got.StatusHistory)
+	}
+
+	_, err = proposals.ChangeStatus(testActor, proposal.ID, models.ProposalStatusRequest{Status: models.ProposalStatusDraft})
+	var transition *TransitionError
+	if !errors.As(err, &transition) || transition.From != models.ProposalStatusSent {
+		t.Fatalf("sent -> draft: err = %v, want a TransitionError from sent", err)
+	}
+	if _, err := proposals.ChangeStatus(testActor, 99, models.ProposalStatusRequest{Status: models.ProposalStatusSent}); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("missing proposal: err = %v, want ErrNotFound", err)
+	}
+	if n := len(store.AuditLog()); n != 1 {
+		t.Fatalf("audit entries = %d, want 1", n)
+	}
+}
+
+func TestProposalServiceChangeStatusRollsBack(t *testing.T) {
+	store := memory.NewStore()
+	proposals := NewProposalService(store)
+	proposal := store.PutProposal(models.Proposal{Status: models.ProposalStatusSent})
+	store.FailAudit = errors.New("audit unavailable")
+
+	if _, err := proposals.ChangeStatus(testActor, proposal.ID, models.ProposalStatusRequest{Status: models.ProposalStatusAccepted}); err == nil {
+		t.Fatal("status change succeeded without its audit entry")
+	}
+	got, err := proposals.Get(proposal.ID)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.Status != models.ProposalStatusSent || len(got.StatusHistory) != 0 {
+		t.Fatalf("status = %s with %d history entries, want sent and none", got.Status, len(got.StatusHistory))
+	}
+}
+
+func TestProposalServiceTrash(t *testing.T) {
+	store := memory.NewStore()
+	proposals := NewProposalService(store)
+	proposal := store.PutProposal(models.Proposal{Status: models.ProposalStatusDraft})
+
+	if _, err := proposals.Restore(testActor, proposal.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("restore live proposal: err = %v, want ErrNotFound", err)
+	}
+	if err := proposals.Delete(testActor, proposal.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if list, _ := proposals.List(""); len(list) != 0 {
+		t.Fatalf("listed proposals = %d, want 0 while in the trash", len(list))
+	}
+	if _, err := proposals.Restore(testActor, proposal.ID); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if _, err := proposals.Get(proposal.ID); err != nil {
+		t.Fatalf("get after restore: %v", err)
+	}
+}
+
+func TestProposalServiceCreateAndConvert(t *testing.T) {
+	store := memory.NewStore()
+	proposals := NewProposalService(store)
+	hotel := store.PutHotel(models.Hotel{Name: "Test Hotel", Address: "Via Roma 1"})
+	client := store.PutClient(models.Client{Name: "Ann", Email: "ann@example.com", Phone: "+998901234567"})
+
+	req := models.ProposalRequest{ClientID: &client.ID, Guests: 2, CheckIn: "2026-05-10", CheckOut: "2026-05-12",
+		Price: models.MoneyFromCents(45000), HotelID: 99, Rooms: []models.RoomRequest{{Count: 1}}}
+	if _, err := proposals.Create(testActor, req); !errors.Is(err, ErrHotelNotFound) {
+		t.Fatalf("missing hotel: err = %v, want ErrHotelNotFound", err)
+	}
+	req.HotelID = hotel.ID
+	proposal, err := proposals.Create(testActor, req)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if proposal.ClientName != "Ann" || proposal.Status != models.ProposalStatusDraft || len(proposal.Rooms) != 1 || len(proposal.StatusHistory) != 1 {
+		t.Fatalf("created proposal = %+v", proposal)
+	}
+
+	if _, err := proposals.Convert(testActor, proposal.ID); !errors.Is(err, ErrNotAccepted) {
+		t.Fatalf("convert draft: err = %v, want ErrNotAccepted", err)
+	}
+	proposal.Status = models.ProposalStatusAccepted
+	store.PutProposal(proposal)
+
+	receipt, err := proposals.Convert(testActor, proposal.ID)
+	if err != nil {
+		t.Fatalf("convert: %v", err)
+	}
+	if receipt.ClientEmail != client.Email || receipt.ClientPhone != client.Phone || len(receipt.Activities) != 1 || receipt.Activities[0].PropertyName != hotel.Name {
+		t.Fatalf("receipt = %+v, want the client's contacts and one hotel activity", receipt)
+	}
+	var converted *ConvertedError
+	if _, err := proposals.Convert(testActor, proposal.ID); !errors.As(err, &converted) || converted.ReceiptID != receipt.ID {
+		t.Fatalf("convert twice: err = %v, want a ConvertedError for receipt %d", err, receipt.ID)
+	}
+}
+
+// racingStore links a proposal to another receipt right after Convert reads it, as a
+// concurrent convert of the same proposal would.
+type racingStore struct {
+	*memory.Store
+	receiptID uint
+}
+
+func (s racingStore) Proposals() repository.ProposalRepository {
+	return racingProposals{s.Store.Proposals(), s}
+}
+
+type racingProposals struct {
+	repository.ProposalRepository
+	s racingStore
+}
+
+func (p racingProposals) Get(id uint) (models.Proposal, error) {
+	proposal, err := p.ProposalRepository.Get(id)
+	if err == nil && proposal.ReceiptID == nil {
+		p.s.Store.Proposals().LinkReceipt(id, p.s.receiptID)
+	}
+	return proposal, err
+}
+
+func TestProposalServiceConvertLosesRace(t *testing.T) {
+	store := memory.NewStore()
+	hotel := store.PutHotel(models.Hotel{Name: "Test Hotel"})
+	winner := store.PutReceipt(models.Receipt{ReceiptNumber: "M00001"})
+	proposal := store.PutProposal(models.Proposal{Status: models.ProposalStatusAccepted, HotelID: hotel.ID})
+
+	if err := store.Proposals().LinkReceipt(proposal.ID, winner.ID); err != nil {
+		t.Fatalf("link: %v", err)
+	}
+	if err := store.Proposals().LinkReceipt(proposal.ID, winner.ID+1); !errors.Is(err, repository.ErrConflict) {
+		t.Fatalf("link a linked proposal: err = %v, want ErrConflict", err)
+	}
+	store.Proposals().UnlinkReceipt(winner.ID)
+
+	_, err := NewProposalService(racingStore{store, winner.ID}).Convert(testActor, proposal.ID)
+	var converted *ConvertedError
+	if !errors.As(err, &converted) || converted.ReceiptID != winner.ID {
+		t.Fatalf("convert: err = %v, want a ConvertedError for receipt %d", err, winner.ID)
+	}
+	if n := len(store.AuditLog()); n != 0 {
+		t.Fatalf("audit entries = %d, want the losing convert rolled back", n)
+	}
+}
@@ -1,25 +1,345 @@
 package services
 
 import (
-	"fmt"
-	"strconv"
+	"errors"
+	"strings"
+	"time"
 
-	"github.com/Otabek228101/mehmon/database"
+	"github.com/Otabek228101/mehmon/config"
 	"github.com/Otabek228101/mehmon/models"
+	"github.com/Otabek228101/mehmon/repository"
+	"github.com/Otabek228101/mehmon/validation"
 )
 
-func GenerateReceiptNumber() string {
-	var lastReceipt models.Receipt
-	result := database.DB.Order("created_at desc").First(&lastReceipt)
+var (
+	// ErrReceiptHasPayments rejects an update that changes amountPaid on a receipt with payments.
+	ErrReceiptHasPayments = errors.New("receipt has payments; change them through its payments instead of amountPaid")
+	// ErrPaidCurrency rejects changing the currency of a receipt with payments, which are
+	// recorded in that currency.
+	ErrPaidCurrency = errors.New("receipt has payments; its currency cannot change")
+)
+
+func nextReceiptNumber(store repository.Store) (string, error) {
+	return nextNumber(store, "receipt", NumberFormat(config.Current.ReceiptNumber))
+}
 
-	nextNumber := 1
-	if result.Error == nil && lastReceipt.ReceiptNumber != "" {
-		numStr := lastReceipt.ReceiptNumber[1:]
-		num, err := strconv.Atoi(numStr)
-		if err == nil {
-			nextNumber = num + 1
+// ReceiptService creates, edits and reads receipts with their balance and moves them in
+// and out of the trash. Missing receipts come back as repository.ErrNotFound and bad
+// requests as validation.Errors.
+type ReceiptService struct {
+	store repository.Store
+}
+
+func NewReceiptService(store repository.Store) *ReceiptService {
+	return &ReceiptService{store: store}
+}
+
+// Create issues a numbered receipt. A non-zero amountPaid is recorded as its first payment.
+func (s *ReceiptService) Create(actor *models.User, req models.ReceiptRequest) (models.Receipt, error) {
+	if err := s.prepare(&req, BaseCurrency()); err != nil {
+		return models.Receipt{}, err
+	}
+	receiptDate, _ := validation.ParseTime(req.ReceiptDate)
+	receipt := models.Receipt{
+		ClientName:  req.ClientName,
+		ClientEmail: req.ClientEmail,
+		ClientPhone: req.ClientPhone,
+		ReceiptDate: receiptDate,
+		Currency:    req.Currency,
+		CreatedByID: actorID(actor),
+		Activities:  newActivities(req.Activities),
+	}
+
+	err := s.store.Transaction(func(tx repository.Store) error {
+		number, err := nextReceiptNumber(tx)
+		if err != nil {
+			return err
+		}
+		receipt.ReceiptNumber = number
+		if receipt.ClientID, err = receiptClient(tx, actor, req); err != nil {
+			return err
 		}
+		if err := tx.Receipts().Create(&receipt); err != nil {
+			return err
+		}
+		if req.AmountPaid != nil && !req.AmountPaid.IsZero() {
+			payment := models.Payment{ReceiptID: receipt.ID, PaidAt: receiptDate, Method: models.NormalizePaymentMethod(req.PaymentMethod), Amount: *req.AmountPaid}
+			if err := addPayment(tx, actor, &payment); err != nil {
+				return err
+			}
+		}
+		if receipt, err = tx.Receipts().Get(receipt.ID); err != nil {
+			return err
+		}
+		return storeAudit(tx, actor, "receipt", receipt.ID, AuditCreate, nil, receipt)
+	})
+	return receipt, err
+}
+
+// Update replaces the receipt's details and activities. Changing amountPaid is only
+// allowed while the receipt has no payments, and is recorded as a payment of the
+// difference; otherwise it fails with ErrReceiptHasPayments. Changing the currency of a
+// receipt with payments fails with ErrPaidCurrency.
+func (s *ReceiptService) Update(actor *models.User, id uint, req models.ReceiptRequest) (models.Receipt, error) {
+	before, err := s.store.Receipts().Get(id)
+	if err != nil {
+		return before, err
 	}
+	if err := s.prepare(&req, before.Currency); err != nil {
+		return before, err
+	}
+	paidChanged := req.AmountPaid != nil && *req.AmountPaid != before.AmountPaid
+	if paidChanged && len(before.Payments) > 0 {
+		return before, ErrReceiptHasPayments
+	}
+	if req.Currency != before.Currency && len(before.Payments) > 0 {
+		return before, ErrPaidCurrency
+	}
+	receiptDate, _ := validation.ParseTime(req.ReceiptDate)
 
-	return fmt.Sprintf("M%05d", nextNumber)
+	receipt := before
+	receipt.ClientName = req.ClientName
+	receipt.ClientEmail = req.ClientEmail
+	receipt.ClientPhone = req.ClientPhone
+	receipt.ReceiptDate = receiptDate
+	receipt.Currency = req.Currency
+	receipt.Activities = newActivities(req.Activities)
+	receipt.Payments = nil
+
+	err = s.store.Transaction(func(tx repository.Store) error {
+		// Edits rarely carry clientId, so an existing link is kept unless another client resolves.
+		clientID, err := receiptClient(tx, actor, req)
+		if err != nil {
+			return err
+		}
+		if clientID != nil {
+			receipt.ClientID = clientID
+		}
+		if err := tx.Receipts().Update(&receipt); err != nil {
+			return err
+		}
+		if paidChanged {
+			payment := models.Payment{ReceiptID: receipt.ID, PaidAt: time.Now(), Method: models.NormalizePaymentMethod(req.PaymentMethod), Amount: req.AmountPaid.Sub(before.AmountPaid), Reference: "Receipt edit"}
+			if err := addPayment(tx, actor, &payment); err != nil {
+				return err
+			}
+		}
+		if receipt, err = tx.Receipts().Get(receipt.ID); err != nil {
+			return err
+		}
+		return storeAudit(tx, actor, "receipt", receipt.ID, AuditUpdate, before, receipt)
+	})
+	if err != nil {
+		return before, err
+	}
+	return receipt, nil
+}
+
+// prepare validates req and fills it in: missing currencies default to fallback, and for
+// activities to the receipt's; the picked client supplies empty contact details; and
+// activities are linked to the catalogs.
+func (s *ReceiptService) prepare(req *models.ReceiptRequest, fallback string) error {
+	if err := req.Validate(); err != nil {
+		return err
+	}
+	rates, err := loadRates(s.store)
+	if err != nil {
+		return err
+	}
+	if err := resolveReceiptCurrencies(rates, req, fallback); err != nil {
+		return err
+	}
+	if req.ClientID != nil {
+		client, err := pickedClient(s.store, *req.ClientID, "clientId")
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(req.ClientName) == "" {
+			req.ClientName = client.Name
+		}
+		if strings.TrimSpace(req.ClientEmail) == "" {
+			req.ClientEmail = client.Email
+		}
+		if strings.TrimSpace(req.ClientPhone) == "" {
+			req.ClientPhone = client.Phone
+		}
+	}
+	return linkCatalog(s.store, req.Activities)
+}
+
+// resolveReceiptCurrencies fills in missing currency codes and rejects codes without an
+// exchange rate.
+func resolveReceiptCurrencies(rates Rates, req *models.ReceiptRequest, fallback string) error {
+	v := validation.New()
+	var err error
+	if req.Currency, err = ResolveCurrency(rates, req.Currency, fallback); err != nil {
+		v.Add("currency", validation.CodeCurrency, err.Error())
+		return v.Err()
+	}
+	for i := range req.Activities {
+		if req.Activities[i].Currency, err = ResolveCurrency(rates, req.Activities[i].Currency, req.Currency); err != nil {
+			v.Index("activities", i).Add("currency", validation.CodeCurrency, err.Error())
+		}
+	}
+	return v.Err()
+}
+
+// linkCatalog fills activities from the hotel and car rental catalogs. Linked records must
+// exist and their name and address replace whatever was typed; hotel activities without
+// a link are linked when their name matches exactly one catalog hotel, ignoring case.
+func linkCatalog(store repository.Store, requests []models.ActivityRequest) error {
+	v := validation.New()
+	for i := range requests {
+		r := &requests[i]
+		switch {
+		case r.HotelID != nil:
+			hotel, err := store.Hotels().Get(*r.HotelID)
+			if errors.Is(err, repository.ErrNotFound) {
+				v.Index("activities", i).Add("hotelId", validation.CodeNotFound, "hotel does not exist")
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			r.PropertyName, r.PropertyAddress = hotel.Name, hotel.Address
+		case r.CarRentalID != nil:
+			carRental, err := store.CarRentals().Get(*r.CarRentalID)
+			if errors.Is(err, repository.ErrNotFound) {
+				v.Index("activities", i).Add("carRentalId", validation.CodeNotFound, "car rental does not exist")
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			r.PropertyName = carRental.Name
+		case r.Type == models.ActivityHotel && strings.TrimSpace(r.PropertyName) != "":
+			matches, err := store.Hotels().FindByName(strings.TrimSpace(r.PropertyName))
+			if err != nil {
+				return err
+			}
+			if len(matches) == 1 {
+				r.HotelID = &matches[0].ID
+				r.PropertyName = matches[0].Name
+				if r.PropertyAddress == "" {
+					r.PropertyAddress = matches[0].Address
+				}
+			}
+		}
+	}
+	return v.Err()
+}
+
+func newActivities(requests []models.ActivityRequest) []models.Activity {
+	activities := make([]models.Activity, 0, len(requests))
+	for _, r := range requests {
+		activities = append(activities, r.Activity())
+	}
+	return activities
+}
+
+// receiptClient returns the client a receipt belongs to: the one picked in the request,
+// or one matched or created from the receipt's contact details.
+func receiptClient(tx repository.Store, actor *models.User, req models.ReceiptRequest) (*uint, error) {
+	if req.ClientID != nil {
+		return req.ClientID, nil
+	}
+	client, created, err := ResolveClient(tx, req.ClientName, req.ClientEmail, req.ClientPhone)
+	if err != nil || client == nil {
+		return nil, err
+	}
+	if created {
+		if err := storeAudit(tx, actor, "client", client.ID, AuditCreate, nil, client); err != nil {
+			return nil, err
+		}
+	}
+	return &client.ID, nil
+}
+
+func addPayment(tx repository.Store, actor *models.User, payment *models.Payment) error {
+	if err := tx.Receipts().AddPayment(payment); err != nil {
+		return err
+	}
+	return storeAudit(tx, actor, "payment", payment.ID, AuditCreate, nil, payment)
+}
+
+// actorID is the id of the user making a change, or nil for unauthenticated calls.
+func actorID(actor *models.User) *uint {
+	if actor == nil {
+		return nil
+	}
+	id := actor.ID
+	return &id
+}
+
+// List returns one page of the receipts matching filter and how many match in all. A
+// filter on a hotel that does not exist fails with ErrHotelNotFound.
+func (s *ReceiptService) List(filter repository.ReceiptFilter, page repository.Page) ([]models.Receipt, int64, error) {
+	if filter.HotelID != 0 {
+		if _, err := s.store.Hotels().Get(filter.HotelID); errors.Is(err, repository.ErrNotFound) {
+			return nil, 0, ErrHotelNotFound
+		} else if err != nil {
+			return nil, 0, err
+		}
+	}
+	return s.store.Receipts().List(filter, page)
+}
+
+// Trash lists the deleted receipts, latest first.
+func (s *ReceiptService) Trash() ([]models.Receipt, error) {
+	return s.store.Receipts().ListDeleted()
+}
+
+func (s *ReceiptService) Get(id uint) (models.Receipt, ReceiptBalance, error) {
+	receipt, err := s.store.Receipts().Get(id)
+	if err != nil {
+		return receipt, ReceiptBalance{}, err
+	}
+	rates, err := loadRates(s.store)
+	if err != nil {
+		return receipt, ReceiptBalance{}, err
+	}
+	return receipt, ComputeBalance(receipt, rates), nil
+}
+
+// Delete moves the receipt to the trash and frees the proposal it was converted from, so
+// the proposal can be converted again.
+func (s *ReceiptService) Delete(actor *models.User, id uint) error {
+	receipt, err := s.store.Receipts().Get(id)
+	if err != nil {
+		return err
+	}
+	return s.store.Transaction(func(tx repository.Store) error {
+		if err := tx.Proposals().UnlinkReceipt(receipt.ID); err != nil {
+			return err
+		}
+		if err := tx.Receipts().Delete(receipt.ID); err != nil {
+			return err
+		}
+		return storeAudit(tx, actor, "receipt", receipt.ID, AuditDelete, receipt, nil)
+	})
+}
+
+// Restore takes a receipt out of the trash and links it back to its proposal unless the
+// proposal has been converted again meanwhile.
+func (s *ReceiptService) Restore(actor *models.User, id uint) (models.Receipt, error) {
+	receipt, err := s.store.Receipts().GetDeleted(id)
+	if err != nil {
+		return receipt, err
+	}
+	err = s.store.Transaction(func(tx repository.Store) error {
+		if err := tx.Receipts().Restore(receipt.ID); err != nil {
+			return err
+		}
+		if receipt.ProposalID != nil {
+			err := tx.Proposals().LinkReceipt(*receipt.ProposalID, receipt.ID)
+			if err != nil && !errors.Is(err, repository.ErrConflict) {
+				return err
+			}
+		}
+		return storeAudit(tx, actor, "receipt", receipt.ID, AuditRestore, nil, nil)
+	})
+	if err != nil {
+		return receipt, err
+	}
+	return s.store.Receipts().Get(receipt.ID)
 }
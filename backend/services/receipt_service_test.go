@@ -0,0 +1,188 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Otabek228101/mehmon/models"
+	"github.com/Otabek228101/mehmon/repository"
+	"github.com/Otabek228101/mehmon/repository/memory"
+	"github.com/Otabek228101/mehmon/validation"
+)
+
+func TestReceiptServiceBalance(t *testing.T) {
+	store := memory.NewStore()
+	receipts := NewReceiptService(store)
+	store.PutExchangeRate(models.ExchangeRate{Currency: "EUR", Rate: 0.5})
+	receipt := store.PutReceipt(models.Receipt{
+		Currency:   "USD",
+		AmountPaid: models.MoneyFromCents(10000),
+		Activities: []models.Activity{
+			{Amount: models.MoneyFromCents(15000), Currency: "USD"},
+			{Amount: models.MoneyFromCents(5000), Currency: "EUR"},
+			{Amount: models.MoneyFromCents(700), Currency: "GBP"},
+		},
+	})
+
+	_, balance, err := receipts.Get(receipt.ID)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if balance.Total.Cents() != 25000 || balance.Balance.Cents() != 15000 {
+		t.Fatalf("total %v balance %v, want 250.00 and 150.00", balance.Total, balance.Balance)
+	}
+	if len(balance.Unconverted) != 1 || balance.Unconverted[0] != "GBP" {
+		t.Fatalf("unconverted = %v, want [GBP]", balance.Unconverted)
+	}
+}
+
+func TestReceiptServiceTrashRelinksProposal(t *testing.T) {
+	store := memory.NewStore()
+	receipts := NewReceiptService(store)
+	proposal := store.PutProposal(models.Proposal{Status: models.ProposalStatusAccepted})
+	receipt := store.PutReceipt(models.Receipt{ProposalID: &proposal.ID})
+	proposal.ReceiptID = &receipt.ID
+	store.PutProposal(proposal)
+
+	if err := receipts.Delete(testActor, receipt.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, _, err := receipts.Get(receipt.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("get deleted receipt: err = %v, want ErrNotFound", err)
+	}
+	got, _ := store.Proposals().Get(proposal.ID)
+	if got.ReceiptID != nil {
+		t.Fatalf("proposal receipt = %d, want unlinked", *got.ReceiptID)
+	}
+
+	if _, err := receipts.Restore(testActor, receipt.ID); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	got, _ = store.Proposals().Get(proposal.ID)
+	if got.ReceiptID == nil || *got.ReceiptID != receipt.ID {
+		t.Fatalf("proposal receipt = %v, want %d after restore", got.ReceiptID, receipt.ID)
+	}
+
+	actions := []string{}
+	for _, entry := range store.AuditLog() {
+		actions = append(actions, entry.Action)
+	}
+	if len(actions) != 2 || actions[0] != AuditDelete || actions[1] != AuditRestore {
+		t.Fatalf("audit actions = %v, want [delete restore]", actions)
+	}
+}
+
+func TestReceiptServiceCreateRecordsPaymentAndClient(t *testing.T) {
+	store := memory.NewStore()
+	receipts := NewReceiptService(store)
+	hotel := store.PutHotel(models.Hotel{Name: "Test Hotel", Address: "Via Roma 1"})
+	paid := models.MoneyFromCents(5000)
+	checkIn, checkOut := "2026-05-10", "2026-05-12"
+
+	receipt, err := receipts.Create(testActor, models.ReceiptRequest{
+		ClientName:  "Ann",
+		ClientEmail: "Ann@Example.com",
+		ReceiptDate: "2026-05-10",
+		AmountPaid:  &paid,
+		Activities:  []models.ActivityRequest{{Type: models.ActivityHotel, PropertyName: "test hotel", CheckIn: &checkIn, CheckOut: &checkOut, Amount: models.MoneyFromCents(20000)}},
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if receipt.ReceiptNumber == "" || receipt.Currency != BaseCurrency() {
+		t.Fatalf("number %q currency %q, want a number and the base currency", receipt.ReceiptNumber, receipt.Currency)
+	}
+	if len(receipt.Activities) != 1 || receipt.Activities[0].HotelID == nil || *receipt.Activities[0].HotelID != hotel.ID {
+		t.Fatalf("activities = %+v, want one linked to hotel %d", receipt.Activities, hotel.ID)
+	}
+	if len(receipt.Payments) != 1 || receipt.AmountPaid != paid || receipt.Payments[0].Method != models.PaymentCash {
+		t.Fatalf("paid %v with payments %+v, want one cash payment of 50.00", receipt.AmountPaid, receipt.Payments)
+	}
+	if receipt.ClientID == nil {
+		t.Fatal("receipt has no client")
+	}
+	client, _ := store.Clients().Get(*receipt.ClientID)
+	if client.Email != "ann@example.com" {
+		t.Fatalf("client email = %q, want it normalized", client.Email)
+	}
+
+	entities := []string{}
+	for _, entry := range store.AuditLog() {
+		entities = append(entities, entry.Entity)
+	}
+	if len(entities) != 3 || entities[0] != "client" || entities[1] != "payment" || entities[2] != "receipt" {
+		t.Fatalf("audited %v, want [client payment receipt]", entities)
+	}
+}
+
+func TestReceiptServiceCreateRejectsUnknownReferences(t *testing.T) {
+	store := memory.NewStore()
+	receipts := NewReceiptService(store)
+	missing := uint(99)
+
+	_, err := receipts.Create(testActor, models.ReceiptRequest{
+		ClientID:    &missing,
+		ReceiptDate: "2026-05-10",
+		Activities:  []models.ActivityRequest{{Type: models.ActivityOther, Description: "Visa"}},
+	})
+	var errs validation.Errors
+	if !errors.As(err, &errs) || errs[0].Field != "clientId" {
+		t.Fatalf("err = %v, want a clientId validation error", err)
+	}
+	if n := len(store.AuditLog()); n != 0 {
+		t.Fatalf("audit entries = %d, want none", n)
+	}
+}
+
+func TestReceiptServiceUpdateWithPayments(t *testing.T) {
+	store := memory.NewStore()
+	receipts := NewReceiptService(store)
+	receipt := store.PutReceipt(models.Receipt{ReceiptNumber: "M00001", ClientName: "Ann", Currency: BaseCurrency()})
+	paid := models.MoneyFromCents(3000)
+	req := models.ReceiptRequest{ClientName: "Ann Lee", ReceiptDate: "2026-05-10", AmountPaid: &paid}
+
+	got, err := receipts.Update(testActor, receipt.ID, req)
+	if err != nil {
+		t.Fatalf("first amountPaid: %v", err)
+	}
+	if got.ClientName != "Ann Lee" || got.AmountPaid != paid || len(got.Payments) != 1 || got.Payments[0].Reference != "Receipt edit" {
+		t.Fatalf("updated receipt = %+v, want the new name and one payment of 30.00", got)
+	}
+
+	more := models.MoneyFromCents(4000)
+	req.AmountPaid = &more
+	if _, err := receipts.Update(testActor, receipt.ID, req); !errors.Is(err, ErrReceiptHasPayments) {
+		t.Fatalf("second amountPaid: err = %v, want ErrReceiptHasPayments", err)
+	}
+	req.AmountPaid = nil
+	req.Currency = "EUR"
+	store.PutExchangeRate(models.ExchangeRate{Currency: "EUR", Rate: 0.9})
+	if _, err := receipts.Update(testActor, receipt.ID, req); !errors.Is(err, ErrPaidCurrency) {
+		t.Fatalf("currency change: err = %v, want ErrPaidCurrency", err)
+	}
+	if got, _ := store.Receipts().Get(receipt.ID); got.Currency != BaseCurrency() {
+		t.Fatalf("currency = %s, want it unchanged", got.Currency)
+	}
+	req.Currency = ""
+	if _, err := receipts.Update(testActor, receipt.ID, req); err != nil {
+		t.Fatalf("update keeping the currency: %v", err)
+	}
+	if _, err := receipts.Update(testActor, 99, req); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("missing receipt: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestReceiptServiceUpdateKeepsClient(t *testing.T) {
+	store := memory.NewStore()
+	receipts := NewReceiptService(store)
+	client := store.PutClient(models.Client{Name: "Ann", Email: "ann@example.com"})
+	receipt := store.PutReceipt(models.Receipt{ReceiptNumber: "M00001", ClientName: "Ann", ClientID: &client.ID, Currency: BaseCurrency()})
+
+	got, err := receipts.Update(testActor, receipt.ID, models.ReceiptRequest{ClientName: "Ann", ReceiptDate: "2026-05-10"})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if got.ClientID == nil || *got.ClientID != client.ID {
+		t.Fatalf("client = %v, want %d kept", got.ClientID, client.ID)
+	}
+}
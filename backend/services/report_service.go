@@ -0,0 +1,299 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Otabek228101/mehmon/models"
+	"github.com/Otabek228101/mehmon/repository"
+)
+
+// Report dimensions accepted by ReportService.Build.
+const (
+	ReportByMonth        = "month"
+	ReportByHotel        = "hotel"
+	ReportByCity         = "city"
+	ReportByActivityType = "type"
+	ReportByAgent        = "agent"
+)
+
+// ReportRow aggregates the receipts and activities of one group. Revenue is the sum of
+// activity amounts converted to the report currency; amounts in currencies without a
+// rate are left out and listed in Unconverted.
+type ReportRow struct {
+	Key         string       `json:"key"`
+	Label       string       `json:"label"`
+	City        string       `json:"city,omitempty"`
+	Receipts    int          `json:"receipts"`
+	Activities  int          `json:"activities"`
+	RoomNights  int          `json:"roomNights"`
+	Revenue     models.Money `json:"revenue"`
+	Paid        models.Money `json:"paid"`
+	Unconverted []string     `json:"unconverted,omitempty"`
+}
+
+// CurrencySum is an amount in one currency.
+type CurrencySum struct {
+	Currency string       `json:"currency"`
+	Amount   models.Money `json:"amount"`
+}
+
+// currencySums lists amounts in currency code order.
+func currencySums(amounts map[string]models.Money) []CurrencySum {
+	sums := make([]CurrencySum, 0, len(amounts))
+	for currency, amount := range amounts {
+		sums = append(sums, CurrencySum{Currency: currency, Amount: amount})
+	}
+	sort.Slice(sums, func(i, j int) bool { return sums[i].Currency < sums[j].Currency })
+	return sums
+}
+
+// ReceiptTotals counts receipts and sums what they were paid and what their activities
+// cost, per currency.
+type ReceiptTotals struct {
+	Receipts int
+	Paid     []CurrencySum
+	Billed   []CurrencySum
+}
+
+// OutstandingReceipt is a receipt whose activities cost more than has been paid.
+type OutstandingReceipt struct {
+	ID            uint      `json:"id"`
+	ReceiptNumber string    `json:"receiptNumber"`
+	ClientName    string    `json:"clientName"`
+	ReceiptDate   time.Time `json:"receiptDate"`
+	Currency      string    `json:"currency"`
+	ReceiptBalance
+}
+
+// ReportService aggregates the receipts selected by a repository.ReceiptFilter.
+type ReportService struct {
+	store repository.Store
+}
+
+func NewReportService(store repository.Store) *ReportService {
+	return &ReportService{store: store}
+}
+
+func (s *ReportService) Totals(filter repository.ReceiptFilter) (ReceiptTotals, error) {
+	receipts, err := s.store.Receipts().Find(filter)
+	if err != nil {
+		return ReceiptTotals{}, err
+	}
+	paid := map[string]models.Money{}
+	billed := map[string]models.Money{}
+	for _, receipt := range receipts {
+		paid[receipt.Currency] = paid[receipt.Currency].Add(receipt.AmountPaid)
+		for _, activity := range receipt.Activities {
+			billed[activity.Currency] = billed[activity.Currency].Add(activity.Amount)
+		}
+	}
+	return ReceiptTotals{Receipts: len(receipts), Paid: currencySums(paid), Billed: currencySums(billed)}, nil
+}
+
+// Outstanding lists the receipts matching filter that are not paid in full, oldest first,
+// and their balances per currency.
+func (s *ReportService) Outstanding(filter repository.ReceiptFilter, rates Rates) ([]OutstandingReceipt, []CurrencySum, error) {
+	receipts, err := s.store.Receipts().Find(filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	rows := []OutstandingReceipt{}
+	owed := map[string]models.Money{}
+	for _, receipt := range receipts {
+		balance := ComputeBalance(receipt, rates)
+		if balance.Balance.Sign() <= 0 {
+			continue
+		}
+		rows = append(rows, OutstandingReceipt{
+			ID:             receipt.ID,
+			ReceiptNumber:  receipt.ReceiptNumber,
+			ClientName:     receipt.ClientName,
+			ReceiptDate:    receipt.ReceiptDate,
+			Currency:       receipt.Currency,
+			ReceiptBalance: balance,
+		})
+		owed[receipt.Currency] = owed[receipt.Currency].Add(balance.Balance)
+	}
+	return rows, currencySums(owed), nil
+}
+
+type reportGroup struct {
+	key, label, city string
+}
+
+// RoomNights counts the nights of a hotel stay; other activities and stays with missing or
+// inverted dates count zero.
+func RoomNights(activity models.Activity) int {
+	if activity.Type != models.ActivityHotel || activity.CheckIn == nil || activity.CheckOut == nil {
+		return 0
+	}
+	in := time.Date(activity.CheckIn.Year(), activity.CheckIn.Month(), activity.CheckIn.Day(), 0, 0, 0, 0, time.UTC)
+	out := time.Date(activity.CheckOut.Year(), activity.CheckOut.Month(), activity.CheckOut.Day(), 0, 0, 0, 0, time.UTC)
+	if !out.After(in) {
+		return 0
+	}
+	return int(out.Sub(in).Hours() / 24)
+}
+
+// Build groups the receipts matched by filter. Month and agent are properties of the
+// receipt (its date and creator), so every receipt is counted and paid amounts are included.
+// Hotel, city and activity type group individual activities; a receipt counts once in each
+// group it has activities in, and Paid stays zero.
+func (s *ReportService) Build(filter repository.ReceiptFilter, dimension string, rates Rates, currency string) ([]ReportRow, error) {
+	hotels := map[uint]models.Hotel{}
+	users := map[uint]models.User{}
+	switch dimension {
+	case ReportByHotel, ReportByCity:
+		list, err := s.store.Hotels().List("")
+		if err != nil {
+			return nil, err
+		}
+		for _, h := range list {
+			hotels[h.ID] = h
+		}
+	case ReportByAgent:
+		list, err := s.store.Users().List()
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range list {
+			users[u.ID] = u
+		}
+	case ReportByMonth, ReportByActivityType:
+	default:
+		return nil, fmt.Errorf("unknown report dimension %q", dimension)
+	}
+
+	receipts, err := s.store.Receipts().Find(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := map[string]*ReportRow{}
+	var order []string
+	row := func(g reportGroup) *ReportRow {
+		r, ok := rows[g.key]
+		if !ok {
+			r = &ReportRow{Key: g.key, Label: g.label, City: g.city}
+			rows[g.key] = r
+			order = append(order, g.key)
+		}
+		return r
+	}
+	addUnconverted := func(r *ReportRow, code string) {
+		for _, c := range r.Unconverted {
+			if c == code {
+				return
+			}
+		}
+		r.Unconverted = append(r.Unconverted, code)
+	}
+	addActivity := func(r *ReportRow, activity models.Activity) {
+		r.Activities++
+		r.RoomNights += RoomNights(activity)
+		amount, err := rates.Convert(activity.Amount, activity.Currency, currency)
+		if err != nil {
+			addUnconverted(r, activity.Currency)
+			return
+		}
+		r.Revenue = r.Revenue.Add(amount)
+	}
+
+	for _, receipt := range receipts {
+		if dimension == ReportByMonth || dimension == ReportByAgent {
+			r := row(receiptGroup(dimension, receipt, users))
+			r.Receipts++
+			if paid, err := rates.Convert(receipt.AmountPaid, receipt.Currency, currency); err != nil {
+				addUnconverted(r, receipt.Currency)
+			} else {
+				r.Paid = r.Paid.Add(paid)
+			}
+			for _, activity := range receipt.Activities {
+				addActivity(r, activity)
+			}
+			continue
+		}
+		counted := map[string]bool{}
+		for _, activity := range receipt.Activities {
+			g, ok := activityGroup(dimension, activity, hotels)
+			if !ok {
+				continue
+			}
+			r := row(g)
+			if !counted[g.key] {
+				counted[g.key] = true
+				r.Receipts++
+			}
+			addActivity(r, activity)
+		}
+	}
+
+	result := make([]ReportRow, 0, len(order))
+	for _, key := range order {
+		sort.Strings(rows[key].Unconverted)
+		result = append(result, *rows[key])
+	}
+	if dimension == ReportByMonth {
+		sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+	} else {
+		sort.SliceStable(result, func(i, j int) bool {
+			if result[i].Revenue != result[j].Revenue {
+				return result[j].Revenue.Less(result[i].Revenue)
+			}
+			return result[i].Label < result[j].Label
+		})
+	}
+	return result, nil
+}
+
+func receiptGroup(dimension string, receipt models.Receipt, users map[uint]models.User) reportGroup {
+	if dimension == ReportByMonth {
+		month := receipt.ReceiptDate.Format("2006-01")
+		return reportGroup{key: month, label: month}
+	}
+	if receipt.CreatedByID == nil {
+		return reportGroup{key: "none", label: "Unknown"}
+	}
+	key := fmt.Sprint(*receipt.CreatedByID)
+	user, ok := users[*receipt.CreatedByID]
+	if !ok {
+		return reportGroup{key: key, label: "Deleted user #" + key}
+	}
+	label := user.Name
+	if label == "" {
+		label = user.Email
+	}
+	return reportGroup{key: key, label: label}
+}
+
+// activityGroup places an activity in a hotel, city or type group. Hotels are matched by
+// their catalog link and fall back to the property name for unlinked stays.
+func activityGroup(dimension string, activity models.Activity, hotels map[uint]models.Hotel) (reportGroup, bool) {
+	if dimension == ReportByActivityType {
+		return reportGroup{key: activity.Type, label: activity.Type}, true
+	}
+	if activity.Type != models.ActivityHotel {
+		return reportGroup{}, false
+	}
+	var hotel *models.Hotel
+	if activity.HotelID != nil {
+		if h, ok := hotels[*activity.HotelID]; ok {
+			hotel = &h
+		}
+	}
+	if dimension == ReportByCity {
+		if hotel == nil || hotel.City == "" {
+			return reportGroup{key: "", label: "Unknown"}, true
+		}
+		city := strings.ToUpper(hotel.City)
+		return reportGroup{key: city, label: city}, true
+	}
+	if hotel != nil {
+		return reportGroup{key: fmt.Sprint(hotel.ID), label: hotel.Name, city: hotel.City}, true
+	}
+	name := strings.TrimSpace(activity.PropertyName)
+	return reportGroup{key: "name:" + strings.ToLower(name), label: name}, true
+}
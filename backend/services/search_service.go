@@ -0,0 +1,30 @@
+package services
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/Otabek228101/mehmon/models"
+	"github.com/Otabek228101/mehmon/repository"
+)
+
+// searchTerms splits free text into words, dropping punctuation that has meaning in tsquery syntax.
+func searchTerms(q string) []string {
+	return strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '@' && r != '.' && r != '-' && r != '_'
+	})
+}
+
+type SearchService struct {
+	store repository.Store
+}
+
+func NewSearchService(store repository.Store) *SearchService {
+	return &SearchService{store: store}
+}
+
+// Search finds up to limit receipts, activities and proposals matching every word of q,
+// restricted to types when it is not empty.
+func (s *SearchService) Search(q string, types []string, limit int) ([]models.SearchHit, error) {
+	return s.store.Search().Search(searchTerms(q), types, limit)
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Otabek228101/mehmon/models"
+	"github.com/Otabek228101/mehmon/repository"
+)
+
+// SeedData fills empty catalog and document tables with demo records. Documents are created
+// through their services, so they take the next configured number and are audited like any
+// other; the demo proposal is made for the first hotel in the catalog.
+func SeedData(store repository.Store) error {
+	hotelService := NewHotelService(store)
+	hotels, err := hotelService.List("")
+	if err != nil {
+		return err
+	}
+	if len(hotels) == 0 {
+		requests := []models.CreateHotelRequest{
+			{Name: "UNA Hotels Regina Bari", City: "BARI", GroupName: "UNA Hotels", Type: "hotel", Address: "SP57 Torre a Mare / Noicattaro, Noicattaro (BA)", Stars: 4, Breakfast: true, WebsiteLink: "https://booking.unaitalianhospitality.com/?adult=1&arrive=2025-06-05&chain=33116&child=0&depart=2025-06-06&level=chain&locale=it-IT&rooms=1", LocationLink: "https://goo.gl/maps/example1"},
+			{Name: "UNA Hotels Bologna Centro", City: "BOLOGNA", GroupName: "UNA Hotels", Type: "hotel", Address: "Viale Pietro Pietramellara, 41, Bologna", Stars: 4, Breakfast: true, WebsiteLink: "https://booking.unaitalianhospitality.com/?adult=1&arrive=2025-06-05&chain=33116&child=0&depart=2025-06-06&level=chain&locale=it-IT&rooms=1", LocationLink: "https://goo.gl/maps/example2"},
+			{Name: "Principi di Piemonte | UNA Esperienze", City: "TORINO", GroupName: "UNA Hotels", Type: "hotel", Address: "Via Piero Gobetti, 15, 10123 Torino TO", Stars: 5, Breakfast: true, WebsiteLink: "https://booking.unaitalianhospitality.com/?adult=1&arrive=2025-06-05&chain=33116&child=0&depart=2025-06-06&level=chain&locale=it-IT&rooms=1", LocationLink: "https://goo.gl/maps/example3"},
+		}
+		for _, req := range requests {
+			hotel, err := hotelService.Create(nil, req)
+			if err != nil {
+				return fmt.Errorf("hotel %s: %w", req.Name, err)
+			}
+			hotels = append(hotels, hotel)
+		}
+		log.Println("Hotels seeded successfully")
+	}
+
+	rentalService := NewCarRentalService(store)
+	rentals, err := rentalService.List()
+	if err != nil {
+		return err
+	}
+	if len(rentals) == 0 {
+		for _, name := range []string{"Hertz", "Avis"} {
+			if _, err := rentalService.Create(nil, models.CarRental{Name: name}); err != nil {
+				return fmt.Errorf("car rental %s: %w", name, err)
+			}
+		}
+		log.Println("Car rentals seeded successfully")
+	}
+
+	today := time.Now().Format("2006-01-02")
+	receiptService := NewReceiptService(store)
+	_, receiptCount, err := receiptService.List(repository.ReceiptFilter{}, repository.Page{Sort: "id", Limit: 1})
+	if err != nil {
+		return err
+	}
+	if receiptCount == 0 {
+		paid := models.MoneyFromFloat(500)
+		receipt, err := receiptService.Create(nil, models.ReceiptRequest{
+			ClientName:    "John Doe",
+			ClientEmail:   "john@example.com",
+			ClientPhone:   "+1234567890",
+			ReceiptDate:   today,
+			AmountPaid:    &paid,
+			PaymentMethod: models.PaymentCash,
+		})
+		if err != nil {
+			return fmt.Errorf("receipt: %w", err)
+		}
+		log.Printf("Receipt %s seeded successfully", receipt.ReceiptNumber)
+	}
+
+	proposalService := NewProposalService(store)
+	proposals, err := proposalService.List("")
+	if err != nil {
+		return err
+	}
+	if len(proposals) == 0 {
+		proposal, err := proposalService.Create(nil, models.ProposalRequest{
+			ClientName: "Test Client",
+			Guests:     2,
+			CheckIn:    today,
+			CheckOut:   time.Now().AddDate(0, 0, 3).Format("2006-01-02"),
+			Breakfast:  true,
+			FreeCancel: true,
+			Price:      models.MoneyFromFloat(450),
+			HotelID:    hotels[0].ID,
+			Rooms:      []models.RoomRequest{{Count: 1}},
+		})
+		if err != nil {
+			return fmt.Errorf("proposal: %w", err)
+		}
+		log.Printf("Proposal %s seeded successfully", proposal.ProposalNumber)
+	}
+
+	log.Println("Database seeding completed")
+	return nil
+}
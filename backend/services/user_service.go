@@ -0,0 +1,131 @@
+package services
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/Otabek228101/mehmon/models"
+	"github.com/Otabek228101/mehmon/repository"
+)
+
+var (
+	// ErrEmailTaken rejects a user whose email another user already signs in with.
+	ErrEmailTaken = errors.New("a user with this email already exists")
+	// ErrOwnAdminRole stops an admin from demoting themselves.
+	ErrOwnAdminRole = errors.New("you cannot remove your own admin role")
+	// ErrDeleteSelf stops a user from deleting their own account.
+	ErrDeleteSelf = errors.New("you cannot delete your own account")
+)
+
+// UserService manages the accounts that sign in. Missing users come back as
+// repository.ErrNotFound and bad requests as validation.Errors.
+type UserService struct {
+	store repository.Store
+}
+
+func NewUserService(store repository.Store) *UserService {
+	return &UserService{store: store}
+}
+
+func (s *UserService) List() ([]models.User, error) {
+	return s.store.Users().List()
+}
+
+// emailFree reports ErrEmailTaken when a user other than excludeID has email.
+func (s *UserService) emailFree(email string, excludeID uint) error {
+	_, err := s.store.Users().FindByEmail(email, excludeID)
+	if err == nil {
+		return ErrEmailTaken
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// Create adds a user, an agent unless the request names another role. actor is nil for
+// users created from the command line.
+func (s *UserService) Create(actor *models.User, req models.UserRequest) (models.User, error) {
+	if err := req.Validate(true); err != nil {
+		return models.User{}, err
+	}
+	user := models.User{Email: strings.ToLower(strings.TrimSpace(req.Email)), Name: req.Name, Role: req.Role}
+	if user.Role == "" {
+		user.Role = models.RoleAgent
+	}
+	if err := s.emailFree(user.Email, 0); err != nil {
+		return models.User{}, err
+	}
+	hash, err := HashPassword(req.Password)
+	if err != nil {
+		return models.User{}, err
+	}
+	user.PasswordHash = hash
+
+	err = s.store.Transaction(func(tx repository.Store) error {
+		if err := tx.Users().Create(&user); err != nil {
+			return err
+		}
+		return storeAudit(tx, actor, "user", user.ID, AuditCreate, nil, user)
+	})
+	return user, err
+}
+
+// Update changes the fields set in req.
+func (s *UserService) Update(actor *models.User, id uint, req models.UserRequest) (models.User, error) {
+	user, err := s.store.Users().Get(id)
+	if err != nil {
+		return user, err
+	}
+	before := user
+	if err := req.Validate(false); err != nil {
+		return user, err
+	}
+
+	if req.Role != "" {
+		if actor != nil && actor.ID == user.ID && req.Role != models.RoleAdmin {
+			return user, ErrOwnAdminRole
+		}
+		user.Role = req.Role
+	}
+	if email := strings.ToLower(strings.TrimSpace(req.Email)); email != "" && email != user.Email {
+		if err := s.emailFree(email, user.ID); err != nil {
+			return user, err
+		}
+		user.Email = email
+	}
+	if req.Name != "" {
+		user.Name = req.Name
+	}
+	if req.Password != "" {
+		hash, err := HashPassword(req.Password)
+		if err != nil {
+			return user, err
+		}
+		user.PasswordHash = hash
+	}
+
+	err = s.store.Transaction(func(tx repository.Store) error {
+		if err := tx.Users().Update(&user); err != nil {
+			return err
+		}
+		return storeAudit(tx, actor, "user", user.ID, AuditUpdate, before, user)
+	})
+	return user, err
+}
+
+func (s *UserService) Delete(actor *models.User, id uint) error {
+	user, err := s.store.Users().Get(id)
+	if err != nil {
+		return err
+	}
+	if actor != nil && actor.ID == user.ID {
+		return ErrDeleteSelf
+	}
+	return s.store.Transaction(func(tx repository.Store) error {
+		if err := tx.Users().Delete(user.ID); err != nil {
+			return err
+		}
+		return storeAudit(tx, actor, "user", user.ID, AuditDelete, user, nil)
+	})
+}
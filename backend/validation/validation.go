@@ -0,0 +1,145 @@
+package validation
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Error codes returned in FieldError.Code.
+const (
+	CodeRequired      = "required"
+	CodeInvalidDate   = "invalid_date"
+	CodeInvalidEmail  = "invalid_email"
+	CodeInvalidPhone  = "invalid_phone"
+	CodeCurrency      = "invalid_currency"
+	CodeOneOf         = "one_of"
+	CodeNegative      = "negative"
+	CodeZero          = "zero"
+	CodeOutOfRange    = "out_of_range"
+	CodeTooShort      = "too_short"
+	CodeBeforeCheckIn = "before_check_in"
+	CodeNotFound      = "not_found"
+	CodeNotAllowed    = "not_allowed"
+)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	parts := make([]string, len(e))
+	for i, fe := range e {
+		parts[i] = fe.Field + ": " + fe.Message
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Validator collects field errors. Validators returned by Index share the parent's
+// errors and prefix their field names, e.g. "activities[2].checkOut".
+type Validator struct {
+	prefix string
+	errs   *Errors
+}
+
+func New() *Validator {
+	return &Validator{errs: &Errors{}}
+}
+
+func (v *Validator) Index(field string, i int) *Validator {
+	return &Validator{prefix: fmt.Sprintf("%s%s[%d].", v.prefix, field, i), errs: v.errs}
+}
+
+func (v *Validator) Add(field, code, message string) {
+	*v.errs = append(*v.errs, FieldError{Field: v.prefix + field, Code: code, Message: message})
+}
+
+// Has reports whether field already has an error, so follow-up checks can stay quiet.
+func (v *Validator) Has(field string) bool {
+	for _, fe := range *v.errs {
+		if fe.Field == v.prefix+field {
+			return true
+		}
+	}
+	return false
+}
+
+// Check adds an error unless ok holds, and reports ok.
+func (v *Validator) Check(ok bool, field, code, message string) bool {
+	if !ok {
+		v.Add(field, code, message)
+	}
+	return ok
+}
+
+func (v *Validator) Required(field, value string) bool {
+	return v.Check(strings.TrimSpace(value) != "", field, CodeRequired, "is required")
+}
+
+var emailPattern = regexp.MustCompile(`^[^\s@]+@[^\s@]+\.[^\s@]+$`)
+
+// Email checks the format of a non-empty address; pair with Required when it is mandatory.
+func (v *Validator) Email(field, value string) bool {
+	return value == "" || v.Check(emailPattern.MatchString(value), field, CodeInvalidEmail, "is not a valid email address")
+}
+
+var phonePattern = regexp.MustCompile(`^\+?[0-9]{10,15}$`)
+
+func (v *Validator) Phone(field, value string) bool {
+	value = strings.NewReplacer(" ", "", "-", "", "(", "", ")", "").Replace(value)
+	return value == "" || v.Check(phonePattern.MatchString(value), field, CodeInvalidPhone, "must be 10-15 digits with an optional leading +")
+}
+
+var currencyPattern = regexp.MustCompile(`^[A-Za-z]{3}$`)
+
+func (v *Validator) Currency(field, value string) bool {
+	value = strings.TrimSpace(value)
+	return value == "" || v.Check(currencyPattern.MatchString(value), field, CodeCurrency, "must be a 3-letter currency code")
+}
+
+func (v *Validator) OneOf(field, value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	v.Add(field, CodeOneOf, "must be one of: "+strings.Join(allowed, ", "))
+	return false
+}
+
+// Date parses a non-empty value with ParseTime; empty values are left to Required.
+func (v *Validator) Date(field, value string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	t, err := ParseTime(value)
+	if err != nil {
+		v.Add(field, CodeInvalidDate, "must be a date (YYYY-MM-DD) or ISO 8601 timestamp")
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+func (v *Validator) Err() error {
+	if len(*v.errs) == 0 {
+		return nil
+	}
+	return *v.errs
+}
+
+var timeLayouts = []string{time.RFC3339Nano, time.RFC3339, "2006-01-02", "2006-01-02T15:04:05"}
+
+// ParseTime accepts the date formats the frontend sends.
+func ParseTime(value string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
@@ -0,0 +1,106 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestValidatorCollectsIndexedErrors(t *testing.T) {
+	v := New()
+	if v.Err() != nil {
+		t.Fatal("new validator has errors")
+	}
+	v.Required("name", "  ")
+	item := v.Index("activities", 2)
+	item.Add("checkOut", CodeBeforeCheckIn, "must not be before check-in")
+
+	var errs Errors
+	if !errors.As(v.Err(), &errs) || len(errs) != 2 {
+		t.Fatalf("errors = %v, want two", v.Err())
+	}
+	if errs[0] != (FieldError{Field: "name", Code: CodeRequired, Message: "is required"}) {
+		t.Errorf("first error = %+v", errs[0])
+	}
+	if errs[1].Field != "activities[2].checkOut" || errs[1].Code != CodeBeforeCheckIn {
+		t.Errorf("indexed error = %+v", errs[1])
+	}
+	if !item.Has("checkOut") || item.Has("checkIn") || !v.Has("name") || v.Has("checkOut") {
+		t.Error("Has does not follow the field prefix")
+	}
+	want := "name: is required; activities[2].checkOut: must not be before check-in"
+	if got := errs.Error(); got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+func TestFormatChecks(t *testing.T) {
+	tests := []struct {
+		name  string
+		check func(v *Validator, field, value string) bool
+		value string
+		ok    bool
+		code  string
+	}{
+		{"email", (*Validator).Email, "", true, ""},
+		{"email", (*Validator).Email, "ann@example.com", true, ""},
+		{"email", (*Validator).Email, "ann@example", false, CodeInvalidEmail},
+		{"email", (*Validator).Email, "ann @example.com", false, CodeInvalidEmail},
+		{"phone", (*Validator).Phone, "", true, ""},
+		{"phone", (*Validator).Phone, "+998 (90) 123-45-67", true, ""},
+		{"phone", (*Validator).Phone, "12345", false, CodeInvalidPhone},
+		{"phone", (*Validator).Phone, "+99890abc4567", false, CodeInvalidPhone},
+		{"currency", (*Validator).Currency, "", true, ""},
+		{"currency", (*Validator).Currency, " eur ", true, ""},
+		{"currency", (*Validator).Currency, "EURO", false, CodeCurrency},
+		{"required", (*Validator).Required, "x", true, ""},
+		{"required", (*Validator).Required, "", false, CodeRequired},
+	}
+	for _, tt := range tests {
+		v := New()
+		if ok := tt.check(v, tt.name, tt.value); ok != tt.ok {
+			t.Errorf("%s(%q) = %v, want %v", tt.name, tt.value, ok, tt.ok)
+		}
+		var errs Errors
+		errors.As(v.Err(), &errs)
+		switch {
+		case tt.ok && len(errs) != 0:
+			t.Errorf("%s(%q) recorded %v", tt.name, tt.value, errs)
+		case !tt.ok && (len(errs) != 1 || errs[0].Code != tt.code || errs[0].Field != tt.name):
+			t.Errorf("%s(%q) recorded %v, want one %s error", tt.name, tt.value, errs, tt.code)
+		}
+	}
+}
+
+func TestOneOf(t *testing.T) {
+	v := New()
+	if !v.OneOf("type", "hotel", "hotel", "car") {
+		t.Error("hotel rejected")
+	}
+	if v.OneOf("type", "boat", "hotel", "car") {
+		t.Error("boat accepted")
+	}
+	var errs Errors
+	if !errors.As(v.Err(), &errs) || len(errs) != 1 || errs[0].Message != "must be one of: hotel, car" {
+		t.Fatalf("errors = %v", v.Err())
+	}
+}
+
+func TestDate(t *testing.T) {
+	want := time.Date(2026, 5, 10, 0, 0, 0, 0, time.UTC)
+	for _, value := range []string{"2026-05-10", "2026-05-10T00:00:00", "2026-05-10T00:00:00Z", "2026-05-10T00:00:00.000Z"} {
+		v := New()
+		got, ok := v.Date("checkIn", value)
+		if !ok || !got.Equal(want) || v.Err() != nil {
+			t.Errorf("Date(%q) = %v, %v, %v", value, got, ok, v.Err())
+		}
+	}
+
+	v := New()
+	if _, ok := v.Date("checkIn", ""); ok || v.Err() != nil {
+		t.Errorf("empty date: ok %v, errors %v; want it left to Required", ok, v.Err())
+	}
+	if _, ok := v.Date("checkIn", "10/05/2026"); ok || !v.Has("checkIn") {
+		t.Errorf("10/05/2026 accepted")
+	}
+}